@@ -38,6 +38,7 @@ import (
 	"github.com/pborman/uuid"
 	"github.com/wanchain/schnorr-mpc/accounts"
 	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
 	"github.com/wanchain/schnorr-mpc/common/math"
 	"github.com/wanchain/schnorr-mpc/crypto"
 )
@@ -62,6 +63,44 @@ type Key struct {
 	WAddress common.WAddress
 	// extended info
 	Exten string
+	// participants of the storeman group which created the mpc key
+	Group *StoremanGroup
+}
+
+// StoremanPeer is one participant of a storeman group and its polynomial seed.
+type StoremanPeer struct {
+	NodeID hexutil.Bytes `json:"nodeId"`
+	Seed   uint64        `json:"seed"`
+}
+
+// StoremanGroup records the storeman group which created an mpc key.
+type StoremanGroup struct {
+	ID        string         `json:"id,omitempty"`
+	Threshold int            `json:"threshold"`
+	Total     int            `json:"total"`
+	Peers     []StoremanPeer `json:"peers"`
+}
+
+// legacySeedsLimit is the max number of 3 bytes seeds which can be packed into WAddress.
+const legacySeedsLimit = common.WAddressLength / 3
+
+// LegacyStoremanGroup rebuilds the group of a key created before the group was recorded
+// in the keystore file. The seeds were packed 3 bytes each into WAddress, in the order of
+// the sorted storeman node ids.
+func LegacyStoremanGroup(waddress common.WAddress, nodeIDs [][]byte, threshold int) (*StoremanGroup, error) {
+	if len(nodeIDs) > legacySeedsLimit {
+		return nil, ErrLegacyGroupTooLarge
+	}
+
+	group := &StoremanGroup{Threshold: threshold, Total: len(nodeIDs), Peers: make([]StoremanPeer, len(nodeIDs))}
+	b := make([]byte, 8)
+	for i, nodeID := range nodeIDs {
+		copy(b[5:], waddress[i*3:])
+		group.Peers[i].NodeID = common.CopyBytes(nodeID)
+		group.Peers[i].Seed = binary.BigEndian.Uint64(b)
+	}
+
+	return group, nil
 }
 
 // Used to import and export raw keypair
@@ -100,13 +139,14 @@ type plainKeyJSON struct {
 }
 
 type encryptedKeyJSONV3 struct {
-	Address  string     `json:"address"`
-	Crypto   cryptoJSON `json:"crypto"`
-	Crypto2  cryptoJSON `json:"crypto2"`
-	Id       string     `json:"id"`
-	Version  int        `json:"version"`
-	WAddress string     `json:"waddress"`
-	Exten    string     `json:"exten"`
+	Address  string         `json:"address"`
+	Crypto   cryptoJSON     `json:"crypto"`
+	Crypto2  cryptoJSON     `json:"crypto2"`
+	Id       string         `json:"id"`
+	Version  int            `json:"version"`
+	WAddress string         `json:"waddress"`
+	Exten    string         `json:"exten"`
+	Group    *StoremanGroup `json:"group,omitempty"`
 }
 
 type encryptedKeyJSONV1 struct {
@@ -270,7 +310,7 @@ func newStoremanKey(pKey *ecdsa.PublicKey, pShare *big.Int, seeds []uint64, accT
 	return key, nil
 }
 
-func newMpcKey(pKey *ecdsa.PublicKey, pShare *big.Int, seeds []uint64, accType string) (*Key, error) {
+func newMpcKey(pKey *ecdsa.PublicKey, pShare *big.Int, group *StoremanGroup, accType string) (*Key, error) {
 	priv := new(ecdsa.PrivateKey)
 	priv.PublicKey.Curve = crypto.S256()
 	priv.D = pShare
@@ -294,13 +334,7 @@ func newMpcKey(pKey *ecdsa.PublicKey, pShare *big.Int, seeds []uint64, accType s
 		PrivateKey:  priv,
 		PrivateKey2: priv,
 		Exten:       exten,
-	}
-
-	//3 bytes for every seed
-	for i, seed := range seeds {
-		b := make([]byte, 8)
-		binary.BigEndian.PutUint64(b, seed)
-		copy(key.WAddress[i*3:], b[5:])
+		Group:       group,
 	}
 
 	return key, nil
@@ -319,8 +353,8 @@ func storeNewKey(ks keyStore, rand io.Reader, auth string) (*Key, accounts.Accou
 	return key, a, err
 }
 
-func storeStoremanKey(ks keyStore, pKey *ecdsa.PublicKey, pShare *big.Int, seeds []uint64, passphrase string, accType string) (*Key, accounts.Account, error) {
-	key, err := newMpcKey(pKey, pShare, seeds, accType)
+func storeStoremanKey(ks keyStore, pKey *ecdsa.PublicKey, pShare *big.Int, group *StoremanGroup, passphrase string, accType string) (*Key, accounts.Account, error) {
+	key, err := newMpcKey(pKey, pShare, group, accType)
 	if err != nil {
 		return nil, accounts.Account{}, err
 	}
@@ -0,0 +1,83 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
+)
+
+func TestStoremanGroupInKeystore(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	group := &StoremanGroup{Threshold: 26, Total: 50}
+	for i := 0; i < group.Total; i++ {
+		nodeID := make([]byte, 64)
+		nodeID[0] = byte(i + 1)
+		group.Peers = append(group.Peers, StoremanPeer{NodeID: nodeID, Seed: uint64(0xffffff00 + i)})
+	}
+
+	key, err := newMpcKey(&prv.PublicKey, big.NewInt(100), group, StoremanWanAcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyjson, err := EncryptKey(key, "password", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := DecryptKey(keyjson, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dec.Group == nil {
+		t.Fatal("storeman group is lost")
+	}
+
+	if dec.Group.Threshold != group.Threshold || dec.Group.Total != group.Total || len(dec.Group.Peers) != len(group.Peers) {
+		t.Fatalf("storeman group mismatch, got %+v", dec.Group)
+	}
+
+	for i, peer := range dec.Group.Peers {
+		if !bytes.Equal(peer.NodeID, group.Peers[i].NodeID) || peer.Seed != group.Peers[i].Seed {
+			t.Errorf("storeman peer %d mismatch", i)
+		}
+	}
+}
+
+func TestLegacyStoremanGroup(t *testing.T) {
+	var waddress common.WAddress
+	nodeIDs := make([][]byte, legacySeedsLimit)
+	for i := range nodeIDs {
+		nodeIDs[i] = make([]byte, 64)
+		nodeIDs[i][0] = byte(i + 1)
+
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(0x100000+i))
+		copy(waddress[i*3:], b[5:])
+	}
+
+	group, err := LegacyStoremanGroup(waddress, nodeIDs, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if group.Threshold != 12 || group.Total != legacySeedsLimit {
+		t.Errorf("legacy group mismatch, got %+v", group)
+	}
+
+	for i, peer := range group.Peers {
+		if peer.Seed != uint64(0x100000+i) || !bytes.Equal(peer.NodeID, nodeIDs[i]) {
+			t.Errorf("legacy peer %d mismatch, seed %x", i, peer.Seed)
+		}
+	}
+
+	_, err = LegacyStoremanGroup(waddress, append(nodeIDs, make([]byte, 64)), 12)
+	if err != ErrLegacyGroupTooLarge {
+		t.Errorf("expect ErrLegacyGroupTooLarge, got %v", err)
+	}
+}
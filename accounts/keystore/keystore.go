@@ -477,8 +477,8 @@ func (ks *KeyStore) NewAccount(passphrase string) (accounts.Account, error) {
 //	return account, nil
 //}
 
-func (ks *KeyStore) NewStoremanAccount(pKey *ecdsa.PublicKey, pShare *big.Int, seeds []uint64, passphrase string, accType string) (accounts.Account, error) {
-	_, account, err := storeStoremanKey(ks.storage, pKey, pShare, seeds, passphrase, accType)
+func (ks *KeyStore) NewStoremanAccount(pKey *ecdsa.PublicKey, pShare *big.Int, group *StoremanGroup, passphrase string, accType string) (accounts.Account, error) {
+	_, account, err := storeStoremanKey(ks.storage, pKey, pShare, group, passphrase, accType)
 	if err != nil {
 		return accounts.Account{}, err
 	}
@@ -594,6 +594,29 @@ func (ks *KeyStore) UpdateStoreman(a accounts.Account, passphrase, newPassphrase
 	return ks.storage.StoreKey(fa.URL.Path, key, newPassphrase)
 }
 
+// UpdateStoremanGroup records the storeman group into an existing storeman keystore file.
+// It is used to migrate the keystore files which only have the seeds packed in WAddress.
+func (ks *KeyStore) UpdateStoremanGroup(a accounts.Account, passphrase string, group *StoremanGroup) error {
+	fa, err := ks.Find(a)
+	if err != nil {
+		return errors.New("storeman keystore file doesn't exist")
+	}
+
+	keyjson, err := ioutil.ReadFile(fa.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	key, err := DecryptKey(keyjson, passphrase)
+	if err != nil {
+		return err
+	}
+
+	key.Address = a.Address
+	key.Group = group
+	return ks.storage.StoreKey(fa.URL.Path, key, passphrase)
+}
+
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
 // a key file in the key directory. The key file is encrypted with the same passphrase.
 func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (accounts.Account, error) {
@@ -81,6 +81,7 @@ var (
 	ErrWAddressInvalid       = errors.New("invalid wanchain address")
 	ErrInvalidAccountKey     = errors.New("invalid account key")
 	ErrInvalidPrivateKey     = errors.New("invalid private key")
+	ErrLegacyGroupTooLarge   = errors.New("too many storeman nodes for a legacy storeman keystore")
 )
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -212,6 +213,7 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 		version,
 		hex.EncodeToString(key.WAddress[:]),
 		key.Exten,
+		key.Group,
 	}
 	return json.Marshal(encryptedKeyJSONV3)
 }
@@ -278,6 +280,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		err                        error
 		waddressStr                *string
 		exten                      *string
+		group                      *StoremanGroup
 	)
 	if version, ok := m["version"].(string); ok && version == "1" {
 		k := new(encryptedKeyJSONV1)
@@ -308,6 +311,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 
 		waddressStr = &k.WAddress
 		exten = &k.Exten
+		group = k.Group
 	}
 
 	key, err := crypto.ToECDSA(keyBytes)
@@ -335,6 +339,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		PrivateKey2: key2,
 		WAddress:    waddress,
 		Exten:		 *exten,
+		Group:       group,
 	}, nil
 }
 
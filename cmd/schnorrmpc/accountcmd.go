@@ -26,6 +26,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/wanchain/schnorr-mpc/accounts"
@@ -33,6 +34,8 @@ import (
 	"github.com/wanchain/schnorr-mpc/cmd/utils"
 	"github.com/wanchain/schnorr-mpc/console"
 	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -135,6 +138,25 @@ The keystore will be decrypted by AWS KMS, and plaintext will be saved into new
 				Description: `
 	for example:schnorrmpc --datadir <path of data> account update <hex string of gpk(0x1234...abef)>
 change the password of the keystore file.
+`,
+			},
+			{
+				Name:      "migrate",
+				Usage:     "record the storeman group into a legacy keystore",
+				Action:    utils.MigrateFlags(accountMigrate),
+				ArgsUsage: "<hex string of gpk>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.SchnorrThresholdFlag,
+				},
+				Description: `
+	for example:schnorrmpc --datadir <path of data> account migrate --threshold 17 <hex string of gpk(0x1234...abef)>
+Legacy keystore files only keep the seeds of at most 22 storeman nodes, packed in the waddress field.
+The command rebuilds the storeman group from storemans.json of the data directory and records
+the node ids, seeds, threshold and total number of nodes into the keystore file.
+The storemans.json must be the one used when the gpk was created.
+An AWS KMS encrypted keystore file should be decrypted by "account decrypt" before migrating.
 `,
 			},
 		},
@@ -361,6 +383,78 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountMigrate records the storeman group into the keystore files created before
+// the group was recorded in the keystore.
+func accountMigrate(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("No accounts specified to migrate")
+	}
+
+	ctx.GlobalSet(utils.StoremanFlag.Name, "true")
+	stack, cfg := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	threshold := cfg.Sm.SchnorrThreshold
+	if ctx.GlobalIsSet(utils.SchnorrThresholdFlag.Name) {
+		threshold = ctx.GlobalInt(utils.SchnorrThresholdFlag.Name)
+	}
+
+	nodeIDs := make([]discover.NodeID, len(cfg.Sm.StoremanNodes))
+	for i, node := range cfg.Sm.StoremanNodes {
+		nodeIDs[i] = node.ID
+	}
+	sort.Sort(mpcprotocol.SliceStoremanGroup(nodeIDs))
+
+	groupIDs := make([][]byte, len(nodeIDs))
+	for i := range nodeIDs {
+		groupIDs[i] = nodeIDs[i][:]
+	}
+
+	for _, pkStr := range ctx.Args() {
+		pk, err := shcnorrmpc.StringtoPk(pkStr)
+		if err != nil {
+			fmt.Println("StringtoPk error", err.Error())
+			continue
+		}
+
+		pkBytes := crypto.FromECDSAPub(pk)
+		addr, err := shcnorrmpc.PkToAddress(pkBytes[:])
+		if err != nil {
+			fmt.Println("PkToAddress error", err.Error())
+			continue
+		}
+
+		account, password := unlockAccount(ctx, ks, addr.String(), 0, utils.MakePasswordList(ctx))
+		account, err = ks.Find(account)
+		if err != nil {
+			utils.Fatalf("Could not find the account: %v", err)
+		}
+
+		key, err := ks.GetKey(account, password)
+		if err != nil {
+			utils.Fatalf("Could not decrypt the account: %v", err)
+		}
+
+		if key.Group != nil {
+			fmt.Printf("Storeman group of %v is already recorded\n", pkStr)
+			continue
+		}
+
+		group, err := keystore.LegacyStoremanGroup(key.WAddress, groupIDs, threshold)
+		if err != nil {
+			utils.Fatalf("Could not rebuild the storeman group: %v", err)
+		}
+		group.ID = mpcprotocol.DefaultGroupID
+
+		if err := ks.UpdateStoremanGroup(account, password, group); err != nil {
+			utils.Fatalf("Could not migrate the account: %v", err)
+		}
+
+		fmt.Printf("Migrate %v successfully, storeman nodes: %d, threshold: %d\n", pkStr, group.Total, group.Threshold)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
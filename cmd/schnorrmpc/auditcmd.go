@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"path/filepath"
+
+	"github.com/wanchain/schnorr-mpc/cmd/utils"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/storeman/audit"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	auditNodeFlag = cli.StringFlag{
+		Name:  "nodeid",
+		Usage: "Node id (hex public key) of the storeman expected to sign the audit log",
+	}
+
+	auditCommand = cli.Command{
+		Name:     "audit",
+		Usage:    "Manage the storeman audit log",
+		Category: "AUDIT COMMANDS",
+		Description: `
+Every mpc context finished by the storeman is recorded in a hash chained audit log,
+signed by the node key, which is stored in <DATADIR>/storeman/audit.log.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "verify",
+				Usage:     "Verify the hash chain and the signatures of the audit log",
+				Action:    utils.MigrateFlags(auditVerify),
+				ArgsUsage: "[<auditFile>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					auditNodeFlag,
+				},
+				Description: `
+    schnorrmpc audit verify [<auditFile>]
+
+Verify that no record of the audit log has been modified, inserted or removed, and
+that all the records are signed by the node given by --nodeid, or by the same node
+if it is not given. The signer is printed, compare it with the id of the storeman.
+The audit log in the data directory is verified if the file is not given.
+
+Removing the last records of the log is not detected, compare the last record with
+a record known to be appended before, e.g. one returned by storeman_auditLog.`,
+			},
+		},
+	}
+)
+
+func auditVerify(ctx *cli.Context) error {
+	path := ctx.Args().First()
+	if path == "" {
+		path = filepath.Join(utils.GetActualDataDir(ctx), "storeman", audit.FileName)
+	}
+
+	var signer *ecdsa.PublicKey
+	if id := ctx.String(auditNodeFlag.Name); id != "" {
+		nodeID, err := discover.HexID(id)
+		if err != nil {
+			utils.Fatalf("Invalid node id %s: %v", id, err)
+		}
+
+		signer, err = nodeID.Pubkey()
+		if err != nil {
+			utils.Fatalf("Invalid node id %s: %v", id, err)
+		}
+	}
+
+	count, signer, err := audit.Verify(path, signer)
+	if err != nil {
+		utils.Fatalf("Audit log %s verify fail: %v", path, err)
+	}
+
+	if signer == nil {
+		fmt.Printf("Audit log %s verified, no record\n", path)
+		return nil
+	}
+
+	fmt.Printf("Audit log %s verified, %d records signed by node %x\n", path, count, discover.PubkeyID(signer).Bytes())
+	return nil
+}
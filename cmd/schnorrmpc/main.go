@@ -69,6 +69,11 @@ var (
 		utils.CheckGpkFlag,
 	}
 
+	metricsFlags = []cli.Flag{
+		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+	}
+
 	rpcFlags = []cli.Flag{
 		utils.RPCEnabledFlag,
 		utils.RPCListenAddrFlag,
@@ -123,6 +128,8 @@ func init() {
 		// See config.go
 		dumpConfigCommand,
 		accountCommand,
+		// See auditcmd.go
+		auditCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -131,6 +138,7 @@ func init() {
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, syslogFlags...)
 	app.Flags = append(app.Flags, schnorrFlags...)
+	app.Flags = append(app.Flags, metricsFlags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -139,6 +147,9 @@ func init() {
 		}
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
+		if addr := ctx.GlobalString(utils.MetricsHTTPFlag.Name); addr != "" {
+			metrics.StartPrometheusServer(addr)
+		}
 
 		utils.SetupNetwork(ctx)
 		return nil
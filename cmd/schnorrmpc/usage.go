@@ -128,6 +128,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.SyslogTagFlag,
 		}, debug.Flags...),
 	},
+	{
+		Name:  "METRICS",
+		Flags: metricsFlags,
+	},
 	{
 		Name:  "WHISPER (EXPERIMENTAL)",
 		Flags: whisperFlags,
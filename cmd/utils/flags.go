@@ -131,6 +131,11 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  metrics.MetricsHTTPFlag,
+		Usage: "Enable metrics collection and serve them in Prometheus format on the HTTP address (e.g. 127.0.0.1:6060), at /metrics",
+		Value: "",
+	}
 
 	// RPC settings
 	RPCEnabledFlag = cli.BoolFlag{
@@ -20,6 +20,7 @@ package metrics
 import (
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +32,10 @@ import (
 // MetricsEnabledFlag is the CLI flag name to use to enable metrics collections.
 const MetricsEnabledFlag = "metrics"
 
+// MetricsHTTPFlag is the CLI flag name of the Prometheus endpoint address, which
+// enables metrics collections as well.
+const MetricsHTTPFlag = "metrics.addr"
+
 // Enabled is the flag specifying if metrics are enable or not.
 var Enabled = false
 
@@ -39,7 +44,8 @@ var Enabled = false
 // and peek into the command line args for the metrics flag.
 func init() {
 	for _, arg := range os.Args {
-		if strings.TrimLeft(arg, "-") == MetricsEnabledFlag {
+		flag := strings.TrimLeft(arg, "-")
+		if flag == MetricsEnabledFlag || strings.HasPrefix(flag, MetricsHTTPFlag) {
 			log.Info("Enabling metrics collection")
 			Enabled = true
 		}
@@ -74,6 +80,29 @@ func NewTimer(name string) metrics.Timer {
 	return metrics.GetOrRegisterTimer(name, metrics.DefaultRegistry)
 }
 
+// NewGauge create a new metrics Gauge, either a real one of a NOP stub depending
+// on the metrics flag.
+func NewGauge(name string) metrics.Gauge {
+	if !Enabled {
+		return new(metrics.NilGauge)
+	}
+	return metrics.GetOrRegisterGauge(name, metrics.DefaultRegistry)
+}
+
+// LabeledName appends the label pairs to the metric name in the Prometheus notation,
+// name{key="value",...}, so that the exporter reports them as labels.
+func LabeledName(name string, labels ...string) string {
+	if len(labels) < 2 {
+		return name
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
 // CollectProcessMetrics periodically collects various metrics about the running
 // process.
 func CollectProcessMetrics(refresh time.Duration) {
@@ -0,0 +1,165 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/wanchain/schnorr-mpc/log"
+)
+
+// quantiles reported for the histograms and timers.
+var quantiles = []float64{0.5, 0.9, 0.99}
+
+// PrometheusHandler returns the handler which exposes the metrics of the registry
+// in the Prometheus text format.
+func PrometheusHandler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(w, reg)
+	})
+}
+
+// StartPrometheusServer serves the metrics of the default registry on addr/metrics.
+func StartPrometheusServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", PrometheusHandler(metrics.DefaultRegistry))
+
+	log.Info("Starting Prometheus metrics endpoint", "addr", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("Prometheus metrics endpoint failed", "err", err)
+		}
+	}()
+}
+
+// WritePrometheus writes the metrics of the registry in the Prometheus text format.
+// Counters and meters are reported as counters named with the _total suffix,
+// histograms and timers as summaries, the timers in seconds.
+func WritePrometheus(w io.Writer, reg metrics.Registry) {
+	families := make(map[string]*bytes.Buffer)
+	types := make(map[string]string)
+
+	reg.Each(func(name string, i interface{}) {
+		base, labels := splitLabels(name)
+		base = sanitizeName(base)
+
+		var (
+			kind string
+			buf  bytes.Buffer
+		)
+		switch metric := i.(type) {
+		case metrics.Counter:
+			kind = "counter"
+			base = counterName(base)
+			fmt.Fprintf(&buf, "%s%s %d\n", base, labels, metric.Count())
+		case metrics.Gauge:
+			kind = "gauge"
+			fmt.Fprintf(&buf, "%s%s %d\n", base, labels, metric.Value())
+		case metrics.GaugeFloat64:
+			kind = "gauge"
+			fmt.Fprintf(&buf, "%s%s %g\n", base, labels, metric.Value())
+		case metrics.Meter:
+			kind = "counter"
+			base = counterName(base)
+			fmt.Fprintf(&buf, "%s%s %d\n", base, labels, metric.Count())
+		case metrics.Histogram:
+			kind = "summary"
+			snapshot := metric.Snapshot()
+			writeSummary(&buf, base, labels, snapshot.Percentiles(quantiles), float64(snapshot.Sum()), snapshot.Count())
+		case metrics.Timer:
+			kind = "summary"
+			snapshot := metric.Snapshot()
+			ps := snapshot.Percentiles(quantiles)
+			for j := range ps {
+				ps[j] /= 1e9
+			}
+			writeSummary(&buf, base, labels, ps, float64(snapshot.Sum())/1e9, snapshot.Count())
+		default:
+			return
+		}
+
+		if _, ok := types[base]; !ok {
+			types[base] = kind
+			families[base] = new(bytes.Buffer)
+		}
+		if types[base] == kind {
+			families[base].Write(buf.Bytes())
+		}
+	})
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, types[name])
+		lines := strings.Split(strings.TrimSuffix(families[name].String(), "\n"), "\n")
+		sort.Strings(lines)
+		fmt.Fprintln(w, strings.Join(lines, "\n"))
+	}
+}
+
+func writeSummary(w io.Writer, base, labels string, ps []float64, sum float64, count int64) {
+	for i, q := range quantiles {
+		fmt.Fprintf(w, "%s%s %g\n", base, addLabel(labels, "quantile", fmt.Sprint(q)), ps[i])
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", base, labels, sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", base, labels, count)
+}
+
+// counterName appends the _total suffix which Prometheus expects of the counters.
+func counterName(name string) string {
+	if strings.HasSuffix(name, "_total") {
+		return name
+	}
+	return name + "_total"
+}
+
+// splitLabels splits the name created by LabeledName into the base name and the label block.
+func splitLabels(name string) (string, string) {
+	if i := strings.IndexByte(name, '{'); i > 0 && strings.HasSuffix(name, "}") {
+		return name[:i], name[i:]
+	}
+	return name, ""
+}
+
+func addLabel(labels, key, value string) string {
+	pair := fmt.Sprintf("%s=%q", key, value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// sanitizeName replaces the characters not allowed in Prometheus metric names.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter(LabeledName("storeman/mpc/failed", "protocol", "sign", "error", `bad "data"`), reg).Inc(2)
+	metrics.GetOrRegisterCounter(LabeledName("storeman/mpc/failed", "protocol", "gpk", "error", "timeout"), reg).Inc(1)
+	metrics.GetOrRegisterCounter("storeman/mpc/dropped_total", reg).Inc(4)
+	metrics.GetOrRegisterGauge("storeman/peers", reg).Update(3)
+	metrics.GetOrRegisterTimer(LabeledName("storeman/mpc/step", "step", "0"), reg).Update(2 * time.Second)
+
+	var buf bytes.Buffer
+	WritePrometheus(&buf, reg)
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE storeman_mpc_failed_total counter\n",
+		`storeman_mpc_failed_total{protocol="gpk",error="timeout"} 1` + "\n",
+		`storeman_mpc_failed_total{protocol="sign",error="bad \"data\""} 2` + "\n",
+		"# TYPE storeman_mpc_dropped_total counter\nstoreman_mpc_dropped_total 4\n",
+		"# TYPE storeman_peers gauge\nstoreman_peers 3\n",
+		"# TYPE storeman_mpc_step summary\n",
+		`storeman_mpc_step{step="0",quantile="0.5"} 2` + "\n",
+		`storeman_mpc_step_count{step="0"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+
+	if strings.Count(out, "# TYPE storeman_mpc_failed_total") != 1 {
+		t.Errorf("metric family reported more than once:\n%s", out)
+	}
+}
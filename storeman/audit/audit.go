@@ -0,0 +1,318 @@
+// Package audit implements the tamper-evident log of the mpc contexts run by a storeman.
+//
+// Every record is chained to the previous one by its hash and signed by the node key, so
+// modifying, inserting or removing a record breaks the chain, and the chain can not be
+// recomputed without the node key. Both are detected by Verify.
+//
+// Removing the last records leaves a valid chain, it is detected only by comparing the
+// log with a record known to be appended, e.g. one returned by storeman_auditLog before.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/log"
+)
+
+const FileName = "audit.log"
+
+var (
+	ErrBrokenChain   = errors.New("audit log chain is broken")
+	ErrClosed        = errors.New("audit log is closed")
+	ErrNoKey         = errors.New("audit log has no key to sign the records")
+	ErrInvalidSigner = errors.New("audit record is not signed by the node")
+)
+
+// Approval records how the signed data was approved.
+type Approval struct {
+	Required bool          `json:"required"`
+	Data     hexutil.Bytes `json:"data,omitempty"` // the approval record stored in the approval database
+}
+
+// Record is an entry of the audit log.
+type Record struct {
+	Index     uint64        `json:"index"`
+	Time      int64         `json:"time"`
+	ContextID uint64        `json:"contextId"`
+	Protocol  string        `json:"protocol"`
+	Leader    string        `json:"leader"`
+	GPK       hexutil.Bytes `json:"gpk,omitempty"`
+	MsgHash   common.Hash   `json:"msgHash"`
+	Extern    string        `json:"extern,omitempty"`
+	Peers     []string      `json:"peers"`
+	R         hexutil.Bytes `json:"r,omitempty"`
+	S         hexutil.Bytes `json:"s,omitempty"`
+	Approval  *Approval     `json:"approval,omitempty"`
+	Err       string        `json:"err,omitempty"`
+	PrevHash  common.Hash   `json:"prevHash"`
+	Hash      common.Hash   `json:"hash"`
+	Sig       hexutil.Bytes `json:"sig"` // signature of the node key over the hash
+}
+
+// SigHash returns the hash of the record, which covers every field except the hash and the signature.
+func (rec *Record) SigHash() (common.Hash, error) {
+	cpy := *rec
+	cpy.Hash = common.Hash{}
+	cpy.Sig = nil
+	enc, err := json.Marshal(&cpy)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// Log is an append only, hash chained log stored in a file, one JSON record per line.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	next uint64
+	last common.Hash
+
+	key    *ecdsa.PrivateKey
+	signer *ecdsa.PublicKey // signer of the existing records, nil if the log is empty
+}
+
+// Open opens the audit log at the path, creating it if it does not exist.
+// A torn record at the end of the file, left by a crash during its write, is truncated.
+// The existing records are verified before new records are appended, they must all be signed by the same key.
+func Open(path string) (*Log, error) {
+	if err := truncateTorn(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	next, last, signer, err := verifyFile(path, nil)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{path: path, file: file, next: next, last: last, signer: signer}, nil
+}
+
+// SetKey sets the node key signing the records, the existing records must be signed by the same key.
+func (l *Log) SetKey(key *ecdsa.PrivateKey) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.signer != nil && !bytes.Equal(crypto.FromECDSAPub(l.signer), crypto.FromECDSAPub(&key.PublicKey)) {
+		log.SyslogErr("audit log is signed by another key", "path", l.path)
+		return ErrInvalidSigner
+	}
+
+	l.key = key
+	return nil
+}
+
+// Append chains the record to the log and writes it to the disk.
+func (l *Log) Append(rec *Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+
+	if l.key == nil {
+		return ErrNoKey
+	}
+
+	rec.Index = l.next
+	rec.PrevHash = l.last
+	hash, err := rec.SigHash()
+	if err != nil {
+		return err
+	}
+
+	rec.Hash = hash
+	rec.Sig, err = crypto.Sign(hash[:], l.key)
+	if err != nil {
+		return err
+	}
+
+	enc, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if _, err = l.file.Write(append(enc, '\n')); err != nil {
+		log.SyslogErr("audit log write fail", "err", err.Error())
+		return err
+	}
+
+	if err = l.file.Sync(); err != nil {
+		return err
+	}
+
+	l.next++
+	l.last = hash
+	return nil
+}
+
+// Records returns the records with time in [from, to], to is ignored if it is zero.
+// If gpk is not empty, only the records of the gpk are returned.
+func (l *Log) Records(from, to int64, gpk []byte) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := make([]Record, 0)
+	err := readFile(l.path, func(rec *Record) error {
+		if rec.Time < from || (to != 0 && rec.Time > to) {
+			return nil
+		}
+
+		if len(gpk) != 0 && !bytes.Equal(gpk, rec.GPK) {
+			return nil
+		}
+
+		records = append(records, *rec)
+		return nil
+	})
+
+	return records, err
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// truncateTorn truncates the last line of the file if it is not terminated by a newline.
+// Append writes a record with its newline at once, so such a line is a record whose write
+// did not complete, and which was never reported as appended.
+func truncateTorn(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		n := int64(len(buf))
+		if end < n {
+			n = end
+		}
+
+		if _, err := file.ReadAt(buf[:n], end-n); err != nil {
+			return err
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = end - n + int64(i) + 1
+			break
+		}
+		end -= n
+	}
+
+	if end == size {
+		return nil
+	}
+
+	log.SyslogWarning("audit log ends with a torn record, truncate it", "path", path, "bytes", size-end)
+	if err := file.Truncate(end); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Verify checks the chain and the signatures of the audit log file, and returns the number of records
+// and their signer. If signer is nil, the records must be signed by the signer of the first record.
+func Verify(path string, signer *ecdsa.PublicKey) (uint64, *ecdsa.PublicKey, error) {
+	count, _, signer, err := verifyFile(path, signer)
+	return count, signer, err
+}
+
+func verifyFile(path string, signer *ecdsa.PublicKey) (uint64, common.Hash, *ecdsa.PublicKey, error) {
+	var (
+		next uint64
+		last common.Hash
+	)
+
+	err := readFile(path, func(rec *Record) error {
+		hash, err := rec.SigHash()
+		if err != nil {
+			return err
+		}
+
+		if rec.Index != next || rec.PrevHash != last || rec.Hash != hash {
+			return fmt.Errorf("%v at record %d", ErrBrokenChain, next)
+		}
+
+		pub, err := crypto.SigToPub(hash[:], rec.Sig)
+		if err != nil {
+			return fmt.Errorf("%v at record %d: %v", ErrInvalidSigner, next, err)
+		}
+
+		if signer == nil {
+			signer = pub
+		} else if !bytes.Equal(crypto.FromECDSAPub(signer), crypto.FromECDSAPub(pub)) {
+			return fmt.Errorf("%v at record %d", ErrInvalidSigner, next)
+		}
+
+		next++
+		last = hash
+		return nil
+	})
+
+	return next, last, signer, err
+}
+
+func readFile(path string, fn func(*Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%v: invalid record at line %d: %v", ErrBrokenChain, line, err)
+		}
+
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,164 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storeman-audit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	path := filepath.Join(dir, FileName)
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Append(&Record{Time: 99}); err != ErrNoKey {
+		t.Fatalf("record appended without key, err: %v", err)
+	}
+	l.SetKey(key)
+
+	gpk := []byte{0x04, 0x01}
+	for i := 0; i < 3; i++ {
+		rec := &Record{Time: int64(100 + i), ContextID: uint64(i), Protocol: "sign", MsgHash: common.Hash{byte(i)}}
+		if i != 1 {
+			rec.GPK = gpk
+		}
+
+		if err := l.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	// reopen and continue the chain, only with the same key
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := crypto.GenerateKey()
+	if err := l.SetKey(other); err != ErrInvalidSigner {
+		t.Fatalf("log continued with another key, err: %v", err)
+	}
+
+	if err := l.SetKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Append(&Record{Time: 103, ContextID: 3, Protocol: "gpk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := l.Records(101, 0, nil)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("records from time 101: %d, err: %v", len(records), err)
+	}
+
+	records, err = l.Records(0, 102, gpk)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("records of gpk: %d, err: %v", len(records), err)
+	}
+	l.Close()
+
+	count, signer, err := Verify(path, nil)
+	if err != nil || count != 4 || signer == nil || signer.X.Cmp(key.PublicKey.X) != 0 {
+		t.Fatalf("verify: %d records, err: %v", count, err)
+	}
+
+	if _, _, err := Verify(path, &key.PublicKey); err != nil {
+		t.Fatalf("verify with the node key: %v", err)
+	}
+
+	if _, _, err := Verify(path, &other.PublicKey); err == nil || !strings.Contains(err.Error(), ErrInvalidSigner.Error()) {
+		t.Fatalf("log verified with another key, err: %v", err)
+	}
+
+	// tamper a record
+	content, _ := ioutil.ReadFile(path)
+	tampered := strings.Replace(string(content), `"contextId":1`, `"contextId":9`, 1)
+	ioutil.WriteFile(path, []byte(tampered), 0600)
+	if _, _, err := Verify(path, nil); err == nil || !strings.Contains(err.Error(), ErrBrokenChain.Error()) {
+		t.Fatalf("tampered log verified, err: %v", err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Fatal("tampered log opened")
+	}
+
+	// remove a record
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	removed := strings.Join(append(lines[:1], lines[2:]...), "\n") + "\n"
+	ioutil.WriteFile(path, []byte(removed), 0600)
+	if _, _, err := Verify(path, nil); err == nil {
+		t.Fatal("log with removed record verified")
+	}
+
+	// recompute the chain after tampering a record, without the node key
+	os.Remove(path)
+	l, _ = Open(path)
+	l.SetKey(other)
+	for i := 0; i < 4; i++ {
+		l.Append(&Record{Time: int64(100 + i), ContextID: uint64(i), Protocol: "sign"})
+	}
+	l.Close()
+
+	if _, _, err := Verify(path, &key.PublicKey); err == nil || !strings.Contains(err.Error(), ErrInvalidSigner.Error()) {
+		t.Fatalf("recomputed log verified, err: %v", err)
+	}
+}
+
+func TestAuditLogTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storeman-audit-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	path := filepath.Join(dir, FileName)
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetKey(key)
+	for i := 0; i < 2; i++ {
+		if err := l.Append(&Record{Time: int64(i), ContextID: uint64(i), Protocol: "sign"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	// a crash in the middle of writing the third record
+	content, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, append(content, []byte(`{"index":2,"time":2,"contex`)...), 0600)
+	if _, _, err := Verify(path, nil); err == nil {
+		t.Fatal("torn log verified")
+	}
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("torn log is not opened: %v", err)
+	}
+	l.SetKey(key)
+	if err := l.Append(&Record{Time: 2, ContextID: 2, Protocol: "sign"}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	if count, _, err := Verify(path, &key.PublicKey); err != nil || count != 3 {
+		t.Fatalf("verify: %d records, err: %v", count, err)
+	}
+}
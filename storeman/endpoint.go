@@ -0,0 +1,149 @@
+package storeman
+
+import (
+	"crypto/ecdsa"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/rlp"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	endpointTTL       = time.Hour        // lifetime of an endpoint record
+	endpointRefresh   = 10 * time.Minute // a node signs a new record of itself after this interval
+	endpointClockSkew = time.Minute      // tolerated clock difference between the storeman nodes
+)
+
+// EndpointRecord is the endpoint of a storeman node signed by its node key. Any member can relay
+// the records of others, since a relay can not forge them.
+type EndpointRecord struct {
+	Enode     string // enode url of the node
+	Timestamp uint64 // unix time the record is signed, the newer record wins
+	Expiry    uint64 // unix time the record expires
+	Signature []byte
+}
+
+func (rec *EndpointRecord) sigHash() (common.Hash, error) {
+	enc, err := rlp.EncodeToBytes([]interface{}{rec.Enode, rec.Timestamp, rec.Expiry})
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// newEndpointRecord signs the endpoint of the node.
+func newEndpointRecord(self *discover.Node, prv *ecdsa.PrivateKey, now time.Time) (*EndpointRecord, error) {
+	rec := &EndpointRecord{
+		Enode:     self.String(),
+		Timestamp: uint64(now.Unix()),
+		Expiry:    uint64(now.Add(endpointTTL).Unix()),
+	}
+
+	hash, err := rec.sigHash()
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Signature, err = crypto.Sign(hash[:], prv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// verify checks the record is signed by the node in the enode url and is in its lifetime.
+func (rec *EndpointRecord) verify(now time.Time) (*discover.Node, error) {
+	node, err := discover.ParseNode(rec.Enode)
+	if err != nil || node.Incomplete() {
+		return nil, mpcprotocol.ErrInvalidEndpoint
+	}
+
+	if rec.Expiry <= uint64(now.Unix()) {
+		return nil, mpcprotocol.ErrEndpointExpired
+	}
+
+	if rec.Timestamp > uint64(now.Add(endpointClockSkew).Unix()) || rec.Expiry > rec.Timestamp+uint64(endpointTTL/time.Second) {
+		return nil, mpcprotocol.ErrInvalidEndpoint
+	}
+
+	hash, err := rec.sigHash()
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := crypto.SigToPub(hash[:], rec.Signature)
+	if err != nil || discover.PubkeyID(pub) != node.ID {
+		return nil, mpcprotocol.ErrInvalidEndpoint
+	}
+
+	return node, nil
+}
+
+// endpointTable keeps the newest valid endpoint record of every storeman node.
+type endpointTable struct {
+	mu      sync.RWMutex
+	records map[discover.NodeID]*EndpointRecord
+	nodes   map[discover.NodeID]*discover.Node
+}
+
+func newEndpointTable() *endpointTable {
+	return &endpointTable{
+		records: make(map[discover.NodeID]*EndpointRecord),
+		nodes:   make(map[discover.NodeID]*discover.Node),
+	}
+}
+
+// add verifies the record and keeps it if it is newer than the known one of the node.
+// isMember decides whose records are accepted.
+func (tab *endpointTable) add(rec *EndpointRecord, now time.Time, isMember func(discover.NodeID) bool) (bool, error) {
+	node, err := rec.verify(now)
+	if err != nil {
+		return false, err
+	}
+
+	if !isMember(node.ID) {
+		return false, mpcprotocol.ErrNotMpcParticipant
+	}
+
+	tab.mu.Lock()
+	defer tab.mu.Unlock()
+	if old, exist := tab.records[node.ID]; exist && old.Timestamp >= rec.Timestamp {
+		return false, nil
+	}
+
+	tab.records[node.ID] = rec
+	tab.nodes[node.ID] = node
+	return true, nil
+}
+
+// node returns the endpoint of the node from its unexpired record.
+func (tab *endpointTable) node(id discover.NodeID, now time.Time) *discover.Node {
+	tab.mu.RLock()
+	defer tab.mu.RUnlock()
+	rec, exist := tab.records[id]
+	if !exist || rec.Expiry <= uint64(now.Unix()) {
+		return nil
+	}
+
+	return tab.nodes[id]
+}
+
+// valid returns all the unexpired records.
+func (tab *endpointTable) valid(now time.Time) []EndpointRecord {
+	tab.mu.RLock()
+	defer tab.mu.RUnlock()
+	records := make([]EndpointRecord, 0, len(tab.records))
+	for _, rec := range tab.records {
+		if rec.Expiry > uint64(now.Unix()) {
+			records = append(records, *rec)
+		}
+	}
+
+	return records
+}
@@ -0,0 +1,70 @@
+package storeman
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestEndpointRecord(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	self := discover.NewNode(discover.PubkeyID(&prv.PublicKey), net.ParseIP("10.0.0.1"), 30303, 30303)
+	now := time.Now()
+
+	isMember := func(id discover.NodeID) bool { return id == self.ID }
+	tab := newEndpointTable()
+
+	rec, err := newEndpointRecord(self, prv, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added, err := tab.add(rec, now, isMember); !added || err != nil {
+		t.Fatalf("valid record is not added, err: %v", err)
+	}
+
+	if node := tab.node(self.ID, now); node == nil || node.String() != self.String() {
+		t.Fatalf("endpoint of the node: %v", node)
+	}
+
+	// an older record does not replace the newer one
+	old, _ := newEndpointRecord(self, prv, now.Add(-time.Minute))
+	if added, _ := tab.add(old, now, isMember); added {
+		t.Fatal("older record replaced the newer one")
+	}
+
+	// a record relayed with a forged address
+	forged := *rec
+	forged.Enode = discover.NewNode(self.ID, net.ParseIP("10.0.0.2"), 30303, 30303).String()
+	if _, err := tab.add(&forged, now, isMember); err != mpcprotocol.ErrInvalidEndpoint {
+		t.Fatalf("forged record, err: %v", err)
+	}
+
+	// a record signed by another key
+	fake, _ := newEndpointRecord(self, other, now)
+	if _, err := tab.add(fake, now, isMember); err != mpcprotocol.ErrInvalidEndpoint {
+		t.Fatalf("record signed by another key, err: %v", err)
+	}
+
+	// a record of a node which is not a member
+	stranger := discover.NewNode(discover.PubkeyID(&other.PublicKey), net.ParseIP("10.0.0.3"), 30303, 30303)
+	strangerRec, _ := newEndpointRecord(stranger, other, now)
+	if _, err := tab.add(strangerRec, now, isMember); err != mpcprotocol.ErrNotMpcParticipant {
+		t.Fatalf("record of a stranger, err: %v", err)
+	}
+
+	// expired records are neither accepted nor returned
+	later := now.Add(endpointTTL + time.Second)
+	if _, err := tab.add(rec, later, isMember); err != mpcprotocol.ErrEndpointExpired {
+		t.Fatalf("expired record, err: %v", err)
+	}
+
+	if tab.node(self.ID, later) != nil || len(tab.valid(later)) != 0 {
+		t.Fatal("expired record is returned")
+	}
+}
@@ -0,0 +1,144 @@
+package storeman
+
+import (
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/storeman/storemanmpc"
+	"github.com/wanchain/schnorr-mpc/storeman/validator"
+)
+
+// The verdicts of the storeman health.
+const (
+	HealthReady    = "ready"    // all the configured members are connected and every gpk can sign
+	HealthDegraded = "degraded" // some members are missing, but at least one gpk can still sign
+	HealthDown     = "down"     // the node can not take part in signing
+)
+
+// PeerHealth is the state of a configured storeman member.
+type PeerHealth struct {
+	ID         discover.NodeID `json:"id"`
+	Connected  bool            `json:"connected"`
+	Handshaked bool            `json:"handshaked"`
+	Since      int64           `json:"since,omitempty"`    // unix time the connection is established
+	LastSeen   int64           `json:"lastSeen,omitempty"` // unix time of the last message received
+	RTT        time.Duration   `json:"rtt,omitempty"`      // rtt used to select the signing peers
+	Keepalive  *KeepaliveStats `json:"keepalive,omitempty"`
+}
+
+// Health is the health report returned by storeman_health.
+type Health struct {
+	Status    string                        `json:"status"`
+	Reasons   []string                      `json:"reasons"`
+	Peers     []PeerHealth                  `json:"peers"`
+	Live      int                           `json:"live"` // live members including this node
+	Threshold int                           `json:"threshold"`
+	Quorums   []storemanmpc.MpcGPKQuorum    `json:"quorums"`
+	Keystore  storemanmpc.MpcKeystoreStatus `json:"keystore"`
+	DB        string                        `json:"db"`
+}
+
+// isLivePeer reports whether the peer is connected and has finished the handshake.
+func (sm *Storeman) isLivePeer(peerID *discover.NodeID) bool {
+	sm.peerMu.RLock()
+	defer sm.peerMu.RUnlock()
+	peer, exist := sm.peers[*peerID]
+	return exist && peer.Handshaked()
+}
+
+// liveMembers counts the live members of the group, including this node.
+func (sm *Storeman) liveMembers(group *storemanmpc.MpcGroup) int {
+	live := 0
+	for i := range group.Members {
+		if group.Members[i] == sm.server.Self().ID || sm.isLivePeer(&group.Members[i]) {
+			live++
+		}
+	}
+
+	return live
+}
+
+func (sm *Storeman) health() *Health {
+	h := &Health{
+		Status:    HealthReady,
+		Reasons:   make([]string, 0),
+		Peers:     make([]PeerHealth, 0, len(sm.storemanPeers)),
+		Live:      1,
+		Threshold: sm.cfg.SchnorrThreshold,
+		DB:        "ok",
+	}
+
+	degrade := func(reason string) {
+		if h.Status == HealthReady {
+			h.Status = HealthDegraded
+		}
+		h.Reasons = append(h.Reasons, reason)
+	}
+	down := func(reason string) {
+		h.Status = HealthDown
+		h.Reasons = append(h.Reasons, reason)
+	}
+
+	self := sm.server.Self().ID
+	missing := 0
+	sm.peerMu.RLock()
+	for id := range sm.storemanPeers {
+		if id == self {
+			continue
+		}
+
+		ph := PeerHealth{ID: id}
+		if peer, exist := sm.peers[id]; exist {
+			ph.Connected = true
+			ph.Handshaked = peer.Handshaked()
+			ph.Since = peer.connected.Unix()
+			if ph.Handshaked {
+				ph.LastSeen = peer.LastSeen().Unix()
+				stats := peer.keepalive.Stats()
+				ph.Keepalive = &stats
+			}
+		}
+		ph.RTT, _ = sm.mpcDistributor.Latency.RTT(&id)
+
+		if ph.Handshaked {
+			h.Live++
+		} else {
+			missing++
+		}
+		h.Peers = append(h.Peers, ph)
+	}
+	sm.peerMu.RUnlock()
+
+	if err := validator.Status(); err != nil {
+		h.DB = err.Error()
+		down("storeman database is unavailable")
+	}
+
+	h.Keystore = sm.mpcDistributor.KeystoreStatus()
+	if h.Keystore.Err != "" {
+		down("keystore can not be decrypted")
+	}
+
+	if missing != 0 {
+		degrade("storeman members are not connected")
+	}
+
+	h.Quorums = sm.mpcDistributor.GPKQuorums(sm.isLivePeer)
+	reachable := 0
+	for _, quorum := range h.Quorums {
+		if quorum.Reachable {
+			reachable++
+		}
+	}
+
+	switch {
+	case len(h.Quorums) == 0 && h.Live < h.Threshold:
+		down("live members are less than the threshold")
+	case len(h.Quorums) != 0 && reachable == 0:
+		down("no gpk can reach a signing quorum")
+	case reachable < len(h.Quorums):
+		degrade("some gpks can not reach a signing quorum")
+	}
+
+	return h
+}
@@ -0,0 +1,96 @@
+package storeman
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultKeepaliveMissed = 3 // a peer is evicted after missing so many pongs in a row
+	rttAlpha               = 8 // weight of the old rtt in the moving average, rtt = (7*rtt + sample)/8
+	jitterAlpha            = 16
+)
+
+// KeepaliveStats is the keepalive state of a storeman peer.
+type KeepaliveStats struct {
+	RTT      time.Duration `json:"rtt"`    // moving average of the ping rtt
+	Jitter   time.Duration `json:"jitter"` // moving average of the rtt variation
+	LastPong int64         `json:"lastPong,omitempty"`
+	Missed   int           `json:"missed"` // pongs missed in a row
+	Sent     uint64        `json:"sent"`
+	Received uint64        `json:"received"`
+}
+
+// keepalive tracks the pings sent to a peer and measures the rtt from the pongs.
+type keepalive struct {
+	mu      sync.Mutex
+	nonce   uint64
+	pending map[uint64]time.Time // nonce of the unanswered pings -> sent time
+	lastRTT time.Duration
+	stats   KeepaliveStats
+}
+
+func newKeepalive() *keepalive {
+	return &keepalive{pending: make(map[uint64]time.Time)}
+}
+
+// ping expires the pings not answered in timeout, and returns the nonce of a new ping.
+func (k *keepalive) ping(now time.Time, timeout time.Duration) uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for nonce, sent := range k.pending {
+		if now.Sub(sent) >= timeout {
+			delete(k.pending, nonce)
+			k.stats.Missed++
+		}
+	}
+
+	k.nonce++
+	k.pending[k.nonce] = now
+	k.stats.Sent++
+	return k.nonce
+}
+
+// pong updates the rtt statistics, it returns false if the nonce is unknown or expired.
+func (k *keepalive) pong(nonce uint64, now time.Time) (time.Duration, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	sent, exist := k.pending[nonce]
+	if !exist {
+		return 0, false
+	}
+	delete(k.pending, nonce)
+
+	rtt := now.Sub(sent)
+	if k.stats.Received == 0 {
+		k.stats.RTT = rtt
+	} else {
+		k.stats.RTT = (k.stats.RTT*(rttAlpha-1) + rtt) / rttAlpha
+
+		diff := rtt - k.lastRTT
+		if diff < 0 {
+			diff = -diff
+		}
+		k.stats.Jitter += (diff - k.stats.Jitter) / jitterAlpha
+	}
+
+	k.lastRTT = rtt
+	k.stats.Received++
+	k.stats.Missed = 0
+	k.stats.LastPong = now.Unix()
+	return rtt, true
+}
+
+func (k *keepalive) missed() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.stats.Missed
+}
+
+func (k *keepalive) Stats() KeepaliveStats {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.stats
+}
@@ -0,0 +1,47 @@
+package storeman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeepalive(t *testing.T) {
+	k := newKeepalive()
+	now := time.Now()
+	timeout := 10 * time.Second
+
+	nonce := k.ping(now, timeout)
+	if _, ok := k.pong(nonce+1, now); ok {
+		t.Fatal("pong with an unknown nonce accepted")
+	}
+
+	rtt, ok := k.pong(nonce, now.Add(100*time.Millisecond))
+	if !ok || rtt != 100*time.Millisecond {
+		t.Fatalf("rtt: %v, ok: %v", rtt, ok)
+	}
+
+	if _, ok := k.pong(nonce, now.Add(time.Second)); ok {
+		t.Fatal("pong accepted twice")
+	}
+
+	nonce = k.ping(now, timeout)
+	k.pong(nonce, now.Add(300*time.Millisecond))
+	stats := k.Stats()
+	if stats.RTT != 125*time.Millisecond || stats.Jitter != 12500*time.Microsecond || stats.Received != 2 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	// pings not answered in time are missed
+	k.ping(now, timeout)
+	k.ping(now.Add(timeout), timeout)
+	k.ping(now.Add(2*timeout), timeout)
+	if k.missed() != 2 {
+		t.Fatalf("missed: %d", k.missed())
+	}
+
+	nonce = k.ping(now.Add(2*timeout), timeout)
+	k.pong(nonce, now.Add(2*timeout+time.Millisecond))
+	if k.missed() != 0 {
+		t.Fatalf("missed is not reset by a pong: %d", k.missed())
+	}
+}
@@ -0,0 +1,13 @@
+// Contains the meters of the storeman peers.
+
+package storeman
+
+import (
+	"github.com/wanchain/schnorr-mpc/metrics"
+)
+
+var (
+	connectedPeersGauge  = metrics.NewGauge("storeman/peers/connected")
+	configuredPeersGauge = metrics.NewGauge("storeman/peers/configured")
+	thresholdGauge       = metrics.NewGauge("storeman/peers/threshold")
+)
@@ -6,6 +6,7 @@ import (
 	"github.com/wanchain/schnorr-mpc/p2p"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	"github.com/wanchain/schnorr-mpc/rlp"
+	"sync/atomic"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	set "gopkg.in/fatih/set.v0"
 	"time"
@@ -20,18 +21,25 @@ type Peer struct {
 
 	known *set.Set // Messages already known by the peer to avoid wasting bandwidth
 
+	connected  time.Time
+	handshaked int32 // set to 1 once the handshake succeeds
+	lastSeen   int64 // unix nano time of the last message received from the peer
+	keepalive  *keepalive
+
 	quit chan struct{}
 }
 
 // newPeer creates a new whisper peer object, but does not run the handshake itself.
 func newPeer(host *Storeman, remote *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
 	return &Peer{
-		host:    host,
-		Peer:    remote,
-		ws:      rw,
-		trusted: false,
-		known:   set.New(),
-		quit:    make(chan struct{}),
+		host:      host,
+		Peer:      remote,
+		ws:        rw,
+		trusted:   false,
+		known:     set.New(),
+		connected: time.Now(),
+		keepalive: newKeepalive(),
+		quit:      make(chan struct{}),
 	}
 }
 
@@ -39,21 +47,27 @@ func newPeer(host *Storeman, remote *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
 // into the network.
 func (p *Peer) start() {
 	log.SyslogInfo("storeman peer start", "peer", p.ID().String())
+	go p.update()
 }
 
-// update executes periodic operations on the peer, including message transmission
-// and expiration.
+// update executes periodic operations on the peer, including the keepalive pings and
+// the eviction of the peer which misses too many pongs.
 func (p *Peer) update() {
 	// Start the tickers for the updates
 	keepalive := time.NewTicker(mpcprotocol.KeepaliveCycle * time.Second)
+	defer keepalive.Stop()
 
 	// Loop and transmit until termination is requested
 	for {
+		p.sendKeepalive()
+		if missed := p.keepalive.missed(); missed >= p.host.keepaliveMissed() {
+			log.SyslogWarning("storeman peer does not answer keepalive, evict it", "peer", p.ID().String(), "missed", missed)
+			p.Peer.Disconnect(p2p.DiscReadTimeout)
+			return
+		}
+
 		select {
 		case <-keepalive.C:
-
-			p.sendKeepalive()
-
 		case <-p.quit:
 			return
 		}
@@ -67,16 +81,37 @@ func (p *Peer) stop() {
 }
 
 func (p *Peer) sendKeepalive() {
-	p2p.Send(p.ws, mpcprotocol.KeepaliveCode, StrmanKeepAlive{version: 1, magic: keepaliveMagic, recipient: p.Peer.ID()})
+	now := time.Now()
+	nonce := p.keepalive.ping(now, mpcprotocol.KeepaliveCycle*time.Second)
+	p2p.Send(p.ws, mpcprotocol.KeepaliveCode, StrmanKeepAlive{
+		Version:   1,
+		Magic:     keepaliveMagic,
+		Nonce:     nonce,
+		Timestamp: uint64(now.UnixNano()),
+		Recipient: p.Peer.ID()})
+}
+
+func (p *Peer) sendKeepaliveOk(ping *StrmanKeepAlive) {
+	p2p.Send(p.ws, mpcprotocol.KeepaliveOkCode, StrmanKeepAliveOk{
+		Version:   1,
+		Magic:     keepaliveMagic,
+		Nonce:     ping.Nonce,
+		Timestamp: ping.Timestamp,
+		Status:    0})
 }
 
-func (p *Peer) sendKeepaliveOk() {
-	p2p.Send(p.ws, mpcprotocol.KeepaliveOkCode, StrmanKeepAliveOk{version: 1, magic: keepaliveMagic, status: 0})
+// handlePong measures the rtt of the ping answered by the pong.
+func (p *Peer) handlePong(pong *StrmanKeepAliveOk) (time.Duration, bool) {
+	if pong.Magic != keepaliveMagic {
+		return 0, false
+	}
+
+	return p.keepalive.pong(pong.Nonce, time.Now())
 }
 
 
-func (p *Peer) sendAllpeers(allp *StrmanAllPeers) {
-	p2p.Send(p.ws, mpcprotocol.AllPeersInfo, *allp)
+func (p *Peer) sendEndpoints(records []EndpointRecord) {
+	p2p.Send(p.ws, mpcprotocol.EndpointRecords, records)
 }
 
 // handshake sends the protocol initiation status message to the remote peer and
@@ -170,6 +205,24 @@ func (p *Peer) handshake() error {
 	return nil
 }
 
+// Handshaked reports whether the handshake with the peer succeeded.
+func (p *Peer) Handshaked() bool {
+	return atomic.LoadInt32(&p.handshaked) == 1
+}
+
+func (p *Peer) setHandshaked() {
+	atomic.StoreInt32(&p.handshaked, 1)
+}
+
+// LastSeen returns the time of the last message received from the peer.
+func (p *Peer) LastSeen() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&p.lastSeen))
+}
+
+func (p *Peer) markSeen() {
+	atomic.StoreInt64(&p.lastSeen, time.Now().UnixNano())
+}
+
 func (p *Peer) ID() discover.NodeID {
 	id := p.Peer.ID()
 	return id
@@ -0,0 +1,154 @@
+package storeman
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	initialPeerScore     = 100
+	DefaultPeerBanScore  = 0                // a peer is banned when its score drops to it
+	DefaultPeerBanPeriod = 30 * time.Minute // how long a banned peer is refused
+	defaultPenalty       = 10
+	peerScoreRecovery    = 10 * time.Minute // a peer regains a point of its score every interval, up to the initial score
+)
+
+// penalties decremented from the score of a peer, keyed by its misbehaviour.
+var penalties = map[error]int{
+	mpcprotocol.ErrMalformedMessage:     20,
+	mpcprotocol.ErrOversizedMessage:     20,
+	mpcprotocol.ErrInvalidMsgSignature:  30,
+	mpcprotocol.ErrInvalidRelay:         20,
+	mpcprotocol.ErrInvalidEndpoint:      10,
+	mpcprotocol.ErrDuplicateStepMessage: 10,
+	mpcprotocol.ErrInconsistentGPK:      50,
+	mpcprotocol.ErrInvalidMPCR:          50,
+	mpcprotocol.ErrInvalidMPCS:          50,
+}
+
+// PeerScore is the reputation of a storeman peer returned by rpc.
+type PeerScore struct {
+	ID          discover.NodeID `json:"id"`
+	Score       int             `json:"score"`
+	LastOffence string          `json:"lastOffence,omitempty"`
+	BannedUntil int64           `json:"bannedUntil,omitempty"` // unix time, zero if the peer is not banned
+
+	recovered time.Time // the score has regained the points up to this time
+}
+
+// recover regains the points of the score since its last recovery, so rare offences spread over
+// a long time never ban a peer.
+func (score *PeerScore) recover(now time.Time) {
+	points := int(now.Sub(score.recovered) / peerScoreRecovery)
+	if points <= 0 {
+		return
+	}
+
+	score.recovered = score.recovered.Add(time.Duration(points) * peerScoreRecovery)
+	if score.Score += points; score.Score >= initialPeerScore {
+		score.Score, score.recovered = initialPeerScore, now
+	}
+}
+
+// reputation scores the storeman peers by their misbehaviours, and bans the peers whose score drops to
+// the ban score for the ban period.
+type reputation struct {
+	mu       sync.Mutex
+	scores   map[discover.NodeID]*PeerScore
+	banScore int
+	period   time.Duration
+}
+
+func newReputation(banScore int, period time.Duration) *reputation {
+	if period <= 0 {
+		period = DefaultPeerBanPeriod
+	}
+
+	return &reputation{scores: make(map[discover.NodeID]*PeerScore), banScore: banScore, period: period}
+}
+
+func (r *reputation) score(id discover.NodeID, now time.Time) *PeerScore {
+	score, exist := r.scores[id]
+	if !exist {
+		score = &PeerScore{ID: id, Score: initialPeerScore, recovered: now}
+		r.scores[id] = score
+	}
+
+	return score
+}
+
+// penalize decrements the score of the peer, and returns true if the peer gets banned by it.
+func (r *reputation) penalize(id discover.NodeID, offence error, now time.Time) bool {
+	penalty, exist := penalties[offence]
+	if !exist {
+		penalty = defaultPenalty
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score := r.score(id, now)
+	if score.BannedUntil > now.Unix() {
+		return false
+	}
+
+	score.recover(now)
+	score.Score -= penalty
+	score.LastOffence = offence.Error()
+	if score.Score > r.banScore {
+		return false
+	}
+
+	score.BannedUntil = now.Add(r.period).Unix()
+	return true
+}
+
+// banned reports whether the peer is banned, the score of a peer is restored when its ban expires.
+func (r *reputation) banned(id discover.NodeID, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	score, exist := r.scores[id]
+	if !exist || score.BannedUntil == 0 {
+		return false
+	}
+
+	if score.BannedUntil > now.Unix() {
+		return true
+	}
+
+	score.Score, score.BannedUntil, score.recovered = initialPeerScore, 0, now
+	return false
+}
+
+// clear restores the score and lifts the ban of the peer, or of all the peers if id is nil.
+func (r *reputation) clear(id *discover.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id == nil {
+		r.scores = make(map[discover.NodeID]*PeerScore)
+	} else {
+		delete(r.scores, *id)
+	}
+}
+
+func (r *reputation) list(now time.Time) []PeerScore {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scores := make([]PeerScore, 0, len(r.scores))
+	for _, score := range r.scores {
+		if score.BannedUntil <= now.Unix() {
+			score.recover(now)
+		}
+		scores = append(scores, *score)
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Score < scores[j].Score })
+
+	return scores
+}
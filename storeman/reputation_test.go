@@ -0,0 +1,84 @@
+package storeman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestReputation(t *testing.T) {
+	r := newReputation(DefaultPeerBanScore, time.Minute)
+	peer := discover.NodeID{1}
+	now := time.Now()
+
+	if r.penalize(peer, mpcprotocol.ErrDuplicateStepMessage, now) {
+		t.Fatal("peer banned by a small offence")
+	}
+
+	// 100 - 10 - 50 = 40
+	if r.penalize(peer, mpcprotocol.ErrInconsistentGPK, now) {
+		t.Fatal("peer banned above the ban score")
+	}
+
+	banned := false
+	for i := 0; i < 3 && !banned; i++ {
+		banned = r.penalize(peer, mpcprotocol.ErrInvalidMsgSignature, now)
+	}
+	if !banned || !r.banned(peer, now) {
+		t.Fatal("peer is not banned after its score drops to the ban score")
+	}
+
+	if scores := r.list(now); len(scores) != 1 || scores[0].BannedUntil != now.Add(time.Minute).Unix() {
+		t.Fatalf("scores: %+v", scores)
+	}
+
+	// the ban expires and the score is restored
+	if r.banned(peer, now.Add(time.Minute)) {
+		t.Fatal("peer is banned after the ban period")
+	}
+	if scores := r.list(now); scores[0].Score != initialPeerScore {
+		t.Fatalf("score is not restored: %+v", scores)
+	}
+
+	for !r.penalize(peer, mpcprotocol.ErrInconsistentGPK, now) {
+	}
+	r.clear(&peer)
+	if r.banned(peer, now) || len(r.list(now)) != 0 {
+		t.Fatal("ban is not cleared")
+	}
+}
+
+func TestReputationRecovery(t *testing.T) {
+	r := newReputation(DefaultPeerBanScore, time.Minute)
+	peer := discover.NodeID{1}
+	now := time.Now()
+
+	// a duplicate message every day for months never bans the peer
+	for day := 0; day < 100; day++ {
+		if r.penalize(peer, mpcprotocol.ErrDuplicateStepMessage, now.Add(time.Duration(day)*24*time.Hour)) {
+			t.Fatalf("peer banned by rare offences at day %d", day)
+		}
+	}
+
+	// the score regains a point every interval, up to the initial score
+	now = now.Add(100 * 24 * time.Hour)
+	r.clear(&peer)
+	r.penalize(peer, mpcprotocol.ErrInconsistentGPK, now)
+	if scores := r.list(now.Add(3*peerScoreRecovery + time.Second)); scores[0].Score != initialPeerScore-50+3 {
+		t.Fatalf("score is not recovered: %+v", scores)
+	}
+	if scores := r.list(now.Add(100 * peerScoreRecovery)); scores[0].Score != initialPeerScore {
+		t.Fatalf("score is recovered beyond the initial score: %+v", scores)
+	}
+
+	// frequent offences still ban the peer
+	banned := false
+	for i := 0; i < 20 && !banned; i++ {
+		banned = r.penalize(peer, mpcprotocol.ErrDuplicateStepMessage, now.Add(time.Duration(i)*time.Minute))
+	}
+	if !banned {
+		t.Fatal("peer is not banned by frequent offences")
+	}
+}
@@ -5,9 +5,7 @@ import (
 	"github.com/wanchain/schnorr-mpc/common"
 	"github.com/wanchain/schnorr-mpc/common/hexutil"
 	"github.com/wanchain/schnorr-mpc/rlp"
-	"net"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +17,7 @@ import (
 	"github.com/wanchain/schnorr-mpc/p2p"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	"github.com/wanchain/schnorr-mpc/rpc"
+	"github.com/wanchain/schnorr-mpc/storeman/audit"
 	"github.com/wanchain/schnorr-mpc/storeman/storemanmpc"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"github.com/wanchain/schnorr-mpc/storeman/validator"
@@ -30,36 +29,58 @@ type Config struct {
 	DataPath          string
 	SchnorrThreshold  int
 	SchnorrTotalNodes int
+	Groups            []GroupConfig // storeman groups besides the default group made of StoremanNodes
+	Timeouts          map[string]mpcprotocol.ProtocolTimeout // timeout configs keyed by mpc protocol name
+	SignExtraPeers    int                                    // peers selected to sign besides the threshold
+	KeepaliveMissed   int                                    // a peer is evicted after missing so many keepalive pongs in a row
+	PeerBanScore      int                                    // a misbehaving peer is banned when its score drops to it
+	PeerBanPeriod     time.Duration                          // how long a banned peer is disconnected and refused
+	ApprovalPolicy    string                                 // json file of the rules which approve, reject or send data to manual review
+	Operators         []common.Address                       // operators whose signatures approve the data
+	OperatorThreshold int                                    // distinct operator signatures needed to approve the data
+	ApprovingTTL      time.Duration                          // how long a data waits for approval, zero: validator.DefaultApprovingTTL
+	ApprovedTTL       time.Duration                          // how long an approval can be used to sign, zero: validator.DefaultApprovedTTL
+	RejectedTTL       time.Duration                          // how long a rejected data stays rejected, zero: validator.DefaultRejectedTTL
+	ExternalValidator validator.ExternalConfig               // local service which approves or rejects the data, empty URL: none
+	Limits            validator.LimitConfig                  // rolling signature and value limits of the gpks
+}
+
+// GroupConfig describes a storeman group hosted by this node.
+type GroupConfig struct {
+	ID         string
+	Nodes      []*discover.Node
+	Threshold  int
+	TotalNodes int
 }
 
 var DefaultConfig = Config{
 	StoremanNodes:     make([]*discover.Node, 0),
 	SchnorrThreshold:  26,
 	SchnorrTotalNodes: 50,
+	SignExtraPeers:    storemanmpc.DefaultExtraPeers,
+	KeepaliveMissed:   DefaultKeepaliveMissed,
+	PeerBanScore:      DefaultPeerBanScore,
+	PeerBanPeriod:     DefaultPeerBanPeriod,
 }
 
+// StrmanKeepAlive is the ping sent to a storeman peer every KeepaliveCycle seconds.
 type StrmanKeepAlive struct {
-	version   int
-	magic     int
-	recipient discover.NodeID
+	Version   uint64
+	Magic     uint64
+	Nonce     uint64
+	Timestamp uint64 // unix nano time of the sender
+	Recipient discover.NodeID
 }
 
+// StrmanKeepAliveOk is the pong which echoes the nonce and timestamp of the ping.
 type StrmanKeepAliveOk struct {
-	version int
-	magic   int
-	status  int
-}
-
-
-type StrmanAllPeers struct {
-	Ip 		[]string
-	Port 	[]string
-	Nodeid 	[]string
+	Version   uint64
+	Magic     uint64
+	Nonce     uint64
+	Timestamp uint64
+	Status    uint64
 }
 
-type StrmanGetPeers struct {
-	LocalPort string
-}
 
 const keepaliveMagic = 0x33
 
@@ -69,21 +90,25 @@ func New(cfg *Config, accountManager *accounts.Manager, aKID, secretKey, region
 		peers: make(map[discover.NodeID]*Peer),
 		quit:  make(chan struct{}),
 		cfg:   cfg,
-		isSentPeer:false,
-		peersPort:make(map[discover.NodeID]string),
+		memberNodes: make(map[discover.NodeID]*discover.Node),
+		endpoints:   newEndpointTable(),
+		reputation:  newReputation(cfg.PeerBanScore, cfg.PeerBanPeriod),
 	}
 
-	mpcprotocol.MpcSchnrThr = cfg.SchnorrThreshold
-	mpcprotocol.MpcSchnrNodeNumber = cfg.SchnorrTotalNodes
-	mpcprotocol.MPCDegree = mpcprotocol.MpcSchnrThr - 1
-
-	if mpcprotocol.MpcSchnrNodeNumber < mpcprotocol.MpcSchnrThr {
+	if cfg.SchnorrTotalNodes < cfg.SchnorrThreshold {
 		log.SyslogErr("should: SchnorrTotalNodes >= SchnorrThreshold")
 		os.Exit(1)
 	}
-	log.Info("=========New storeman", "SchnorrThreshold", mpcprotocol.MpcSchnrThr)
-	log.Info("=========New storeman", "SchnorrTotalNodes", mpcprotocol.MpcSchnrNodeNumber)
-	mpcprotocol.MPCDegree = mpcprotocol.MpcSchnrThr -1
+	log.Info("=========New storeman", "SchnorrThreshold", cfg.SchnorrThreshold)
+	log.Info("=========New storeman", "SchnorrTotalNodes", cfg.SchnorrTotalNodes)
+
+	for _, group := range cfg.Groups {
+		if group.ID == "" || group.ID == mpcprotocol.DefaultGroupID || group.TotalNodes < group.Threshold {
+			log.SyslogErr("invalid storeman group config", "group", group.ID)
+			os.Exit(1)
+		}
+		log.Info("=========New storeman", "group", group.ID, "threshold", group.Threshold, "totalNodes", group.TotalNodes)
+	}
 
 	storeman.mpcDistributor = storemanmpc.CreateMpcDistributor(accountManager,
 		storeman,
@@ -92,6 +117,21 @@ func New(cfg *Config, accountManager *accounts.Manager, aKID, secretKey, region
 		region,
 		cfg.Password)
 
+	if cfg.SignExtraPeers < 0 {
+		log.SyslogErr("should: SignExtraPeers >= 0")
+		os.Exit(1)
+	}
+	storeman.mpcDistributor.SetExtraPeers(cfg.SignExtraPeers)
+	storeman.mpcDistributor.SetMisbehaviourHandler(storeman.penalizePeer)
+	thresholdGauge.Update(int64(cfg.SchnorrThreshold))
+
+	for name, timeout := range cfg.Timeouts {
+		if err := storeman.mpcDistributor.Protocols.SetTimeout(name, timeout); err != nil {
+			log.SyslogErr("invalid mpc timeout config", "protocol", name, "err", err.Error())
+			os.Exit(1)
+		}
+	}
+
 	dataPath := filepath.Join(cfg.DataPath, "storeman", "data")
 	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(dataPath, 0700); err != nil {
@@ -102,6 +142,52 @@ func New(cfg *Config, accountManager *accounts.Manager, aKID, secretKey, region
 	log.Info("=========New storeman", "DB file path", dataPath)
 	log.Info("==================================")
 	validator.NewDatabase(dataPath)
+
+	if err := validator.SetOperators(cfg.Operators, cfg.OperatorThreshold); err != nil {
+		log.SyslogErr("invalid approval operators config", "operators", len(cfg.Operators), "threshold", cfg.OperatorThreshold)
+		os.Exit(1)
+	}
+
+	retention := validator.Retention{ApprovingTTL: cfg.ApprovingTTL, ApprovedTTL: cfg.ApprovedTTL, RejectedTTL: cfg.RejectedTTL}
+	if err := validator.SetRetention(retention); err != nil {
+		log.SyslogErr("invalid approval retention config", "err", err.Error())
+		os.Exit(1)
+	}
+
+	if err := validator.SetLimits(cfg.Limits); err != nil {
+		log.SyslogErr("invalid gpk limits config", "err", err.Error())
+		os.Exit(1)
+	}
+
+	if cfg.ApprovalPolicy != "" {
+		policy, err := validator.LoadPolicy(cfg.ApprovalPolicy)
+		if err != nil {
+			log.SyslogErr("load approval policy fail", "path", cfg.ApprovalPolicy, "err", err.Error())
+			os.Exit(1)
+		}
+		validator.SetPolicy(policy)
+		log.Info("=========New storeman", "approval policy", cfg.ApprovalPolicy, "rules", len(policy.Rules))
+	}
+
+	if cfg.ExternalValidator.URL != "" {
+		external, err := validator.NewExternalValidator(cfg.ExternalValidator)
+		if err != nil {
+			log.SyslogErr("invalid external validator config", "url", cfg.ExternalValidator.URL, "err", err.Error())
+			os.Exit(1)
+		}
+		validator.SetExternalValidator(external)
+		log.Info("=========New storeman", "external validator", cfg.ExternalValidator.URL)
+	}
+
+	auditPath := filepath.Join(cfg.DataPath, "storeman", audit.FileName)
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		log.SyslogErr("open storeman audit log fail", "path", auditPath, "err", err.Error())
+		os.Exit(1)
+	}
+	storeman.auditLog = auditLog
+	storeman.mpcDistributor.SetAuditLog(auditLog)
+
 	// p2p storeman sub protocol handler
 	storeman.protocol = p2p.Protocol{
 		Name:    mpcprotocol.PName,
@@ -128,14 +214,25 @@ type Storeman struct {
 	peerMu         sync.RWMutex  // Mutex to sync the active peer set
 	quit           chan struct{} // Channel used for graceful exit
 	mpcDistributor *storemanmpc.MpcDistributor
+	auditLog       *audit.Log
 	cfg            *Config
 	server 			*p2p.Server
-	isSentPeer 	   bool
-	peersPort  	   map[discover.NodeID]string
+	memberNodes    map[discover.NodeID]*discover.Node // configured endpoints of the storeman members
+	endpoints      *endpointTable
+	selfEndpoint   *EndpointRecord
+	noEndpointWarned bool // the missing external address is reported, only maintainMesh uses it
+	reputation     *reputation
 
 	//allPeersConnected chan bool
 }
 
+func (sm *Storeman) keepaliveMissed() int {
+	if sm.cfg.KeepaliveMissed <= 0 {
+		return DefaultKeepaliveMissed
+	}
+	return sm.cfg.KeepaliveMissed
+}
+
 // MaxMessageSize returns the maximum accepted message size.
 func (sm *Storeman) MaxMessageSize() uint32 {
 	// TODO what is the max size of storeman???
@@ -148,6 +245,8 @@ func (sm *Storeman) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 	log.SyslogInfo("runMessageLoop begin")
 	defer log.SyslogInfo("runMessageLoop exit")
 
+	peerID := p.ID()
+
 	for {
 		// fetch the next packet
 		packet, err := rw.ReadMsg()
@@ -156,99 +255,53 @@ func (sm *Storeman) runMessageLoop(p *Peer, rw p2p.MsgReadWriter) error {
 			return err
 		}
 
+		p.markSeen()
+
 		switch packet.Code {
 
-			case mpcprotocol.GetPeersInfo:
-				var peerGeting StrmanGetPeers
-				err := rlp.Decode(packet.Payload, &peerGeting)
+			case mpcprotocol.KeepaliveCode:
+				var ping StrmanKeepAlive
+				err := rlp.Decode(packet.Payload, &ping)
 				if err != nil {
-					log.SyslogErr("failed decode peers getting info", "err", err.Error())
+					log.SyslogErr("failed decode keepalive", "peer", p.Peer.ID().String(), "err", err.Error())
+					sm.penalizePeer(&peerID, mpcprotocol.ErrMalformedMessage)
 					return err
 				}
 
-				sm.peerMu.Lock()
+				if ping.Magic == keepaliveMagic {
+					p.sendKeepaliveOk(&ping)
+				}
 
-				sm.peersPort[p.ID()] = peerGeting.LocalPort
+			case mpcprotocol.KeepaliveOkCode:
+				var pong StrmanKeepAliveOk
+				err := rlp.Decode(packet.Payload, &pong)
 				if err != nil {
-					log.SyslogErr("failed decode port info", "err", err.Error())
+					log.SyslogErr("failed decode keepalive ok", "peer", p.Peer.ID().String(), "err", err.Error())
+					sm.penalizePeer(&peerID, mpcprotocol.ErrMalformedMessage)
 					return err
 				}
 
-				log.Debug("adding peer","",peerGeting.LocalPort)
-
-				allp := &StrmanAllPeers{make([]string, 0),make([]string,0),make([]string,0)}
-
-
-				for _, smpr := range sm.peers {
-
-					if sm.peersPort[smpr.Peer.ID()] == "" ||
-						smpr.Peer.ID().String() == sm.cfg.StoremanNodes[0].ID.String(){
-						continue
-					}
-
-					n :=  smpr.Peer.Info()
-
-					addr,err := net.ResolveTCPAddr("tcp",n.Network.RemoteAddress)
-					if err != nil {
-						log.SyslogErr("failed get address for peer", "err", err.Error())
-						return err
-					}
-
-					splits := strings.Split(addr.String(),":")
-
-					allp.Ip = append(allp.Ip,splits[0])
-
-
-					allp.Port = append(allp.Port, sm.peersPort[smpr.Peer.ID()])
-					allp.Nodeid = append(allp.Nodeid,smpr.ID().String())
-
-					log.Debug("append peer addrs,port",splits[0],sm.peersPort[smpr.ID()])
-				}
-				sm.peerMu.Unlock()
-
-				if len(allp.Port)>0 {
-					log.Debug("send all peers from leader, count","",len(allp.Port))
-					p.sendAllpeers(allp)
+				if rtt, ok := p.handlePong(&pong); ok {
+					sm.mpcDistributor.ReportPeerRTT(&peerID, rtt)
 				}
 
-
-			case mpcprotocol.AllPeersInfo:
-
-				var allp StrmanAllPeers
-				err := rlp.Decode(packet.Payload, &allp)
+			case mpcprotocol.EndpointRecords:
+				var records []EndpointRecord
+				err := rlp.Decode(packet.Payload, &records)
 				if err != nil {
-					log.SyslogErr("failed decode all peers info", "err", err.Error())
+					log.SyslogErr("failed decode endpoint records", "peer", p.Peer.ID().String(), "err", err.Error())
+					sm.penalizePeer(&peerID, mpcprotocol.ErrMalformedMessage)
 					return err
 				}
 
-
-				for i:= 0;i<len(allp.Port);i++ {
-
-					if allp.Nodeid[i] == sm.server.Self().ID.String() ||
-					   allp.Nodeid[i] == sm.cfg.StoremanNodes[0].ID.String() 	{
-						continue
-					}
-
-					//if allready exist,check next
-					url := "enode://" + allp.Nodeid[i] + "@" + allp.Ip[i] + ":" + allp.Port[i]
-
-					log.Debug("got peer, url=","",url)
-
-					nd, err := discover.ParseNode(url)
-					if err != nil {
-						log.SyslogErr("failed parse peer url", "err", err.Error())
-						return err
-					}
-
-					sm.server.AddPeer(nd)
-				}
-
+				sm.addEndpoints(p, records)
 
 			default:
 
 				log.SyslogInfo("runMessageLoop, received a msg", "peer", p.Peer.ID().String(), "packet size", packet.Size)
 				if packet.Size > sm.MaxMessageSize() {
 					log.SyslogWarning("runMessageLoop, oversized message received", "peer", p.Peer.ID().String(), "packet size", packet.Size)
+					sm.penalizePeer(&peerID, mpcprotocol.ErrOversizedMessage)
 				} else {
 					err = sm.mpcDistributor.GetMessage(p.Peer.ID(), rw, &packet)
 					if err != nil {
@@ -270,6 +323,12 @@ func (sm *Storeman) APIs() []rpc.API {
 			Service:   &StoremanAPI{sm: sm},
 			Public:    true,
 		},
+		{
+			Namespace: mpcprotocol.PAdminName,
+			Version:   mpcprotocol.PVerStr,
+			Service:   &StoremanAdminAPI{sm: sm},
+			Public:    false,
+		},
 	}
 }
 
@@ -283,60 +342,232 @@ func (sm *Storeman) Protocols() []p2p.Protocol {
 func (sm *Storeman) Start(server *p2p.Server) error {
 
 	sm.mpcDistributor.Self = server.Self()
-	sm.mpcDistributor.StoreManGroup = make([]discover.NodeID, len(server.StoremanNodes))
+	sm.mpcDistributor.SetNodeKey(server.PrivateKey)
+	if err := sm.auditLog.SetKey(server.PrivateKey); err != nil {
+		return err
+	}
+
 	sm.storemanPeers = make(map[discover.NodeID]bool)
 	sm.server = server
-	for i, item := range server.StoremanNodes {
-		sm.mpcDistributor.StoreManGroup[i] = item.ID
-		sm.storemanPeers[item.ID] = true
+
+	err := sm.registerGroup(mpcprotocol.DefaultGroupID, server.StoremanNodes, sm.cfg.SchnorrThreshold, sm.cfg.SchnorrTotalNodes)
+	if err != nil {
+		return err
 	}
 
-	sm.mpcDistributor.InitStoreManGroup()
+	for _, group := range sm.cfg.Groups {
+		err = sm.registerGroup(group.ID, group.Nodes, group.Threshold, group.TotalNodes)
+		if err != nil {
+			return err
+		}
+	}
 
-	go sm.checkPeerInfo()
+	go sm.maintainMesh()
+	go sm.compactApprovals()
+	go sm.checkKeystore()
 
 	return nil
 
 }
 
-func (sm *Storeman) checkPeerInfo() {
-
+// registerGroup registers a storeman group to the mpc distributor and accepts its members as storeman peers.
+func (sm *Storeman) registerGroup(id string, nodes []*discover.Node, threshold, total int) error {
+	members := make([]discover.NodeID, len(nodes))
+	for i, item := range nodes {
+		members[i] = item.ID
+	}
 
-	// Start the tickers for the updates
-	keepQuest := time.NewTicker(mpcprotocol.KeepaliveCycle * time.Second)
+	group, err := storemanmpc.NewMpcGroup(id, members, threshold, total)
+	if err != nil {
+		log.SyslogErr("register storeman group fail", "group", id, "err", err.Error())
+		return err
+	}
 
-	leaderid,err := discover.BytesID(sm.cfg.StoremanNodes[0].ID.Bytes())
+	err = sm.mpcDistributor.Groups.Register(group)
 	if err != nil {
-		log.Info("err decode leader node id from config")
+		log.SyslogErr("register storeman group fail", "group", id, "err", err.Error())
+		return err
 	}
 
-	if sm.cfg.StoremanNodes[0].ID.String()==sm.server.Self().ID.String() {
-		return
+	for _, item := range nodes {
+		sm.storemanPeers[item.ID] = true
+		if _, exist := sm.memberNodes[item.ID]; !exist {
+			sm.memberNodes[item.ID] = item
+		}
 	}
 
-	//leader will not checkPeerInfo
-	log.Info("Entering checkPeerInfo")
-	// Loop and transmit until termination is requested
+	configuredPeersGauge.Update(int64(len(sm.storemanPeers)))
+	log.SyslogInfo("register storeman group", "group", id, "threshold", threshold, "members", len(members))
+	return nil
+}
+
+// maintainMesh gossips the endpoint records, and keeps dialing the storeman members until every
+// configured member is connected. The node signs a record of itself only if it knows its external
+// address, so a node behind a NAT must be started with --nat extip:<IP> (or a working upnp/pmp mapping)
+// for the other members to learn its endpoint, otherwise they can only dial its configured endpoint.
+func (sm *Storeman) maintainMesh() {
+	ticker := time.NewTicker(mpcprotocol.KeepaliveCycle * time.Second)
+	defer ticker.Stop()
+
 	for {
+		sm.refreshSelfEndpoint()
+		sm.broadcastEndpoints()
+		sm.dialMissingMembers()
 
 		select {
-			case <-keepQuest.C:
-				//log.Info("Entering checkPeerInfo for loop")
-				if sm.IsActivePeer(&leaderid) {
-					splits := strings.Split(sm.server.ListenAddr, ":")
-					sm.SendToPeer(&leaderid, mpcprotocol.GetPeersInfo, StrmanGetPeers{splits[len(splits)-1]})
-				} else {
-					log.Info("leader is connecting...")
-					sm.server.AddPeer(sm.server.StoremanNodes[0])
-				}
+		case <-ticker.C:
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// compactApprovals purges the expired and consumed approval records periodically.
+func (sm *Storeman) compactApprovals() {
+	ticker := time.NewTicker(validator.DefaultCompactCycle)
+	defer ticker.Stop()
+
+	for {
+		if _, err := validator.Compact(time.Now()); err != nil {
+			log.SyslogErr("compact approval records fail", "err", err.Error())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// checkKeystore decrypts the keystore accounts at start, and retries the accounts failed to decrypt periodically,
+// so the keystore status reports whether the password or aws kms can unlock the keystore.
+func (sm *Storeman) checkKeystore() {
+	ticker := time.NewTicker(storemanmpc.KeystoreCheckCycle)
+	defer ticker.Stop()
+
+	for {
+		if err := sm.mpcDistributor.CheckKeystore(); err != nil {
+			log.SyslogErr("check storeman keystore fail", "err", err.Error())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-sm.quit:
+			return
+		}
+	}
+}
+
+// refreshSelfEndpoint signs a new endpoint record of this node when the old one is about to expire.
+func (sm *Storeman) refreshSelfEndpoint() {
+	now := time.Now()
+	if sm.selfEndpoint != nil && time.Unix(int64(sm.selfEndpoint.Timestamp), 0).Add(endpointRefresh).After(now) {
+		return
+	}
+
+	self := sm.server.Self()
+	if self.IP == nil || self.IP.IsUnspecified() {
+		if !sm.noEndpointWarned {
+			log.SyslogErr("storeman endpoint record is not signed, the node has no external address",
+				"hint", "start the node with --nat extip:<IP>, or the members can only dial its configured endpoint")
+			sm.noEndpointWarned = true
+		}
+		return
+	}
+	sm.noEndpointWarned = false
+
+	rec, err := newEndpointRecord(self, sm.server.PrivateKey, now)
+	if err != nil {
+		log.SyslogErr("sign storeman endpoint record fail", "err", err.Error())
+		return
+	}
+
+	sm.selfEndpoint = rec
+	sm.endpoints.add(rec, now, sm.isMember)
+}
+
+// broadcastEndpoints sends all the known endpoint records to the connected members.
+func (sm *Storeman) broadcastEndpoints() {
+	records := sm.endpoints.valid(time.Now())
+	if len(records) == 0 {
+		return
+	}
+
+	sm.peerMu.RLock()
+	defer sm.peerMu.RUnlock()
+	for _, peer := range sm.peers {
+		if peer.Handshaked() {
+			peer.sendEndpoints(records)
+		}
+	}
+}
+
+// dialMissingMembers dials the members which are not connected, by their newest endpoint record
+// or by the configured endpoint.
+func (sm *Storeman) dialMissingMembers() {
+	now := time.Now()
+	self := sm.server.Self().ID
+	for id, configured := range sm.memberNodes {
+		if id == self || sm.isLivePeer(&id) || sm.reputation.banned(id, now) {
+			continue
+		}
 
+		node := sm.endpoints.node(id, now)
+		if node == nil {
+			node = configured
 		}
+
+		log.SyslogInfo("dial storeman member", "node", node.String())
+		sm.server.AddPeer(node)
 	}
 }
+
+// addEndpoints keeps the valid endpoint records received from the peer.
+func (sm *Storeman) addEndpoints(p *Peer, records []EndpointRecord) {
+	if len(records) > len(sm.storemanPeers) {
+		log.SyslogWarning("too many endpoint records received", "peer", p.ID().String(), "count", len(records))
+		records = records[:len(sm.storemanPeers)]
+	}
+
+	now := time.Now()
+	for i := range records {
+		if _, err := sm.endpoints.add(&records[i], now, sm.isMember); err != nil {
+			log.SyslogWarning("invalid endpoint record received", "peer", p.ID().String(), "err", err.Error())
+			if err == mpcprotocol.ErrInvalidEndpoint {
+				peerID := p.ID()
+				sm.penalizePeer(&peerID, err)
+			}
+		}
+	}
+}
+
+// penalizePeer decrements the score of a misbehaving peer, and disconnects the peer if it gets banned.
+func (sm *Storeman) penalizePeer(peerID *discover.NodeID, offence error) {
+	if !sm.reputation.penalize(*peerID, offence, time.Now()) {
+		log.SyslogWarning("peer misbehaved", "peer", peerID.String(), "err", offence.Error())
+		return
+	}
+
+	log.SyslogWarning("peer banned", "peer", peerID.String(), "err", offence.Error())
+	sm.peerMu.RLock()
+	peer, exist := sm.peers[*peerID]
+	sm.peerMu.RUnlock()
+	if exist {
+		peer.Peer.Disconnect(p2p.DiscUselessPeer)
+	}
+}
+
+func (sm *Storeman) isMember(id discover.NodeID) bool {
+	return sm.storemanPeers[id]
+}
+
 // Stop implements node.Service, stopping the background data propagation thread
 // of the Whisper protocol.
 func (sm *Storeman) Stop() error {
-	return nil
+	close(sm.quit)
+	sm.mpcDistributor.Stop()
+	return sm.auditLog.Close()
 }
 
 func (sm *Storeman) SendToPeer(peerID *discover.NodeID, msgcode uint64, data interface{}) error {
@@ -366,6 +597,11 @@ func (sm *Storeman) HandlePeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
 		return errors.New("Peer is not in storemangroup")
 	}
 
+	if sm.reputation.banned(peer.ID(), time.Now()) {
+		log.SyslogWarning("refuse banned peer", "peerID", peer.ID().String())
+		return mpcprotocol.ErrPeerBanned
+	}
+
 	log.Info("handle new peer", "remoteAddr", peer.RemoteAddr().String(), "peerID", peer.ID().String())
 
 	// Create the new peer and start tracking it
@@ -374,6 +610,7 @@ func (sm *Storeman) HandlePeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
 
 	sm.peerMu.Lock()
 	sm.peers[storemanPeer.ID()] = storemanPeer
+	connectedPeersGauge.Update(int64(len(sm.peers)))
 	sm.peerMu.Unlock()
 
 	// Run the peer handshake and state updates
@@ -381,12 +618,15 @@ func (sm *Storeman) HandlePeer(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
 		log.SyslogErr("storemanPeer.handshake failed", "peerID", peer.ID().String(), "err", err.Error())
 		return err
 	}
+	storemanPeer.setHandshaked()
+	storemanPeer.markSeen()
 
 
 	defer func() {
 		sm.peerMu.Lock()
 
 		delete(sm.peers, storemanPeer.ID())
+		connectedPeersGauge.Update(int64(len(sm.peers)))
 
 		for _,smnode := range sm.server.StoremanNodes {
 			if smnode.ID == storemanPeer.ID() {
@@ -426,16 +666,34 @@ func (sa *StoremanAPI) Peers(ctx context.Context) []*p2p.PeerInfo {
 	return ps
 }
 
-func (sa *StoremanAPI) CreateGPK(ctx context.Context) (pk hexutil.Bytes, err error) {
+// Health reports the connections to the storeman members, the signing quorum of every gpk, the keystore
+// and database status, with a ready/degraded/down verdict.
+func (sa *StoremanAPI) Health(ctx context.Context) *Health {
+	return sa.sm.health()
+}
+
+// CreateGPK creates a group public key within the storeman group, the default group is used if groupID is omitted.
+func (sa *StoremanAPI) CreateGPK(ctx context.Context, groupID *string) (pk hexutil.Bytes, err error) {
 
-	log.SyslogInfo("CreateGPK begin")
+	id := mpcprotocol.DefaultGroupID
+	if groupID != nil && *groupID != "" {
+		id = *groupID
+	}
+
+	log.SyslogInfo("CreateGPK begin", "group", id)
 	log.SyslogInfo("CreateGPK begin", "peers", len(sa.sm.peers), "storeman peers", len(sa.sm.storemanPeers))
 
-	if len(sa.sm.storemanPeers)+1 < mpcprotocol.MpcSchnrThr {
+	group, err := sa.sm.mpcDistributor.Groups.Get(id)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if live := sa.sm.liveMembers(group); live < group.Threshold {
+		log.SyslogErr("CreateGPK fail", "group", id, "live", live, "threshold", group.Threshold)
 		return []byte{}, mpcprotocol.ErrTooLessStoreman
 	}
 
-	gpk, err := sa.sm.mpcDistributor.CreateRequestGPK()
+	gpk, err := sa.sm.mpcDistributor.CreateRequestGPK(id)
 	if err == nil {
 		log.SyslogInfo("CreateGPK end", "gpk", hexutil.Encode(gpk))
 	} else {
@@ -448,10 +706,6 @@ func (sa *StoremanAPI) CreateGPK(ctx context.Context) (pk hexutil.Bytes, err err
 func (sa *StoremanAPI) SignDataByApprove(ctx context.Context, data mpcprotocol.SendData) (result mpcprotocol.SignedResult, err error) {
 	//Todo  check the input parameter
 
-	if len(sa.sm.storemanPeers)+1 < mpcprotocol.MpcSchnrThr {
-		return mpcprotocol.SignedResult{R: []byte{}, S: []byte{}}, mpcprotocol.ErrTooLessStoreman
-	}
-
 	PKBytes := data.PKBytes
 
 	//signed, err := sa.sm.mpcDistributor.CreateReqMpcSign([]byte(data.Data), PKBytes)
@@ -471,10 +725,6 @@ func (sa *StoremanAPI) SignDataByApprove(ctx context.Context, data mpcprotocol.S
 func (sa *StoremanAPI) SignData(ctx context.Context, data mpcprotocol.SendData) (result mpcprotocol.SignedResult, err error) {
 	//Todo  check the input parameter
 
-	if len(sa.sm.storemanPeers)+1 < mpcprotocol.MpcSchnrThr {
-		return mpcprotocol.SignedResult{R: []byte{}, S: []byte{}}, mpcprotocol.ErrTooLessStoreman
-	}
-
 	PKBytes := data.PKBytes
 
 	//signed, err := sa.sm.mpcDistributor.CreateReqMpcSign([]byte(data.Data), PKBytes)
@@ -491,16 +741,168 @@ func (sa *StoremanAPI) SignData(ctx context.Context, data mpcprotocol.SendData)
 	return mpcprotocol.SignedResult{R: signed[0:65], S: signed[65:]}, nil
 }
 
+// Timeouts returns the timeout configs of the mpc protocols.
+func (sa *StoremanAPI) Timeouts(ctx context.Context) map[string]mpcprotocol.ProtocolTimeout {
+	return sa.sm.mpcDistributor.Protocols.Timeouts()
+}
+
+// Groups returns the storeman groups hosted by this node.
+func (sa *StoremanAPI) Groups(ctx context.Context) []storemanmpc.MpcGroupInfo {
+	groups := sa.sm.mpcDistributor.Groups.Groups()
+	infos := make([]storemanmpc.MpcGroupInfo, len(groups))
+	for i, group := range groups {
+		infos[i] = group.Info()
+	}
+
+	return infos
+}
+
+// AuditLog returns the audit records with unix time in [from, to], to is ignored if it is zero.
+// If gpk is given, only the records of the gpk are returned.
+func (sa *StoremanAPI) AuditLog(ctx context.Context, from, to int64, gpk *hexutil.Bytes) ([]audit.Record, error) {
+	var pk []byte
+	if gpk != nil {
+		pk = *gpk
+	}
+
+	return sa.sm.auditLog.Records(from, to, pk)
+}
+
 func (sa *StoremanAPI) AddValidData(ctx context.Context, data mpcprotocol.SendData) error {
 	return validator.AddValidData(&data)
 }
 
 // non leader node polling the data received from leader node
-func (sa *StoremanAPI) GetDataForApprove(ctx context.Context) ([]mpcprotocol.SendData, error) {
-	return validator.GetDataForApprove()
+// The filter selects the data by gpk and the time they were added, and pages them by offset and limit.
+func (sa *StoremanAPI) GetDataForApprove(ctx context.Context, filter *validator.ApprovingFilter) ([]mpcprotocol.SendData, error) {
+	return validator.QueryDataForApprove(filter)
+}
+
+// RejectData rejects the data waiting for approval, the leader gets an mpc error with the reason at once.
+func (sa *StoremanAPI) RejectData(ctx context.Context, data []mpcprotocol.SendData, reason string) []error {
+	return validator.RejectData(data, reason)
+}
+
+// SignApproveData approves the data by the signature of an operator over its approval hash, as defined by
+// validator.ApprovalHash, the data is approved once OperatorThreshold operators have signed it. It returns the number of operators who have signed.
+func (sa *StoremanAPI) SignApproveData(ctx context.Context, data mpcprotocol.SendData, signature hexutil.Bytes) (int, error) {
+	return validator.SignApproveData(&data, signature)
+}
+
+// PendingApprovals subscribes the data waiting for approval, by storeman_subscribe("pendingApprovals").
+// It emits every data as it is added for approval, and its state changes when it is approved, rejected or expired,
+// and when a context stops waiting for its approval.
+func (sa *StoremanAPI) PendingApprovals(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		events := make(chan validator.ApprovalEvent, 64)
+		sub := validator.SubscribeApprovalEvents(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// MpcEvents subscribes the lifecycle events of the mpc contexts of this node, by storeman_subscribe("mpcEvents").
+// A subscriber too slow to keep up loses the events beyond storemanmpc.MpcEventQueueSize, the mpc contexts never wait for it.
+func (sa *StoremanAPI) MpcEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		events := make(chan storemanmpc.MpcEvent, 64)
+		sub := sa.sm.mpcDistributor.SubscribeMpcEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// ApprovalStats returns the counts of the expired and consumed approval records purged since the node started.
+func (sa *StoremanAPI) ApprovalStats(ctx context.Context) validator.CompactStats {
+	return validator.GetCompactStats()
+}
+
+// GetPendingApprovals returns the data waiting for approval with the operators who have approved them so far.
+func (sa *StoremanAPI) GetPendingApprovals(ctx context.Context, filter *validator.ApprovingFilter) ([]validator.PendingApproval, error) {
+	return validator.GetPendingApprovals(filter)
+}
+
+// DecodeData returns the fields decoded from the data, and an error if they contradict the extern.
+func (sa *StoremanAPI) DecodeData(ctx context.Context, data mpcprotocol.SendData) (map[string]string, error) {
+	if err := validator.CheckPayload(&data); err != nil {
+		return nil, err
+	}
+	return validator.DecodePayload(&data), nil
+}
+
+// GetLimitUsage returns what the gpk has signed in the rolling windows of its limits.
+func (sa *StoremanAPI) GetLimitUsage(ctx context.Context, gpk hexutil.Bytes) (*validator.LimitUsage, error) {
+	return validator.GetLimitUsage(gpk)
 }
 
 //// non leader node ApproveData, and make sure that the data is really required to be signed by them.
 func (sa *StoremanAPI) ApproveData(ctx context.Context, data []mpcprotocol.SendData) []error {
 	return validator.ApproveData(data)
 }
+
+////////////////////////////////////
+// StoremanAdminAPI
+////////////////////////////////////
+type StoremanAdminAPI struct {
+	sm *Storeman
+}
+
+// SetTimeout replaces the timeout config of the mpc protocol, it takes effect on the new mpc contexts.
+// The step overrides are keyed by the step names of the protocol.
+func (sa *StoremanAdminAPI) SetTimeout(ctx context.Context, protocol string, timeout mpcprotocol.ProtocolTimeout) error {
+	return sa.sm.mpcDistributor.Protocols.SetTimeout(protocol, timeout)
+}
+
+// PeerScores returns the reputation of the storeman peers which have misbehaved, the worst first.
+func (sa *StoremanAdminAPI) PeerScores(ctx context.Context) []PeerScore {
+	return sa.sm.reputation.list(time.Now())
+}
+
+// ClearBan restores the score and lifts the ban of the peer, or of all the peers if id is omitted.
+func (sa *StoremanAdminAPI) ClearBan(ctx context.Context, id *discover.NodeID) bool {
+	sa.sm.reputation.clear(id)
+	if id == nil {
+		log.SyslogInfo("all peer bans cleared")
+	} else {
+		log.SyslogInfo("peer ban cleared", "peer", id.String())
+	}
+	return true
+}
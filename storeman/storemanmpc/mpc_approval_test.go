@@ -0,0 +1,136 @@
+package storemanmpc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"github.com/wanchain/schnorr-mpc/storeman/validator"
+)
+
+var errStepFailed = errors.New("step failed")
+
+// stubStep sends a message and fails its finish if err is set.
+type stubStep struct {
+	msgChan chan *mpcprotocol.StepMessage
+	err     error
+}
+
+func (s *stubStep) HandleMessage(*mpcprotocol.StepMessage) bool           { return true }
+func (s *stubStep) InitMessageLoop(mpcprotocol.GetMessageInterface) error { return nil }
+func (s *stubStep) Quit(error)                                            {}
+func (s *stubStep) InitStep(mpcprotocol.MpcResultInterface) error         { return nil }
+func (s *stubStep) GetMessageChan() chan *mpcprotocol.StepMessage         { return s.msgChan }
+func (s *stubStep) SetWaitAll(bool)                                       {}
+func (s *stubStep) SetWaiting(int)                                        {}
+func (s *stubStep) SetTimeout(time.Duration)                              {}
+func (s *stubStep) SetStepId(int)                                         {}
+func (s *stubStep) TimedOut() bool                                        { return false }
+func (s *stubStep) Misbehaviours() []mpcprotocol.PeerMisbehaviour         { return nil }
+func (s *stubStep) CreateMessage() []mpcprotocol.StepMessage              { return []mpcprotocol.StepMessage{{}} }
+func (s *stubStep) FinishStep(mpcprotocol.MpcResultInterface, mpcprotocol.StoremanManager) error {
+	return s.err
+}
+
+// stubManager drops the messages sent by the context.
+type stubManager struct{}
+
+func (stubManager) P2pMessage(*discover.NodeID, uint64, interface{}) error           { return nil }
+func (stubManager) BroadcastMessage([]discover.NodeID, uint64, interface{}) error    { return nil }
+func (stubManager) SetMessagePeers(*mpcprotocol.MpcMessage, *[]mpcprotocol.PeerInfo) {}
+func (stubManager) SelfNodeId() *discover.NodeID                                     { return &discover.NodeID{} }
+func (stubManager) CreateKeystore(mpcprotocol.MpcResultInterface, *[]mpcprotocol.PeerInfo, string) error {
+	return nil
+}
+func (stubManager) ReportPeerTimeout(*discover.NodeID)             {}
+func (stubManager) ReportPeerMisbehaviour(*discover.NodeID, error) {}
+
+// failingSignContext returns a context of the sign steps r, s and ackRS which fails at the step failAt.
+func failingSignContext(failAt int) *MpcContext {
+	mpc := createMpcContext(1, nil, createMpcBaseMpcResult())
+	steps := make([]MpcStepFunc, 3)
+	for i := range steps {
+		step := &stubStep{msgChan: make(chan *mpcprotocol.StepMessage, 1)}
+		if i == failAt {
+			step.err = errStepFailed
+		}
+		steps[i] = step
+	}
+
+	mpc.setMpcStep(steps...)
+	mpc.stepNames = []string{"r", "s", "ackRS"}
+	mpc.shareStep = "s"
+	return mpc
+}
+
+func newApprovalTestDB(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "storeman-approval")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validator.NewDatabase(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if db, err := validator.GetDB(); err == nil {
+			db.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSettleFailedContext(t *testing.T) {
+	defer newApprovalTestDB(t)()
+
+	mpcServer := &MpcDistributor{}
+	for i, test := range []struct {
+		failAt int
+		valid  bool
+	}{
+		{failAt: 0, valid: true},  // failed before the s share is sent, the approval and the limit are released
+		{failAt: 2, valid: false}, // failed after the s share is sent, the approval and the limit are spent
+	} {
+		data := mpcprotocol.SendData{PKBytes: []byte{byte(i + 1)}, Data: []byte("settle"), Extern: "settle"}
+		preSetValue := []MpcValue{
+			{mpcprotocol.MpcAddress, nil, data.PKBytes},
+			{mpcprotocol.MpcM, nil, data.Data},
+			{mpcprotocol.MpcExt, nil, []byte(data.Extern)}}
+
+		if err := validator.AddValidData(&data); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := validator.ValidateData(&data, time.Second); !ok {
+			t.Fatalf("test %d: approval is not claimed: %v", i, err)
+		}
+		reservation, err := validator.ReserveLimit(&data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mpc := failingSignContext(test.failAt)
+		err = mpc.mainMPCProcess(stubManager{})
+		if err != errStepFailed {
+			t.Fatalf("test %d: context does not fail: %v", i, err)
+		}
+		mpcServer.settleApproval(mpc.ContextID, mpc, err, preSetValue...)
+		mpcServer.releaseLimit(mpc.ContextID, mpc, reservation)
+
+		usage, err := validator.GetLimitUsage(data.PKBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spent := usage.SignsInHour != 0; spent == test.valid {
+			t.Fatalf("test %d: limit spent %v after the context failed at step %d", i, spent, test.failAt)
+		}
+
+		ok, err := validator.ValidateData(&data, 100*time.Millisecond)
+		if ok != test.valid {
+			t.Fatalf("test %d: approval valid %v after the context failed at step %d, want %v: %v", i, ok, test.failAt, test.valid, err)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package storemanmpc
 
 import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
 	"github.com/wanchain/schnorr-mpc/log"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/storeman/audit"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"sync"
+	"time"
 )
 
 type MemStatus struct {
@@ -23,7 +29,11 @@ type MpcStepFunc interface {
 	FinishStep(mpcprotocol.MpcResultInterface, mpcprotocol.StoremanManager) error
 	GetMessageChan() chan *mpcprotocol.StepMessage
 	SetWaitAll(bool)
+	SetWaiting(int)
+	SetTimeout(time.Duration)
 	SetStepId(int)
+	TimedOut() bool
+	Misbehaviours() []mpcprotocol.PeerMisbehaviour
 }
 
 type MpcContext struct {
@@ -34,6 +44,12 @@ type MpcContext struct {
 	mpcResult   mpcprotocol.MpcResultInterface
 	MpcSteps    []MpcStepFunc
 	MapStepChan map[uint64]chan *mpcprotocol.StepMessage
+	stepNames   []string // names of the steps declared by the protocol
+	shareStep   string   // name of the step sending the signature share
+	shared      bool     // the signature share is sent to the peers
+	protocol    string
+	leader      bool
+	post        func(MpcEvent)
 }
 
 func (mpcCtx *MpcContext) getMpcResult() []byte {
@@ -58,6 +74,22 @@ func (mpcCtx *MpcContext) getMessage(PeerID *discover.NodeID,
 	return nil
 }
 
+// shareSent returns true if the context has sent the signature share of the node, the data may then be signed
+// by the peers even if the context fails.
+func (mpcCtx *MpcContext) shareSent() bool {
+	return mpcCtx.shared
+}
+
+func (mpcCtx *MpcContext) isParticipant(PeerID *discover.NodeID) bool {
+	for _, item := range mpcCtx.peers {
+		if item.PeerID == *PeerID {
+			return true
+		}
+	}
+
+	return false
+}
+
 func createMpcContext(contextID uint64,
 	peers []mpcprotocol.PeerInfo,
 	mpcResult mpcprotocol.MpcResultInterface) *MpcContext {
@@ -81,6 +113,116 @@ func (mpcCtx *MpcContext) setMpcStep(mpcSteps ...MpcStepFunc) {
 	}
 }
 
+// setTimeout applies the timeout config of the protocol to the steps, the overrides are matched by step name.
+func (mpcCtx *MpcContext) setTimeout(cfg *mpcprotocol.ProtocolTimeout) {
+	for i, step := range mpcCtx.MpcSteps {
+		name := ""
+		if i < len(mpcCtx.stepNames) {
+			name = mpcCtx.stepNames[i]
+		}
+
+		step.SetTimeout(cfg.StepTimeout(name))
+
+		override := cfg.StepOverride(name)
+		if override == nil {
+			continue
+		}
+
+		if override.MinResponses > 0 {
+			step.SetWaiting(override.MinResponses)
+		}
+
+		if override.ContinueOnPartial != nil {
+			step.SetWaitAll(!*override.ContinueOnPartial)
+		}
+	}
+}
+
+// setEventPoster sets the function to post the lifecycle events of the context.
+func (mpcCtx *MpcContext) setEventPoster(protocol string, leader bool, post func(MpcEvent)) {
+	mpcCtx.protocol = protocol
+	mpcCtx.leader = leader
+	mpcCtx.post = post
+}
+
+func (mpcCtx *MpcContext) postEvent(eventType MpcEventType, step int, err error) {
+	if mpcCtx.post == nil {
+		return
+	}
+
+	peers := make([]discover.NodeID, len(mpcCtx.peers))
+	for i, item := range mpcCtx.peers {
+		peers[i] = item.PeerID
+	}
+
+	mpcCtx.post(MpcEvent{
+		Type:      eventType,
+		ContextID: mpcCtx.ContextID,
+		Protocol:  mpcCtx.protocol,
+		Leader:    mpcCtx.leader,
+		GPK:       mpcCtx.gpk(),
+		Step:      step,
+		Peers:     peers,
+		Err:       err,
+		Time:      time.Now()})
+}
+
+// gpk returns the gpk used to sign, or the gpk created by the context.
+func (mpcCtx *MpcContext) gpk() []byte {
+	value, err := mpcCtx.mpcResult.GetValue(mpcprotocol.PublicKeyResult)
+	if err != nil || len(value) != 2 {
+		return nil
+	}
+
+	gpk := ecdsa.PublicKey{Curve: crypto.S256(), X: &value[0], Y: &value[1]}
+	return crypto.FromECDSAPub(&gpk)
+}
+
+// auditRecord builds the audit record of the context from its result.
+func (mpcCtx *MpcContext) auditRecord(err error) *audit.Record {
+	rec := &audit.Record{
+		Time:      time.Now().Unix(),
+		ContextID: mpcCtx.ContextID,
+		Protocol:  mpcCtx.protocol,
+		GPK:       mpcCtx.gpk(),
+		Peers:     make([]string, len(mpcCtx.peers)),
+	}
+
+	for i, item := range mpcCtx.peers {
+		rec.Peers[i] = item.PeerID.String()
+	}
+
+	if leader, errGet := mpcCtx.mpcResult.GetByteValue(mpcprotocol.MpcLeader); errGet == nil {
+		rec.Leader = common.ToHex(leader)
+	}
+
+	if m, errGet := mpcCtx.mpcResult.GetByteValue(mpcprotocol.MpcM); errGet == nil {
+		rec.MsgHash = common.Hash(sha256.Sum256(m))
+	}
+
+	if ext, errGet := mpcCtx.mpcResult.GetByteValue(mpcprotocol.MpcExt); errGet == nil {
+		rec.Extern = string(ext)
+	}
+
+	if byApprove, errGet := mpcCtx.mpcResult.GetValue(mpcprotocol.MpcByApprove); errGet == nil && len(byApprove) != 0 {
+		rec.Approval = &audit.Approval{Required: byApprove[0].Sign() != 0}
+	}
+
+	if rpk, errGet := mpcCtx.mpcResult.GetValue(mpcprotocol.RPublicKeyResult); errGet == nil && len(rpk) == 2 {
+		rec.R = crypto.FromECDSAPub(&ecdsa.PublicKey{Curve: crypto.S256(), X: &rpk[0], Y: &rpk[1]})
+	}
+
+	if s, errGet := mpcCtx.mpcResult.GetValue(mpcprotocol.MpcS); errGet == nil && len(s) != 0 {
+		rec.S = s[0].Bytes()
+	}
+
+	if err != nil {
+		rec.Err = err.Error()
+	}
+
+	return rec
+}
+
 func (mpcCtx *MpcContext) quit(err error) {
 	if err == nil {
 		log.SyslogInfo("MpcContext.quit")
@@ -124,6 +266,7 @@ func (mpcCtx *MpcContext) mainMPCProcess(StoremanManager mpcprotocol.StoremanMan
 				break
 			}
 
+			mpcCtx.postEvent(MpcStepStarted, i, nil)
 			log.SyslogInfo("--------step init finished--------", "ctxid", mpcCtx.ContextID, "stepId", i)
 			msg := mpcCtx.MpcSteps[i].CreateMessage()
 			if msg != nil {
@@ -148,13 +291,28 @@ func (mpcCtx *MpcContext) mainMPCProcess(StoremanManager mpcprotocol.StoremanMan
 				}
 			}
 
+			if msg != nil && mpcCtx.shareStep != "" && i < len(mpcCtx.stepNames) && mpcCtx.stepNames[i] == mpcCtx.shareStep {
+				mpcCtx.shared = true
+			}
+
 			log.SyslogInfo("step send p2p msg finished", "ctxid", mpcCtx.ContextID, "stepId", i)
 			err = mpcCtx.MpcSteps[i].FinishStep(mpcCtx.mpcResult, StoremanManager)
+			for _, misbehaviour := range mpcCtx.MpcSteps[i].Misbehaviours() {
+				StoremanManager.ReportPeerMisbehaviour(&misbehaviour.PeerID, misbehaviour.Err)
+			}
+
+			// a step waiting for all the peers fails by the timeout, the others continue
+			if mpcCtx.MpcSteps[i].TimedOut() {
+				mpcCtx.postEvent(MpcStepTimeout, i, err)
+			}
+
 			if err != nil {
 				mpcErr = err
 				break
 			}
 
+			mpcCtx.postEvent(MpcStepFinished, i, nil)
+
 			log.SyslogInfo("--------step mssage finished--------", "ctxid", mpcCtx.ContextID, "stepId", i)
 		}
 	}
@@ -163,8 +321,11 @@ func (mpcCtx *MpcContext) mainMPCProcess(StoremanManager mpcprotocol.StoremanMan
 		log.SyslogErr("mainMPCProcess fail", "err", mpcErr.Error())
 		mpcMsg := &mpcprotocol.MpcMessage{ContextID: mpcCtx.ContextID,
 			StepID: 0,
-			Peers:  []byte(mpcErr.Error())}
+			ErrMsg: []byte(mpcErr.Error())}
 		StoremanManager.BroadcastMessage(peerIDs, mpcprotocol.MPCError, mpcMsg)
+		mpcCtx.postEvent(MpcContextFailed, -1, mpcErr)
+	} else {
+		mpcCtx.postEvent(MpcContextFinished, -1, nil)
 	}
 
 	mpcCtx.quit(nil)
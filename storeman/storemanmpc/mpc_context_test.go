@@ -3,6 +3,7 @@ package storemanmpc
 import (
 	"github.com/wanchain/schnorr-mpc/common"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"math/big"
 	"testing"
 )
 
@@ -20,11 +21,15 @@ func TestContextQuit(t *testing.T) {
 		peers[i].Seed = uint64(i + 1)
 	}
 
-	mpc, err := requestTxSignMpc(1, peers)
+	ctx, err := (&MpcCtxFactory{}).CreateContext(signProtocol(), true, 1, peers,
+		MpcValue{mpcprotocol.MpcThreshold, []big.Int{*big.NewInt(int64(nThread/2 + 1))}, nil},
+		MpcValue{mpcprotocol.MpcLeader, nil, peers[0].PeerID[:]})
 	if err != nil {
-		t.Error("mpc create error")
+		t.Fatal("mpc create error")
 	}
 
+	mpc := ctx.(*MpcContext)
+
 	go func() {
 		for _, mpcCt := range mpc.MpcSteps {
 			err := mpcCt.InitMessageLoop(mpcCt)
@@ -4,22 +4,54 @@ import (
 	"github.com/wanchain/schnorr-mpc/log"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"github.com/wanchain/schnorr-mpc/storeman/storemanmpc/step"
+	"math/rand"
+	"time"
 )
 
+func gpkProtocol() *MpcProtocol {
+	return &MpcProtocol{
+		Name:        mpcprotocol.MpcGPKProtocol,
+		Leader:      reqGPKMpc,
+		Peer:        ackGPKMpc,
+		Prepare:     prepareGPKMpc,
+		RequestKeys: []string{mpcprotocol.MpcGroupID},
+		Approval:    ApprovalNone,
+		Steps:       []string{"request", "ready", "skShare", "gpk", "ackGPK"}}
+}
+
+// prepareGPKMpc returns the members of the storeman group and its threshold
+func prepareGPKMpc(mpcServer *MpcDistributor, preSetValue ...MpcValue) ([]mpcprotocol.PeerInfo, []MpcValue, error) {
+	groupID := findMpcValue(mpcprotocol.MpcGroupID, preSetValue...)
+	if groupID == nil {
+		return nil, nil, mpcprotocol.ErrInvalidGroup
+	}
+
+	group, err := mpcServer.Groups.Get(string(groupID.ByteValue))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peers := make([]mpcprotocol.PeerInfo, 0, len(group.Members))
+	for i := 0; i < len(group.Members); i++ {
+		peers = append(peers, mpcprotocol.PeerInfo{PeerID: group.Members[i], Seed: 0})
+	}
+
+	log.SyslogInfo("prepareGPKMpc", "group", group.ID, "threshold", group.Threshold)
+	return peers, []MpcValue{thresholdValue(group.Threshold)}, nil
+}
+
 //send create LockAccount from leader
-func reqGPKMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcValue) (*MpcContext, error) {
+func reqGPKMpc(protocol *MpcProtocol, mpc *MpcContext, preSetValue ...MpcValue) ([]MpcStepFunc, error) {
+	generatePeerSeeds(mpc.peers)
 
-	result := createMpcBaseMpcResult()
-	result.InitializeValue(preSetValue...)
-	mpc := createMpcContext(mpcID, peers, result)
-	reqMpc := step.CreateRequestMpcStep(&mpc.peers, mpcprotocol.MpcGPKLeader)
+	reqMpc := step.CreateRequestMpcStep(&mpc.peers, protocol.Name, protocol.RequestKeys)
 	mpcReady := step.CreateMpcReadyStep(&mpc.peers)
-	return genCreateGPKMpc(mpc, reqMpc, mpcReady)
+	return genCreateGPKMpc(mpc, reqMpc, mpcReady, preSetValue...)
 
 }
 
 //get message from leader and create Context
-func ackGPKMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcValue) (*MpcContext, error) {
+func ackGPKMpc(protocol *MpcProtocol, mpc *MpcContext, preSetValue ...MpcValue) ([]MpcStepFunc, error) {
 
 	log.SyslogInfo("ackGPKMpc begin.")
 	for _, preSetValuebyteData := range preSetValue {
@@ -27,7 +59,7 @@ func ackGPKMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcVal
 	}
 
 	findMap := make(map[uint64]bool)
-	for _, item := range peers {
+	for _, item := range mpc.peers {
 		if item.Seed > 0xffffff {
 			log.SyslogErr("ackGPKMpc fail", "err", mpcprotocol.ErrMpcSeedOutRange.Error())
 			return nil, mpcprotocol.ErrMpcSeedOutRange
@@ -42,26 +74,46 @@ func ackGPKMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcVal
 		findMap[item.Seed] = true
 	}
 
-	result := createMpcBaseMpcResult()
-	result.InitializeValue(preSetValue...)
-	mpc := createMpcContext(mpcID, peers, result)
-	AckMpc := step.CreateAckMpcStep(&mpc.peers, mpcprotocol.MpcGPKPeer)
+	AckMpc := step.CreateAckMpcStep(&mpc.peers, protocol.Name)
 	mpcReady := step.CreateGetMpcReadyStep(&mpc.peers)
-	return genCreateGPKMpc(mpc, AckMpc, mpcReady)
+	return genCreateGPKMpc(mpc, AckMpc, mpcReady, preSetValue...)
 }
 
-func genCreateGPKMpc(mpc *MpcContext, firstStep MpcStepFunc, readyStep MpcStepFunc) (*MpcContext, error) {
+func genCreateGPKMpc(mpc *MpcContext, firstStep MpcStepFunc, readyStep MpcStepFunc, preSetValue ...MpcValue) ([]MpcStepFunc, error) {
+
+	threshold, err := groupThreshold(preSetValue...)
+	if err != nil {
+		return nil, err
+	}
 
 	accTypeStr := ""
-	skShare := step.CreateMpcSKShareStep(mpcprotocol.MPCDegree, &mpc.peers)
+	skShare := step.CreateMpcSKShareStep(threshold-1, &mpc.peers)
 	gpk := step.CreateMpcGPKStep(&mpc.peers, accTypeStr)
 	ackGpk := step.CreateAckMpcGPKStep(&mpc.peers)
-	mpc.setMpcStep(firstStep, readyStep, skShare, gpk, ackGpk)
+	return []MpcStepFunc{firstStep, readyStep, skShare, gpk, ackGpk}, nil
+}
 
-	for stepId, stepItem := range mpc.MpcSteps {
-		stepItem.SetStepId(stepId)
-	}
+// generatePeerSeeds gives every peer an unique random seed
+func generatePeerSeeds(peers []mpcprotocol.PeerInfo) {
+	findMap := make(map[uint64]bool)
+	rand.Seed(time.Now().UnixNano())
+	for i := 0; i < len(peers); i++ {
+		for {
+			peers[i].Seed = (uint64)(rand.Intn(0x0FFFFFE) + 1)
+			_, exist := findMap[peers[i].Seed]
+			if exist {
+				continue
+			}
 
-	return mpc, nil
+			findMap[peers[i].Seed] = true
+			break
+		}
+	}
 
+	for index, peer := range peers {
+		log.Info("generatePeerSeeds ",
+			"index", index,
+			"peerID", peer.PeerID.String(),
+			"seed", peer.Seed)
+	}
 }
@@ -9,13 +9,14 @@ import (
 type MpcCtxFactory struct {
 }
 
-func (*MpcCtxFactory) CreateContext(ctxType int,
+func (*MpcCtxFactory) CreateContext(protocol *MpcProtocol,
+	leader bool,
 	mpcID uint64,
 	peers []mpcprotocol.PeerInfo,
 	preSetValue ...MpcValue) (MpcInterface, error) {
 
 	log.SyslogInfo("============================ CreateContext=====================")
-	log.SyslogInfo("CreateContext", "ctxType", ctxType)
+	log.SyslogInfo("CreateContext", "protocol", protocol.Name, "leader", leader)
 	for i := 0; i < len(preSetValue); i++ {
 		if preSetValue[i].Key != mpcprotocol.MpcPrivateShare {
 			if preSetValue[i].Value != nil {
@@ -30,17 +31,21 @@ func (*MpcCtxFactory) CreateContext(ctxType int,
 	}
 	log.SyslogInfo("============================ CreateContext=====================")
 
-	switch ctxType {
-	case mpcprotocol.MpcGPKLeader:
-		return reqGPKMpc(mpcID, peers, preSetValue...)
-	case mpcprotocol.MpcGPKPeer:
-		return ackGPKMpc(mpcID, peers, preSetValue...)
+	result := createMpcBaseMpcResult()
+	result.InitializeValue(preSetValue...)
+	mpc := createMpcContext(mpcID, peers, result)
+	steps, err := protocol.pipeline(leader)(protocol, mpc, preSetValue...)
+	if err != nil {
+		log.SyslogErr("CreateContext fail", "protocol", protocol.Name, "err", err.Error())
+		return nil, err
+	}
 
-	case mpcprotocol.MpcSignLeader:
-		return reqSignMpc(mpcID, peers, preSetValue...)
-	case mpcprotocol.MpcSignPeer:
-		return ackSignMpc(mpcID, peers, preSetValue...)
+	mpc.setMpcStep(steps...)
+	mpc.stepNames = protocol.Steps
+	mpc.shareStep = protocol.ShareStep
+	for stepId, stepItem := range mpc.MpcSteps {
+		stepItem.SetStepId(stepId)
 	}
 
-	return nil, mpcprotocol.ErrContextType
+	return mpc, nil
 }
@@ -7,23 +7,22 @@ import (
 type MpcTestCtxFactory struct {
 }
 
-func (*MpcTestCtxFactory) CreateContext(ctxType int,
+func (*MpcTestCtxFactory) CreateContext(protocol *MpcProtocol,
+	leader bool,
 	mpcID uint64,
 	peers []mpcprotocol.PeerInfo,
 	preSetValue ...MpcValue) (MpcInterface, error) {
 
-	switch ctxType {
-	case mpcprotocol.MpcGPKLeader:
-		return testCreatep2pMpc(mpcID, peers, preSetValue...)
+	switch protocol.Name {
+	case mpcprotocol.MpcGPKProtocol:
+		if leader {
+			return testCreatep2pMpc(mpcID, peers, preSetValue...)
+		}
 
-	case mpcprotocol.MpcGPKPeer:
 		return acknowledgeCreatep2pMpc(mpcID, peers, preSetValue...)
 
-	case mpcprotocol.MpcSignLeader:
-		return reqSignMpc(mpcID, peers, preSetValue...)
-
-	case mpcprotocol.MpcSignPeer:
-		return ackSignMpc(mpcID, peers, preSetValue...)
+	case mpcprotocol.MpcSignProtocol:
+		return (&MpcCtxFactory{}).CreateContext(protocol, leader, mpcID, peers, preSetValue...)
 	}
 
 	return nil, mpcprotocol.ErrContextType
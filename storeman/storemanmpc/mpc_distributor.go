@@ -2,7 +2,7 @@ package storemanmpc
 
 import (
 	"crypto/ecdsa"
-	"encoding/binary"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"github.com/wanchain/schnorr-mpc/accounts"
@@ -11,21 +11,23 @@ import (
 	"github.com/wanchain/schnorr-mpc/common"
 	"github.com/wanchain/schnorr-mpc/common/hexutil"
 	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/event"
 	"github.com/wanchain/schnorr-mpc/log"
 	"github.com/wanchain/schnorr-mpc/p2p"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	"github.com/wanchain/schnorr-mpc/rlp"
+	"github.com/wanchain/schnorr-mpc/storeman/audit"
 	"github.com/wanchain/schnorr-mpc/storeman/shcnorrmpc"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"github.com/wanchain/schnorr-mpc/storeman/validator"
 	"io/ioutil"
 	"math/big"
-	"sort"
 	"sync"
+	"time"
 )
 
 type MpcContextCreater interface {
-	CreateContext(int, uint64, []mpcprotocol.PeerInfo, ...MpcValue) (MpcInterface, error) //createContext
+	CreateContext(*MpcProtocol, bool, uint64, []mpcprotocol.PeerInfo, ...MpcValue) (MpcInterface, error) //createContext
 }
 
 type MpcValue struct {
@@ -51,6 +53,12 @@ type MpcInterface interface {
 	getMessage(*discover.NodeID, *mpcprotocol.MpcMessage, *[]mpcprotocol.PeerInfo) error
 	mainMPCProcess(manager mpcprotocol.StoremanManager) error
 	getMpcResult() []byte
+	isParticipant(*discover.NodeID) bool
+	setTimeout(*mpcprotocol.ProtocolTimeout)
+	setEventPoster(string, bool, func(MpcEvent))
+	postEvent(MpcEventType, int, error)
+	auditRecord(error) *audit.Record
+	shareSent() bool
 	quit(error)
 }
 
@@ -63,6 +71,8 @@ type mpcAccount struct {
 	address      common.Address
 	privateShare big.Int
 	peers        []mpcprotocol.PeerInfo
+	groupID      string
+	threshold    int
 	externString string
 }
 
@@ -75,14 +85,28 @@ type KmsInfo struct {
 type MpcDistributor struct {
 	mu             sync.RWMutex
 	Self           *discover.Node
-	StoreManGroup  []discover.NodeID
-	storeManIndex  map[discover.NodeID]byte
+	nodeKey        *ecdsa.PrivateKey
+	Groups         *MpcGroupRegistry
+	Protocols      *MpcProtocolRegistry
+	Latency        *MpcPeerLatency
+	extraPeers     int
+	eventMu        sync.Mutex
+	eventQueues    map[chan MpcEvent]struct{} // queue of every event subscriber
+	scope          event.SubscriptionScope
+	metrics        *mpcMetrics
+	auditLog       *audit.Log
+	misbehave      func(*discover.NodeID, error)
 	mpcCreater     MpcContextCreater
 	mpcMap         map[uint64]MpcInterface
 	AccountManager *accounts.Manager
 	P2pMessager    P2pMessager
+	relayMu        sync.Mutex
+	relayQueue     []queuedRelay // relay messages waiting for a storeman to relay them
+	relayRetrying  bool          // retryRelays is running
 	accMu          sync.Mutex
 	mpcAccountMap  map[common.Address]*mpcAccount
+	keystoreErr    error     // error of the last keystore check
+	keystoreCheck  time.Time // time of the last keystore check
 	enableAwsKms   bool
 	kmsInfo        KmsInfo
 	password       string
@@ -100,6 +124,11 @@ func CreateMpcDistributor(accountManager *accounts.Manager,
 		mu:             sync.RWMutex{},
 		mpcCreater:     &MpcCtxFactory{},
 		mpcMap:         make(map[uint64]MpcInterface),
+		Groups:         NewMpcGroupRegistry(),
+		Protocols:      NewMpcProtocolRegistry(),
+		Latency:        NewMpcPeerLatency(),
+		metrics:        newMpcMetrics(),
+		extraPeers:     DefaultExtraPeers,
 		AccountManager: accountManager,
 		accMu:          sync.Mutex{},
 		mpcAccountMap:  make(map[common.Address]*mpcAccount),
@@ -110,6 +139,9 @@ func CreateMpcDistributor(accountManager *accounts.Manager,
 
 	mpc.enableAwsKms = (aKID != "") && (secretKey != "") && (region != "")
 
+	mpc.Protocols.Register(gpkProtocol())
+	mpc.Protocols.Register(signProtocol())
+
 	return mpc
 }
 
@@ -171,30 +203,56 @@ func (mpcServer *MpcDistributor) GetMessage(PeerID discover.NodeID, rw p2p.MsgRe
 	case mpcprotocol.KeepaliveOkCode:
 		// this should not happen, but no need to panic; just ignore this message.
 
-	case mpcprotocol.MPCError:
+	case mpcprotocol.MPCError, mpcprotocol.RequestMPC, mpcprotocol.MPCMessage:
 		var mpcMessage mpcprotocol.MpcMessage
 		err := rlp.Decode(msg.Payload, &mpcMessage)
 		if err != nil {
-			log.SyslogErr("MpcDistributor.GetMessage, rlp decode MPCError msg fail", "err", err.Error())
+			log.SyslogErr("MpcDistributor.GetMessage, rlp decode msg fail", "msgCode", msg.Code, "err", err.Error())
+			mpcServer.ReportPeerMisbehaviour(&PeerID, mpcprotocol.ErrMalformedMessage)
 			return err
 		}
 
-		errText := string(mpcMessage.Peers[:])
-		log.SyslogErr("MpcDistributor.GetMessage, MPCError message received", "peer", PeerID.String(), "err", errText)
-		go mpcServer.QuitMpcContext(&mpcMessage)
+		return mpcServer.dispatchMessage(&PeerID, msg.Code, &mpcMessage)
 
-	case mpcprotocol.RequestMPC:
-		log.SyslogInfo("MpcDistributor.GetMessage, RequestMPC message received", "peer", PeerID.String())
-		var mpcMessage mpcprotocol.MpcMessage
-		err := rlp.Decode(msg.Payload, &mpcMessage)
+	case mpcprotocol.RelayMPC:
+		var relay mpcprotocol.RelayMessage
+		err := rlp.Decode(msg.Payload, &relay)
 		if err != nil {
-			log.SyslogErr("MpcDistributor.GetMessage, rlp decode RequestMPC msg fail", "err", err.Error())
+			log.SyslogErr("MpcDistributor.GetMessage, rlp decode RelayMPC msg fail", "err", err.Error())
+			mpcServer.ReportPeerMisbehaviour(&PeerID, mpcprotocol.ErrMalformedMessage)
 			return err
 		}
 
+		return mpcServer.handleRelay(&PeerID, &relay)
+
+	default:
+		// New message types might be implemented in the future versions of Whisper.
+		// For forward compatibility, just ignore.
+	}
+
+	return nil
+}
+
+// dispatchMessage verifies the message is signed by the peer, and handles it by the message code.
+func (mpcServer *MpcDistributor) dispatchMessage(PeerID *discover.NodeID, code uint64, mpcMessage *mpcprotocol.MpcMessage) error {
+	err := mpcMessage.Verify(code, PeerID)
+	if err != nil {
+		log.SyslogErr("MpcDistributor.GetMessage, verify msg fail", "msgCode", code, "peer", PeerID.String(), "err", err.Error())
+		mpcServer.ReportPeerMisbehaviour(PeerID, err)
+		return err
+	}
+
+	switch code {
+	case mpcprotocol.MPCError:
+		errText := string(mpcMessage.ErrMsg[:])
+		log.SyslogErr("MpcDistributor.GetMessage, MPCError message received", "peer", PeerID.String(), "err", errText)
+		go mpcServer.QuitMpcContext(PeerID, mpcMessage)
+
+	case mpcprotocol.RequestMPC:
+		log.SyslogInfo("MpcDistributor.GetMessage, RequestMPC message received", "peer", PeerID.String())
 		//create context
 		go func() {
-			err := mpcServer.createMpcCtx(&mpcMessage)
+			err := mpcServer.createMpcCtx(PeerID, mpcMessage)
 
 			if err != nil {
 				log.SyslogErr("createMpcContext fail", "err", err.Error())
@@ -202,41 +260,27 @@ func (mpcServer *MpcDistributor) GetMessage(PeerID discover.NodeID, rw p2p.MsgRe
 		}()
 
 	case mpcprotocol.MPCMessage:
-		var mpcMessage mpcprotocol.MpcMessage
-		err := rlp.Decode(msg.Payload, &mpcMessage)
-		if err != nil {
-			log.SyslogErr("GetP2pMessage fail", "err", err.Error())
-			return err
-		}
-
 		log.SyslogInfo("MpcDistributor.GetMessage, MPCMessage message received", "peer", PeerID.String())
-		go mpcServer.getMpcMessage(&PeerID, &mpcMessage)
-
-	default:
-		// New message types might be implemented in the future versions of Whisper.
-		// For forward compatibility, just ignore.
+		go mpcServer.getMpcMessage(PeerID, mpcMessage)
 	}
 
 	return nil
 }
 
-func (mpcServer *MpcDistributor) InitStoreManGroup() {
-	log.SyslogInfo("Entering MpcDistributor InitStoreManGroup......")
-	sort.Sort(mpcprotocol.SliceStoremanGroup(mpcServer.StoreManGroup))
-	mpcServer.storeManIndex = make(map[discover.NodeID]byte)
-	for i := 0; i < len(mpcServer.StoreManGroup); i++ {
-		mpcServer.storeManIndex[mpcServer.StoreManGroup[i]] = byte(i)
-	}
-	log.SyslogInfo("InitStoreManGroup......","mpcServer.StoreManGroup",mpcServer.StoreManGroup)
-	log.SyslogInfo("InitStoreManGroup......","storeManIndex",mpcServer.storeManIndex)
+// SetNodeKey sets the p2p node key used to sign the outgoing mpc messages.
+func (mpcServer *MpcDistributor) SetNodeKey(prv *ecdsa.PrivateKey) {
+	mpcServer.nodeKey = prv
+}
+
+func (mpcServer *MpcDistributor) isStoremanNode(peerID *discover.NodeID) bool {
+	return mpcServer.Groups.IsMember(peerID)
 }
 
-func (mpcServer *MpcDistributor) CreateRequestGPK() ([]byte, error) {
-	log.SyslogInfo("CreateRequestGPK begin")
+func (mpcServer *MpcDistributor) CreateRequestGPK(groupID string) ([]byte, error) {
+	log.SyslogInfo("CreateRequestGPK begin", "group", groupID)
 
-	preSetValue := make([]MpcValue, 0, 1)
-	value, err := mpcServer.createRequestMpcContext(mpcprotocol.MpcGPKLeader,
-		preSetValue...)
+	value, err := mpcServer.createRequestMpcContext(mpcprotocol.MpcGPKProtocol,
+		MpcValue{mpcprotocol.MpcGroupID, nil, []byte(groupID)})
 
 	if err != nil {
 		return []byte{}, err
@@ -249,7 +293,7 @@ func (mpcServer *MpcDistributor) CreateReqMpcSign(data []byte, extern []byte, pk
 
 	log.SyslogInfo("CreateReqMpcSign begin")
 
-	value, err := mpcServer.createRequestMpcContext(mpcprotocol.MpcSignLeader,
+	value, err := mpcServer.createRequestMpcContext(mpcprotocol.MpcSignProtocol,
 		MpcValue{mpcprotocol.MpcAddress, nil, pkBytes[:]},
 		MpcValue{mpcprotocol.MpcM, nil, data},
 		MpcValue{mpcprotocol.MpcExt, nil, extern},
@@ -258,66 +302,91 @@ func (mpcServer *MpcDistributor) CreateReqMpcSign(data []byte, extern []byte, pk
 	return value, err
 }
 
-func (mpcServer *MpcDistributor) createRequestMpcContext(ctxType int, preSetValue ...MpcValue) (hexutil.Bytes, error) {
-	log.SyslogInfo("MpcDistributor createRequestMpcContext begin")
-	mpcID, err := mpcServer.getMpcID()
+func (mpcServer *MpcDistributor) createRequestMpcContext(protocolName string, preSetValue ...MpcValue) (hexutil.Bytes, error) {
+	log.SyslogInfo("MpcDistributor createRequestMpcContext begin", "protocol", protocolName)
+	protocol, err := mpcServer.Protocols.Get(protocolName)
 	if err != nil {
-		return nil, err
+		log.SyslogErr("MpcDistributor createRequestMpcContext fail", "protocol", protocolName, "err", err.Error())
+		return []byte{}, err
 	}
 
-	peers := []mpcprotocol.PeerInfo{}
-
-	var address common.Address
-	if ctxType == mpcprotocol.MpcSignLeader {
-		for _, item := range preSetValue {
-			if item.Key == mpcprotocol.MpcAddress {
-				address, err = shcnorrmpc.PkToAddress(item.ByteValue)
-				if err != nil {
-					return []byte{}, err
-				}
-				break
-			}
+	for _, key := range protocol.RequestKeys {
+		if findMpcValue(key, preSetValue...) == nil {
+			log.SyslogErr("MpcDistributor createRequestMpcContext fail, preset value is missing",
+				"protocol", protocolName,
+				"key", key)
+			return []byte{}, mpcprotocol.ErrInvalidProtocol
 		}
-		// peers1: the peers which are used to create the group public key, used to build the sign data.
-		value, value1, peers1, err := mpcServer.loadStoremanAddress(&address)
-		if err != nil {
+	}
 
-			log.SyslogErr("MpcDistributor createRequestMpcContext, loadStoremanAddress fail",
-				"address", address.String(),
-				"err", err.Error())
+	mpcID, err := mpcServer.getMpcID()
+	if err != nil {
+		return nil, err
+	}
 
-			return []byte{}, err
-		}
+	peers, values, err := protocol.Prepare(mpcServer, preSetValue...)
+	if err != nil {
+		log.SyslogErr("MpcDistributor createRequestMpcContext, prepare fail", "protocol", protocolName, "err", err.Error())
+		return []byte{}, err
+	}
 
-		// mpc private share
-		preSetValue = append(preSetValue, *value)
+	preSetValue = append(preSetValue, values...)
 
-		// mpc gpk for sign
-		preSetValue = append(preSetValue, *value1)
+	threshold, err := groupThreshold(preSetValue...)
+	if err != nil {
+		return []byte{}, err
+	}
 
-		peers = peers1
-	} else {
-		for i := 0; i < len(mpcServer.StoreManGroup); i++ {
-			peers = append(peers, mpcprotocol.PeerInfo{PeerID: mpcServer.StoreManGroup[i], Seed: 0})
+	if len(peers) < threshold {
+		log.SyslogErr("MpcDistributor createRequestMpcContext fail",
+			"peers", len(peers),
+			"threshold", threshold,
+			"err", mpcprotocol.ErrTooLessStoreman.Error())
+		return []byte{}, mpcprotocol.ErrTooLessStoreman
+	}
+
+	peers = mpcServer.selectPeers(protocolName, peers, preSetValue...)
+	if protocol.Approval != ApprovalNone {
+		// the peers may wait for the approval, by the request or by their policy, until the deadline, which is
+		// independent of the step timeout
+		deadline := time.Now().Add(mpcServer.Protocols.Timeout(protocol.Name).WaitApprovedTimeout())
+		preSetValue = append(preSetValue, MpcValue{mpcprotocol.MpcApprovalDeadline, []big.Int{*big.NewInt(deadline.Unix())}, nil})
+	}
+	preSetValue = append(preSetValue, MpcValue{mpcprotocol.MpcLeader, nil, mpcServer.Self.ID[:]})
+	var reservation []byte
+	if data := approvalData(preSetValue...); data != nil && protocol.Approval != ApprovalNone {
+		reservation, err = validator.ReserveLimit(data)
+		if err != nil {
+			log.SyslogErr("MpcDistributor createRequestMpcContext, reserve gpk limit fail", "err", err.Error())
+			return []byte{}, err
 		}
 	}
-	mpc, err := mpcServer.mpcCreater.CreateContext(ctxType,
+
+	mpc, err := mpcServer.mpcCreater.CreateContext(protocol,
+		true,
 		mpcID,
 		peers,
 		preSetValue...)
 
 	if err != nil {
 		log.SyslogErr("MpcDistributor createRequestMpcContext, CreateContext fail", "err", err.Error())
+		mpcServer.releaseLimit(mpcID, nil, reservation)
 		return []byte{}, err
 	}
 
-	log.SyslogInfo("MpcDistributor createRequestMpcContext", "ctxType", ctxType, "mpcID", mpcID)
+	mpc.setTimeout(mpcServer.Protocols.Timeout(protocol.Name))
+	mpc.setEventPoster(protocol.Name, true, mpcServer.postEvent)
+	mpc.postEvent(MpcContextCreated, -1, nil)
+
+	log.SyslogInfo("MpcDistributor createRequestMpcContext", "protocol", protocolName, "mpcID", mpcID)
 
 	mpcServer.addMpcContext(mpcID, mpc)
 	defer mpcServer.removeMpcContext(mpcID)
 	err = mpc.mainMPCProcess(mpcServer)
+	mpcServer.appendAudit(protocol, mpc, nil, err)
 	if err != nil {
 		log.SyslogErr("MpcDistributor createRequestMpcContext, mainMPCProcess fail", "err", err.Error())
+		mpcServer.releaseLimit(mpcID, mpc, reservation)
 		return []byte{}, err
 	}
 
@@ -327,43 +396,106 @@ func (mpcServer *MpcDistributor) createRequestMpcContext(ctxType int, preSetValu
 	return result, nil
 }
 
-func (mpcServer *MpcDistributor) loadStoremanAddress(address *common.Address) (*MpcValue, *MpcValue, []mpcprotocol.PeerInfo, error) {
-	log.SyslogInfo("MpcDistributor.loadStoremanAddress begin", "address", address.String())
+// loadAccount decrypts the mpc account of the address from the keystore, unless it is already loaded.
+// The caller must hold accMu.
+func (mpcServer *MpcDistributor) loadAccount(address *common.Address) (*mpcAccount, error) {
+	if value, exist := mpcServer.mpcAccountMap[*address]; exist {
+		return value, nil
+	}
 
-	mpcServer.accMu.Lock()
-	defer mpcServer.accMu.Unlock()
-	value, exist := mpcServer.mpcAccountMap[*address]
+	ks := mpcServer.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	key, _, err := GetPrivateShare(ks, *address, mpcServer.enableAwsKms, &mpcServer.kmsInfo, mpcServer.password)
+	if err != nil {
+		return nil, err
+	}
 
-	var key *keystore.Key
-	var err error
-	if !exist {
-		ks := mpcServer.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
-		key, _, err = GetPrivateShare(ks, *address, mpcServer.enableAwsKms, &mpcServer.kmsInfo, mpcServer.password)
+	group := key.Group
+	if group == nil {
+		group, err = mpcServer.legacyStoremanGroup(key)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, err
 		}
+	}
+
+	groupID := group.ID
+	if groupID == "" {
+		groupID = mpcprotocol.DefaultGroupID
+	}
+
+	if group.Threshold <= 0 || len(group.Peers) < group.Threshold {
+		log.SyslogErr("MpcDistributor.loadAccount, invalid threshold in keystore",
+			"address", address.String(),
+			"threshold", group.Threshold)
+		return nil, mpcprotocol.ErrInvalidKeystoreGroup
+	}
 
-		b := make([]byte, 8)
-		peers := make([]mpcprotocol.PeerInfo, len(mpcServer.StoreManGroup))
-		for i := 0; i < len(mpcServer.StoreManGroup); i++ {
-			copy(b[5:], key.WAddress[i*3:])
-			seed := binary.BigEndian.Uint64(b)
-			peers[i].PeerID = mpcServer.StoreManGroup[i]
-			peers[i].Seed = seed
+	peers := make([]mpcprotocol.PeerInfo, len(group.Peers))
+	for i, item := range group.Peers {
+		if len(item.NodeID) != len(peers[i].PeerID) {
+			log.SyslogErr("MpcDistributor.loadAccount, invalid node id in keystore",
+				"address", address.String(),
+				"nodeId", common.ToHex(item.NodeID))
+			return nil, mpcprotocol.ErrInvalidKeystoreGroup
 		}
 
-		value = &mpcAccount{*address, *key.PrivateKey.D, peers, key.Exten}
+		copy(peers[i].PeerID[:], item.NodeID)
+		peers[i].Seed = item.Seed
+	}
+
+	value := &mpcAccount{*address, *key.PrivateKey.D, peers, groupID, group.Threshold, key.Exten}
+	mpcServer.mpcAccountMap[*address] = value
+	return value, nil
+}
+
+// loadStoremanAddress loads the mpc account of the address, returns the private share, gpk, group id and
+// threshold as preset values, and the peers which created the gpk.
+func (mpcServer *MpcDistributor) loadStoremanAddress(address *common.Address) ([]MpcValue, []mpcprotocol.PeerInfo, error) {
+	log.SyslogInfo("MpcDistributor.loadStoremanAddress begin", "address", address.String())
 
-		mpcServer.mpcAccountMap[*address] = value
+	mpcServer.accMu.Lock()
+	defer mpcServer.accMu.Unlock()
+	value, err := mpcServer.loadAccount(address)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	gpkByte, err := hex.DecodeString(value.externString)
 	gpk := crypto.ToECDSAPub(gpkByte)
 
-	return &MpcValue{mpcprotocol.MpcPrivateShare, []big.Int{value.privateShare}, nil},
-		&MpcValue{mpcprotocol.PublicKeyResult, []big.Int{*gpk.X, *gpk.Y}, nil},
-		value.peers,
-		nil
+	if group, err := mpcServer.Groups.Get(value.groupID); err == nil {
+		group.addGPK(gpkByte)
+	}
+
+	values := []MpcValue{
+		{mpcprotocol.MpcPrivateShare, []big.Int{value.privateShare}, nil},
+		{mpcprotocol.PublicKeyResult, []big.Int{*gpk.X, *gpk.Y}, nil}}
+
+	return append(values, groupValues(value.groupID, value.threshold)...), value.peers, nil
+}
+
+// legacyStoremanGroup rebuilds the group of a keystore file which only has the seeds packed in WAddress.
+// Such a keystore file can be migrated by "account migrate" command.
+func (mpcServer *MpcDistributor) legacyStoremanGroup(key *keystore.Key) (*keystore.StoremanGroup, error) {
+	log.SyslogWarning("MpcDistributor.legacyStoremanGroup, storeman group is not recorded in keystore, please migrate it",
+		"address", key.Address.String())
+
+	group, err := mpcServer.Groups.Get(mpcprotocol.DefaultGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := make([][]byte, len(group.Members))
+	for i := range group.Members {
+		nodeIDs[i] = group.Members[i][:]
+	}
+
+	legacy, err := keystore.LegacyStoremanGroup(key.WAddress, nodeIDs, group.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	legacy.ID = group.ID
+	return legacy, nil
 }
 
 func (mpcServer *MpcDistributor) SetMessagePeers(mpcMessage *mpcprotocol.MpcMessage, peers *[]mpcprotocol.PeerInfo) {
@@ -371,33 +503,17 @@ func (mpcServer *MpcDistributor) SetMessagePeers(mpcMessage *mpcprotocol.MpcMess
 		return
 	}
 
-	mpcMessage.Peers = make([]byte, len(*peers)*4)
-	for i, peer := range *peers {
-		mpcMessage.Peers[i*4] = mpcServer.storeManIndex[peer.PeerID]
-		b := make([]byte, 8)
-		binary.BigEndian.PutUint64(b, peer.Seed)
-		copy(mpcMessage.Peers[i*4+1:], b[5:])
-	}
+	mpcMessage.Peers = make([]mpcprotocol.PeerInfo, len(*peers))
+	copy(mpcMessage.Peers, *peers)
 }
 
 func (mpcServer *MpcDistributor) getMessagePeers(mpcMessage *mpcprotocol.MpcMessage) *[]mpcprotocol.PeerInfo {
-	peerLen := len(mpcMessage.Peers)
-	if peerLen == 0 || peerLen%4 != 0 {
+	if len(mpcMessage.Peers) == 0 {
 		return nil
 	}
 
-	peerLen = peerLen / 4
-	peers := make([]mpcprotocol.PeerInfo, peerLen)
-	b := make([]byte, 8)
-	for i := 0; i < peerLen; i++ {
-		peerIndex := int(mpcMessage.Peers[i*4])
-		if peerIndex < len(mpcServer.StoreManGroup) {
-			peers[i].PeerID = mpcServer.StoreManGroup[peerIndex]
-			copy(b[5:], mpcMessage.Peers[i*4+1:])
-			peers[i].Seed = binary.BigEndian.Uint64(b)
-		}
-	}
-
+	peers := make([]mpcprotocol.PeerInfo, len(mpcMessage.Peers))
+	copy(peers, mpcMessage.Peers)
 	return &peers
 }
 
@@ -420,17 +536,44 @@ func (mpcServer *MpcDistributor) getMpcID() (uint64, error) {
 	}
 }
 
-func (mpcServer *MpcDistributor) QuitMpcContext(msg *mpcprotocol.MpcMessage) {
+func (mpcServer *MpcDistributor) QuitMpcContext(PeerID *discover.NodeID, msg *mpcprotocol.MpcMessage) {
 	mpcServer.mu.RLock()
 	mpc, exist := mpcServer.mpcMap[msg.ContextID]
 	mpcServer.mu.RUnlock()
-	if exist {
-		mpc.quit(errors.New(string(msg.Peers[:])))
+	if !exist {
+		return
 	}
+
+	if !mpc.isParticipant(PeerID) {
+		log.SyslogErr("QuitMpcContext fail",
+			"ctxId", msg.ContextID,
+			"peer", PeerID.String(),
+			"err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return
+	}
+
+	mpc.quit(errors.New(string(msg.ErrMsg[:])))
 }
 
-func (mpcServer *MpcDistributor) createMpcCtx(mpcMessage *mpcprotocol.MpcMessage, preSetValue ...MpcValue) error {
-	log.SyslogInfo("MpcDistributor createMpcCtx begin")
+func (mpcServer *MpcDistributor) createMpcCtx(PeerID *discover.NodeID, mpcMessage *mpcprotocol.MpcMessage, preSetValue ...MpcValue) error {
+	log.SyslogInfo("MpcDistributor createMpcCtx begin", "peer", PeerID.String())
+
+	if !mpcServer.isStoremanNode(PeerID) {
+		log.SyslogErr("createMpcCtx fail", "peer", PeerID.String(), "err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return mpcprotocol.ErrNotMpcParticipant
+	}
+
+	if len(mpcMessage.Peers) == 0 {
+		log.SyslogErr("createMpcCtx fail", "ctxId", mpcMessage.ContextID, "err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return mpcprotocol.ErrNotMpcParticipant
+	}
+
+	for _, item := range mpcMessage.Peers {
+		if !mpcServer.isStoremanNode(&item.PeerID) {
+			log.SyslogErr("createMpcCtx fail", "peer", item.PeerID.String(), "err", mpcprotocol.ErrNotMpcParticipant.Error())
+			return mpcprotocol.ErrNotMpcParticipant
+		}
+	}
 
 	mpcServer.mu.RLock()
 	_, exist := mpcServer.mpcMap[mpcMessage.ContextID]
@@ -440,110 +583,365 @@ func (mpcServer *MpcDistributor) createMpcCtx(mpcMessage *mpcprotocol.MpcMessage
 		return mpcprotocol.ErrMpcContextExist
 	}
 
-	var ctxType int
-	nType := mpcMessage.Data[0].Int64()
-	nByApprove := mpcMessage.Data[1].Int64()
-	if nType == mpcprotocol.MpcGPKLeader {
-		ctxType = mpcprotocol.MpcGPKPeer
-	} else {
-		ctxType = mpcprotocol.MpcSignPeer
+	if len(mpcMessage.Data) == 0 || len(mpcMessage.BytesData) == 0 {
+		log.SyslogErr("createMpcCtx fail", "ctxId", mpcMessage.ContextID, "err", mpcprotocol.ErrInvalidProtocol.Error())
+		return mpcprotocol.ErrInvalidProtocol
 	}
 
-	log.SyslogInfo("createMpcCtx", "ctxType", ctxType, "ctxId", mpcMessage.ContextID)
-	if ctxType == mpcprotocol.MpcSignPeer {
-		log.SyslogInfo("createMpcCtx MpcSignPeer")
-		mpcM := mpcMessage.BytesData[0]
-		address := mpcMessage.BytesData[1]
-		mpcExt := mpcMessage.BytesData[2]
+	protocol, err := mpcServer.Protocols.Get(string(mpcMessage.BytesData[0]))
+	if err != nil {
+		log.SyslogErr("createMpcCtx fail", "ctxId", mpcMessage.ContextID, "err", err.Error())
+		return err
+	}
 
-		//add := common.Address{}
-		//copy(add[:], address)
+	nByApprove := mpcMessage.Data[0].Int64()
+	log.SyslogInfo("createMpcCtx", "protocol", protocol.Name, "ctxId", mpcMessage.ContextID)
 
-		add, err := shcnorrmpc.PkToAddress(address[:])
-		if err != nil {
-			return err
+	requestValues, err := protocol.requestValues(mpcMessage.BytesData[1:])
+	if err != nil {
+		return err
+	}
+
+	preSetValue = append(preSetValue, requestValues...)
+
+	// the sender and all message peers must be allowed to take part in the protocol
+	allowedPeers, values, err := protocol.Prepare(mpcServer, preSetValue...)
+	if err != nil {
+		log.SyslogErr("createMpcCtx, prepare fail", "ctxId", mpcMessage.ContextID, "err", err.Error())
+		return err
+	}
+
+	if !containsPeer(allowedPeers, PeerID) {
+		log.SyslogErr("createMpcCtx fail", "peer", PeerID.String(), "err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return mpcprotocol.ErrNotMpcParticipant
+	}
+
+	for _, item := range mpcMessage.Peers {
+		if !containsPeer(allowedPeers, &item.PeerID) {
+			log.SyslogErr("createMpcCtx fail", "peer", item.PeerID.String(), "err", mpcprotocol.ErrNotMpcParticipant.Error())
+			return mpcprotocol.ErrNotMpcParticipant
 		}
+	}
+
+	preSetValue = append(preSetValue, values...)
+	preSetValue = append(preSetValue,
+		MpcValue{mpcprotocol.MpcLeader, nil, PeerID[:]},
+		MpcValue{mpcprotocol.MpcByApprove, []big.Int{*big.NewInt(nByApprove)}, nil})
 
-		log.SyslogInfo("createMpcCtx", "address", address, "mpcM", mpcM)
-		// load account
-		MpcPrivateShare, MpcPubKey, _, err := mpcServer.loadStoremanAddress(&add)
+	var approval, reservation []byte
+	if protocol.Approval != ApprovalNone {
+		approval, reservation, err = mpcServer.validateData(protocol, mpcMessage, nByApprove, preSetValue...)
 		if err != nil {
+			mpcServer.auditRefused(protocol, mpcMessage, nByApprove, err, preSetValue...)
 			return err
 		}
+	}
 
-		preSetValue = append(preSetValue, MpcValue{mpcprotocol.MpcAddress, nil, address})
-		preSetValue = append(preSetValue, MpcValue{mpcprotocol.MpcM, nil, mpcM})
-		preSetValue = append(preSetValue, MpcValue{mpcprotocol.MpcExt, nil, mpcExt})
-		preSetValue = append(preSetValue, *MpcPrivateShare)
-		preSetValue = append(preSetValue, *MpcPubKey)
-
-		receivedData := &mpcprotocol.SendData{PKBytes: address, Data: mpcM[:], Extern: string(mpcExt[:])}
-
-		if nByApprove != 0 {
-			addApprovingResult := validator.AddApprovingData(receivedData)
-			if addApprovingResult != nil {
-				mpcMsg := &mpcprotocol.MpcMessage{ContextID: mpcMessage.ContextID,
-					StepID: 0,
-					Peers:  []byte(mpcprotocol.ErrFailedAddApproving.Error())}
-				peerInfo := mpcServer.getMessagePeers(mpcMessage)
-				peerIDs := make([]discover.NodeID, 0)
-				for _, item := range *peerInfo {
-					peerIDs = append(peerIDs, item.PeerID)
-				}
+	mpc, err := mpcServer.mpcCreater.CreateContext(protocol,
+		false,
+		mpcMessage.ContextID,
+		*mpcServer.getMessagePeers(mpcMessage),
+		preSetValue...)
 
-				//mpcServer.BroadcastMessage(peerIDs, mpcprotocol.MPCError, mpcMsg)
-				mpcServer.P2pMessage(&mpcServer.Self.ID, mpcprotocol.MPCError, mpcMsg)
+	if err != nil {
+		log.SyslogErr("createMpcContext, createContext fail", "err", err.Error())
+		if protocol.Approval != ApprovalNone {
+			mpcServer.settleApproval(mpcMessage.ContextID, nil, err, preSetValue...)
+			mpcServer.releaseLimit(mpcMessage.ContextID, nil, reservation)
+		}
+		return err
+	}
 
-				log.SyslogErr("createMpcContext, AddApprovingData  fail",
-					"ContextID", mpcMessage.ContextID, "err", addApprovingResult.Error())
-				return mpcprotocol.ErrFailedAddApproving
-			}
+	mpc.setTimeout(mpcServer.Protocols.Timeout(protocol.Name))
+	mpc.setEventPoster(protocol.Name, false, mpcServer.postEvent)
+	mpc.postEvent(MpcContextCreated, -1, nil)
+
+	go func() {
+		mpcServer.addMpcContext(mpcMessage.ContextID, mpc)
+		defer mpcServer.removeMpcContext(mpcMessage.ContextID)
+		err := mpc.mainMPCProcess(mpcServer)
+		mpcServer.appendAudit(protocol, mpc, approval, err)
+		if protocol.Approval != ApprovalNone {
+			mpcServer.settleApproval(mpcMessage.ContextID, mpc, err, preSetValue...)
 		}
+		if err != nil {
+			mpcServer.releaseLimit(mpcMessage.ContextID, mpc, reservation)
+		}
+	}()
 
+	return nil
+}
 
-		verifyResult, err := validator.ValidateData(receivedData)
+// validateData validates the data requested to be signed, and adds it to approving data if it needs approval.
+// It returns the approval record of the data, and the reservation of the gpk limits made for the data.
+func (mpcServer *MpcDistributor) validateData(protocol *MpcProtocol,
+	mpcMessage *mpcprotocol.MpcMessage,
+	byApprove int64,
+	preSetValue ...MpcValue) ([]byte, []byte, error) {
+
+	receivedData := approvalData(preSetValue...)
+	if receivedData == nil {
+		log.SyslogErr("validateData fail, data is missing", "protocol", protocol.Name)
+		return nil, nil, mpcprotocol.ErrInvalidProtocol
+	}
 
-		if !verifyResult {
+	log.SyslogInfo("validateData", "address", receivedData.PKBytes, "mpcM", receivedData.Data)
+
+	if err := validator.CheckPayload(receivedData); err != nil {
+		mpcServer.refuseData(mpcMessage, err, preSetValue...)
+		log.SyslogErr("createMpcContext, check payload fail", "ContextID", mpcMessage.ContextID, "err", err.Error())
+		return nil, nil, err
+	}
+
+	externalApproved, err := validator.ValidateExternal(receivedData, contextSummary(protocol, mpcMessage, byApprove, preSetValue...))
+	if err != nil {
+		mpcServer.refuseData(mpcMessage, err, preSetValue...)
+		log.SyslogErr("createMpcContext, external validator refuses data", "ContextID", mpcMessage.ContextID, "err", err.Error())
+		return nil, nil, err
+	}
+
+	decision, err := validator.ApplyPolicy(receivedData)
+	if err == nil && decision == validator.DecisionReject {
+		err = mpcprotocol.ErrPolicyRejected
+	}
+
+	if err != nil {
+		mpcServer.refuseData(mpcMessage, err, preSetValue...)
+
+		log.SyslogErr("createMpcContext, apply approval policy fail", "ContextID", mpcMessage.ContextID, "err", err.Error())
+		return nil, nil, err
+	}
+
+	if decision == validator.DecisionNone && protocol.needApproval(byApprove) {
+		addApprovingResult := validator.AddApprovingData(receivedData)
+		if addApprovingResult != nil {
 			mpcMsg := &mpcprotocol.MpcMessage{ContextID: mpcMessage.ContextID,
 				StepID: 0,
-				//Peers:  []byte(mpcprotocol.ErrFailedDataVerify.Error())}
-				Peers: []byte(err.Error())}
-			peerInfo := mpcServer.getMessagePeers(mpcMessage)
-			peerIDs := make([]discover.NodeID, 0)
-			for _, item := range *peerInfo {
-				peerIDs = append(peerIDs, item.PeerID)
-			}
+				ErrMsg: []byte(mpcprotocol.ErrFailedAddApproving.Error())}
 
 			//mpcServer.BroadcastMessage(peerIDs, mpcprotocol.MPCError, mpcMsg)
 			mpcServer.P2pMessage(&mpcServer.Self.ID, mpcprotocol.MPCError, mpcMsg)
 
-			log.SyslogErr("createMpcContext, verify data fail", "ContextID", mpcMessage.ContextID)
-			//return mpcprotocol.ErrFailedDataVerify
-			return err
+			log.SyslogErr("createMpcContext, AddApprovingData  fail",
+				"ContextID", mpcMessage.ContextID, "err", addApprovingResult.Error())
+			return nil, nil, mpcprotocol.ErrFailedAddApproving
 		}
+	}
 
-	} else if ctxType == mpcprotocol.MpcGPKPeer {
-		//ToDo add log info
-		//ToDo change reqMPC message sent
+	if externalApproved && decision == validator.DecisionNone && !protocol.needApproval(byApprove) {
+		if err := validator.ApproveByExternal(receivedData); err != nil {
+			log.SyslogErr("createMpcContext, add data approved by external validator fail", "ContextID", mpcMessage.ContextID, "err", err.Error())
+			return nil, nil, err
+		}
 	}
 
-	mpc, err := mpcServer.mpcCreater.CreateContext(ctxType,
-		mpcMessage.ContextID,
-		*mpcServer.getMessagePeers(mpcMessage),
-		preSetValue...)
+	approvalEvent := MpcEvent{
+		Type:      MpcApprovalWaiting,
+		ContextID: mpcMessage.ContextID,
+		Protocol:  protocol.Name,
+		Step:      -1,
+		Time:      time.Now()}
+	mpcServer.postEvent(approvalEvent)
 
+	verifyResult, err := validator.ValidateData(receivedData, mpcServer.approvalTimeout(protocol, mpcMessage))
+
+	approvalEvent.Type, approvalEvent.Err, approvalEvent.Time = MpcApprovalDone, nil, time.Now()
+	if !verifyResult {
+		approvalEvent.Type, approvalEvent.Err = MpcApprovalFailed, err
+	}
+	mpcServer.postEvent(approvalEvent)
+
+	if !verifyResult {
+		//mpcServer.BroadcastMessage(peerIDs, mpcprotocol.MPCError, mpcMsg)
+		mpcServer.refuseData(mpcMessage, err, preSetValue...)
+
+		log.SyslogErr("createMpcContext, verify data fail", "ContextID", mpcMessage.ContextID)
+		//return mpcprotocol.ErrFailedDataVerify
+		return nil, nil, err
+	}
+
+	// the limits hold even for the approved data, so a leader can not drain the gpk by many small requests
+	reservation, err := validator.ReserveLimit(receivedData)
 	if err != nil {
-		log.SyslogErr("createMpcContext, createContext fail", "err", err.Error())
-		return err
+		mpcServer.settleApproval(mpcMessage.ContextID, nil, err, preSetValue...)
+		mpcServer.refuseData(mpcMessage, err, preSetValue...)
+		log.SyslogErr("createMpcContext, reserve gpk limit fail", "ContextID", mpcMessage.ContextID, "err", err.Error())
+		return nil, nil, err
 	}
 
-	go func() {
-		mpcServer.addMpcContext(mpcMessage.ContextID, mpc)
-		defer mpcServer.removeMpcContext(mpcMessage.ContextID)
-		err = mpc.mainMPCProcess(mpcServer)
-	}()
+	approval, err := validator.GetApprovedData(receivedData)
+	if err != nil {
+		log.SyslogWarning("createMpcContext, get approval record fail", "ContextID", mpcMessage.ContextID, "err", err.Error())
+	}
 
-	return nil
+	return approval, reservation, nil
+}
+
+// refuseData sends the reason why the data is refused to self and to the leader, so the caller fails at once.
+func (mpcServer *MpcDistributor) refuseData(mpcMessage *mpcprotocol.MpcMessage, err error, preSetValue ...MpcValue) {
+	mpcMsg := &mpcprotocol.MpcMessage{ContextID: mpcMessage.ContextID,
+		StepID: 0,
+		ErrMsg: []byte(err.Error())}
+
+	mpcServer.P2pMessage(&mpcServer.Self.ID, mpcprotocol.MPCError, mpcMsg)
+
+	if leader := findMpcValue(mpcprotocol.MpcLeader, preSetValue...); leader != nil {
+		var leaderID discover.NodeID
+		copy(leaderID[:], leader.ByteValue)
+		if leaderID != mpcServer.Self.ID {
+			mpcServer.P2pMessage(&leaderID, mpcprotocol.MPCError, mpcMsg)
+		}
+	}
+}
+
+// contextSummary describes the context requesting the data for the external validator.
+func contextSummary(protocol *MpcProtocol,
+	mpcMessage *mpcprotocol.MpcMessage,
+	byApprove int64,
+	preSetValue ...MpcValue) *validator.ContextSummary {
+
+	summary := &validator.ContextSummary{
+		ContextID: mpcMessage.ContextID,
+		Protocol:  protocol.Name,
+		ByApprove: protocol.needApproval(byApprove),
+	}
+
+	if leader := findMpcValue(mpcprotocol.MpcLeader, preSetValue...); leader != nil {
+		var leaderID discover.NodeID
+		copy(leaderID[:], leader.ByteValue)
+		summary.Leader = leaderID.String()
+	}
+
+	for _, item := range mpcMessage.Peers {
+		summary.Peers = append(summary.Peers, item.PeerID.String())
+	}
+
+	if len(mpcMessage.Data) > 1 && mpcMessage.Data[1].Sign() > 0 {
+		summary.Deadline = mpcMessage.Data[1].Int64()
+	}
+
+	return summary
+}
+
+// approvalData returns the data validated by the approval, nil if the data is missing.
+func approvalData(preSetValue ...MpcValue) *mpcprotocol.SendData {
+	address := findMpcValue(mpcprotocol.MpcAddress, preSetValue...)
+	mpcM := findMpcValue(mpcprotocol.MpcM, preSetValue...)
+	mpcExt := findMpcValue(mpcprotocol.MpcExt, preSetValue...)
+	if address == nil || mpcM == nil || mpcExt == nil {
+		return nil
+	}
+
+	return &mpcprotocol.SendData{PKBytes: address.ByteValue, Data: mpcM.ByteValue, Extern: string(mpcExt.ByteValue)}
+}
+
+// settleApproval settles the approval claimed by the context when the context ends, mpc is nil if the context
+// is not run. Once the context has sent the signature share of the node, the peers may complete the signature
+// without it, so the approval is consumed and the data can not be signed again by the same approval. The approval
+// is released only for a context failing before that.
+func (mpcServer *MpcDistributor) settleApproval(ctxID uint64, mpc MpcInterface, ctxErr error, preSetValue ...MpcValue) {
+	data := approvalData(preSetValue...)
+	if data == nil {
+		return
+	}
+
+	if ctxErr != nil && (mpc == nil || !mpc.shareSent()) {
+		if err := validator.ReleaseApprovedData(data); err != nil {
+			log.SyslogErr("release approval fail", "ctxId", ctxID, "err", err.Error())
+		}
+		return
+	}
+
+	if err := validator.ConsumeApprovedData(data); err != nil {
+		log.SyslogErr("consume approval fail", "ctxId", ctxID, "err", err.Error())
+	}
+}
+
+// releaseLimit releases the reservation of the gpk limits made for a context which failed to sign, mpc is nil if
+// the context is not run. Once the context has sent the signature share of the node, the peers may complete the
+// signature without it, so the reservation is kept.
+func (mpcServer *MpcDistributor) releaseLimit(ctxID uint64, mpc MpcInterface, reservation []byte) {
+	if len(reservation) == 0 || (mpc != nil && mpc.shareSent()) {
+		return
+	}
+
+	if err := validator.ReleaseLimit(reservation); err != nil {
+		log.SyslogErr("release gpk limit fail", "ctxId", ctxID, "err", err.Error())
+	}
+}
+
+// approvalTimeout returns the time to wait for the data being approved, which is bounded by the deadline of the
+// request if the leader has set one.
+func (mpcServer *MpcDistributor) approvalTimeout(protocol *MpcProtocol, mpcMessage *mpcprotocol.MpcMessage) time.Duration {
+	timeout := mpcServer.Protocols.Timeout(protocol.Name).WaitApprovedTimeout()
+	if len(mpcMessage.Data) > 1 && mpcMessage.Data[1].Sign() > 0 {
+		if untilDeadline := time.Until(time.Unix(mpcMessage.Data[1].Int64(), 0)); untilDeadline < timeout {
+			timeout = untilDeadline
+		}
+	}
+
+	return timeout
+}
+
+// SetAuditLog sets the log which records every finished mpc context.
+func (mpcServer *MpcDistributor) SetAuditLog(auditLog *audit.Log) {
+	mpcServer.auditLog = auditLog
+}
+
+func (mpcServer *MpcDistributor) appendAudit(protocol *MpcProtocol, mpc MpcInterface, approval []byte, err error) {
+	if mpcServer.auditLog == nil {
+		return
+	}
+
+	rec := mpc.auditRecord(err)
+	if protocol.Approval == ApprovalNone {
+		rec.Approval = nil
+	} else if rec.Approval != nil {
+		rec.Approval.Data = approval
+	}
+
+	if errAppend := mpcServer.auditLog.Append(rec); errAppend != nil {
+		log.SyslogErr("MpcDistributor append audit record fail", "ctxId", rec.ContextID, "err", errAppend.Error())
+	}
+}
+
+// auditRefused records the context whose data is refused by validateData, which fails before the context is created.
+func (mpcServer *MpcDistributor) auditRefused(protocol *MpcProtocol,
+	mpcMessage *mpcprotocol.MpcMessage,
+	byApprove int64,
+	err error,
+	preSetValue ...MpcValue) {
+
+	if mpcServer.auditLog == nil {
+		return
+	}
+
+	rec := &audit.Record{
+		Time:      time.Now().Unix(),
+		ContextID: mpcMessage.ContextID,
+		Protocol:  protocol.Name,
+		Peers:     make([]string, len(mpcMessage.Peers)),
+		Approval:  &audit.Approval{Required: byApprove != 0},
+		Err:       err.Error(),
+	}
+
+	for i, item := range mpcMessage.Peers {
+		rec.Peers[i] = item.PeerID.String()
+	}
+
+	if leader := findMpcValue(mpcprotocol.MpcLeader, preSetValue...); leader != nil {
+		rec.Leader = common.ToHex(leader.ByteValue)
+	}
+
+	if data := approvalData(preSetValue...); data != nil {
+		rec.GPK = data.PKBytes
+		rec.MsgHash = common.Hash(sha256.Sum256(data.Data))
+		rec.Extern = data.Extern
+	}
+
+	if errAppend := mpcServer.auditLog.Append(rec); errAppend != nil {
+		log.SyslogErr("MpcDistributor append audit record fail", "ctxId", rec.ContextID, "err", errAppend.Error())
+	}
 }
 
 func (mpcServer *MpcDistributor) addMpcContext(mpcID uint64, mpc MpcInterface) {
@@ -571,11 +969,19 @@ func (mpcServer *MpcDistributor) getMpcMessage(PeerID *discover.NodeID, mpcMessa
 	mpcServer.mu.RLock()
 	mpc, exist := mpcServer.mpcMap[mpcMessage.ContextID]
 	mpcServer.mu.RUnlock()
-	if exist {
-		return mpc.getMessage(PeerID, mpcMessage, mpcServer.getMessagePeers(mpcMessage))
+	if !exist {
+		return nil
 	}
 
-	return nil
+	if !mpc.isParticipant(PeerID) {
+		log.SyslogErr("getMpcMessage fail",
+			"ctxId", mpcMessage.ContextID,
+			"peer", PeerID.String(),
+			"err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return mpcprotocol.ErrNotMpcParticipant
+	}
+
+	return mpc.getMessage(PeerID, mpcMessage, mpcServer.getMessagePeers(mpcMessage))
 }
 
 func (mpcServer *MpcDistributor) getOwnerP2pMessage(PeerID *discover.NodeID, code uint64, msg interface{}) error {
@@ -590,15 +996,138 @@ func (mpcServer *MpcDistributor) getOwnerP2pMessage(PeerID *discover.NodeID, cod
 	return nil
 }
 
+// SubscribeMpcEvent registers a subscription of the lifecycle events of mpc contexts.
+// The mpc process never waits for the subscriber, the events are dropped if its queue of
+// MpcEventQueueSize events is full.
+func (mpcServer *MpcDistributor) SubscribeMpcEvent(ch chan<- MpcEvent) event.Subscription {
+	queue := make(chan MpcEvent, MpcEventQueueSize)
+
+	mpcServer.eventMu.Lock()
+	if mpcServer.eventQueues == nil {
+		mpcServer.eventQueues = make(map[chan MpcEvent]struct{})
+	}
+	mpcServer.eventQueues[queue] = struct{}{}
+	mpcServer.eventMu.Unlock()
+
+	return mpcServer.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			mpcServer.eventMu.Lock()
+			delete(mpcServer.eventQueues, queue)
+			mpcServer.eventMu.Unlock()
+		}()
+
+		for {
+			select {
+			case ev := <-queue:
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}))
+}
+
+func (mpcServer *MpcDistributor) postEvent(ev MpcEvent) {
+	mpcServer.metrics.update(ev)
+
+	mpcServer.eventMu.Lock()
+	defer mpcServer.eventMu.Unlock()
+	for queue := range mpcServer.eventQueues {
+		select {
+		case queue <- ev:
+		default:
+			log.SyslogWarning("MpcDistributor, mpc event subscriber is too slow, drop event",
+				"ctxId", ev.ContextID,
+				"type", ev.Type.String())
+		}
+	}
+}
+
+// Stop unsubscribes all the subscriptions of mpc events.
+func (mpcServer *MpcDistributor) Stop() {
+	mpcServer.scope.Close()
+}
+
+// SetExtraPeers sets the number of peers selected besides the threshold.
+func (mpcServer *MpcDistributor) SetExtraPeers(extraPeers int) {
+	mpcServer.extraPeers = extraPeers
+}
+
+// selectPeers selects the threshold+k healthy peers with the lowest rtt, if the protocol asks for it.
+func (mpcServer *MpcDistributor) selectPeers(protocolName string, peers []mpcprotocol.PeerInfo, preSetValue ...MpcValue) []mpcprotocol.PeerInfo {
+	protocol, err := mpcServer.Protocols.Get(protocolName)
+	if err != nil || !protocol.SelectPeers {
+		return peers
+	}
+
+	threshold, err := groupThreshold(preSetValue...)
+	if err != nil {
+		return peers
+	}
+
+	return mpcServer.Latency.selectPeers(peers, &mpcServer.Self.ID, threshold+mpcServer.extraPeers, mpcServer.P2pMessager.IsActivePeer)
+}
+
+func (mpcServer *MpcDistributor) ReportPeerRTT(peerID *discover.NodeID, rtt time.Duration) {
+	if *peerID != mpcServer.Self.ID {
+		mpcServer.Latency.ReportRTT(peerID, rtt)
+	}
+}
+
+func (mpcServer *MpcDistributor) ReportPeerTimeout(peerID *discover.NodeID) {
+	if *peerID != mpcServer.Self.ID {
+		log.SyslogWarning("MpcDistributor, peer does not respond in time", "peer", peerID.String())
+		mpcServer.Latency.ReportTimeout(peerID)
+		mpcServer.metrics.peerTimeout(peerID)
+	}
+}
+
+// SetMisbehaviourHandler sets the function called when a peer misbehaves.
+func (mpcServer *MpcDistributor) SetMisbehaviourHandler(handler func(*discover.NodeID, error)) {
+	mpcServer.misbehave = handler
+}
+
+// ReportPeerMisbehaviour reports the misbehaviour of the peer found by the mpc steps or the message handling.
+func (mpcServer *MpcDistributor) ReportPeerMisbehaviour(peerID *discover.NodeID, err error) {
+	if *peerID == mpcServer.Self.ID {
+		return
+	}
+
+	log.SyslogWarning("MpcDistributor, peer misbehaves", "peer", peerID.String(), "err", err.Error())
+	if mpcServer.misbehave != nil {
+		mpcServer.misbehave(peerID, err)
+	}
+}
+
 func (mpcServer *MpcDistributor) SelfNodeId() *discover.NodeID {
 	return &mpcServer.Self.ID
 }
 
+// signMessage signs the outgoing mpc message with the node key, other messages are sent as they are.
+func (mpcServer *MpcDistributor) signMessage(code uint64, msg interface{}) error {
+	mpcMessage, ok := msg.(*mpcprotocol.MpcMessage)
+	if !ok {
+		return nil
+	}
+
+	return mpcMessage.Sign(code, mpcServer.nodeKey)
+}
+
 func (mpcServer *MpcDistributor) P2pMessage(peerID *discover.NodeID, code uint64, msg interface{}) error {
 	if *peerID == mpcServer.Self.ID {
 		mpcServer.getOwnerP2pMessage(&mpcServer.Self.ID, code, msg)
 	} else {
-		err := mpcServer.P2pMessager.SendToPeer(peerID, code, msg)
+		err := mpcServer.signMessage(code, msg)
+		if err != nil {
+			log.SyslogErr("P2pMessage, sign message fail", "err", err.Error())
+			return err
+		}
+
+		err = mpcServer.sendToPeer(peerID, code, msg)
 		if err != nil {
 			log.SyslogErr("BroadcastMessage fail", "err", err.Error())
 		}
@@ -608,13 +1137,19 @@ func (mpcServer *MpcDistributor) P2pMessage(peerID *discover.NodeID, code uint64
 }
 
 func (mpcServer *MpcDistributor) BroadcastMessage(peers []discover.NodeID, code uint64, msg interface{}) error {
+	err := mpcServer.signMessage(code, msg)
+	if err != nil {
+		log.SyslogErr("BroadcastMessage, sign message fail", "err", err.Error())
+		return err
+	}
+
 	if peers == nil {
-		log.Info("Entering BroadcastMessage using mpcServer.StoreManGroup")
-		for _, peer := range mpcServer.StoreManGroup {
+		log.Info("Entering BroadcastMessage using all group members")
+		for _, peer := range mpcServer.Groups.AllMembers() {
 			if peer == mpcServer.Self.ID {
 				mpcServer.getOwnerP2pMessage(&mpcServer.Self.ID, code, msg)
 			} else {
-				err := mpcServer.P2pMessager.SendToPeer(&peer, code, msg)
+				err := mpcServer.sendToPeer(&peer, code, msg)
 				if err != nil {
 					log.SyslogErr("BroadcastMessage fail", "peer", peer.String(), "err", err.Error())
 				}
@@ -626,7 +1161,7 @@ func (mpcServer *MpcDistributor) BroadcastMessage(peers []discover.NodeID, code
 			if peerID == mpcServer.Self.ID {
 				mpcServer.getOwnerP2pMessage(&mpcServer.Self.ID, code, msg)
 			} else {
-				err := mpcServer.P2pMessager.SendToPeer(&peerID, code, msg)
+				err := mpcServer.sendToPeer(&peerID, code, msg)
 				if err != nil {
 					log.SyslogErr("BroadcastMessage fail", "peer", peerID.String(), "err", err.Error())
 				}
@@ -639,12 +1174,12 @@ func (mpcServer *MpcDistributor) BroadcastMessage(peers []discover.NodeID, code
 
 func (mpcServer *MpcDistributor) newStoremanKeyStore(pKey *ecdsa.PublicKey,
 	pShare *big.Int,
-	seeds []uint64,
+	group *keystore.StoremanGroup,
 	passphrase string,
 	accType string) (accounts.Account, error) {
 
 	ks := mpcServer.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
-	account, err := ks.NewStoremanAccount(pKey, pShare, seeds, passphrase, accType)
+	account, err := ks.NewStoremanAccount(pKey, pShare, group, passphrase, accType)
 	if err != nil {
 		log.SyslogErr("NewStoremanKeyStore fail", "err", err.Error())
 	} else {
@@ -676,17 +1211,39 @@ func (mpcServer *MpcDistributor) CreateKeystore(result mpcprotocol.MpcResultInte
 	//result1.X = &point[0]
 	result1.X = big.NewInt(0).SetBytes(point[0].Bytes())
 	result1.Y = big.NewInt(0).SetBytes(point[1].Bytes())
-	seed := make([]uint64, len(*peers))
+
+	groupID, err := result.GetByteValue(mpcprotocol.MpcGroupID)
+	if err != nil {
+		log.SyslogErr("CreateKeystore fail. get MpcGroupID fail")
+		return err
+	}
+
+	threshold, err := result.GetValue(mpcprotocol.MpcThreshold)
+	if err != nil {
+		log.SyslogErr("CreateKeystore fail. get MpcThreshold fail")
+		return err
+	}
+
+	group := &keystore.StoremanGroup{
+		ID:        string(groupID),
+		Threshold: int(threshold[0].Int64()),
+		Total:     len(*peers),
+		Peers:     make([]keystore.StoremanPeer, len(*peers))}
 
 	for i, item := range *peers {
-		seed[i] = item.Seed
+		group.Peers[i].NodeID = common.CopyBytes(item.PeerID[:])
+		group.Peers[i].Seed = item.Seed
 	}
 
-	_, err = mpcServer.newStoremanKeyStore(result1, &private[0], seed, mpcServer.password, accType)
+	_, err = mpcServer.newStoremanKeyStore(result1, &private[0], group, mpcServer.password, accType)
 	if err != nil {
 		return err
 	}
 
+	if mpcGroup, err := mpcServer.Groups.Get(group.ID); err == nil {
+		mpcGroup.addGPK(crypto.FromECDSAPub(result1))
+	}
+
 	result.SetByteValue(mpcprotocol.MpcContextResult, crypto.FromECDSAPub(result1))
 	log.Info("CreateKeystore ",
 		"gpk address", crypto.PubkeyToAddress(*result1),
@@ -7,6 +7,7 @@ import (
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"math/big"
 	"testing"
+	"time"
 )
 
 func TestMpcSelectPeers(t *testing.T) {
@@ -35,14 +36,95 @@ func TestMpcSelectPeers(t *testing.T) {
 	}
 
 	mpcDistributor.Self = &discover.Node{ID: peers[0].PeerID}
-	mpcDistributor.StoreManGroup = make([]discover.NodeID, nThread)
+	members := make([]discover.NodeID, nThread)
 	for i, item := range peers {
-		mpcDistributor.StoreManGroup[i] = item.PeerID
+		members[i] = item.PeerID
 	}
 
+	group, _ := NewMpcGroup(mpcprotocol.DefaultGroupID, members, nThread/2+1, nThread)
+	mpcDistributor.Groups.Register(group)
+
 	txHash := common.HexToHash("340dd630ad21bf010b4e676dbfa9ba9a02175262d1fa356232cfde6cb5b47ef2")
-	mpcDistributor.selectPeers(mpcprotocol.MpcSignLeader, peers, MpcValue{mpcprotocol.MpcTxHash, []big.Int{*txHash.Big()}, nil})
+	mpcDistributor.selectPeers(mpcprotocol.MpcSignProtocol, peers, MpcValue{mpcprotocol.MpcTxHash, []big.Int{*txHash.Big()}, nil})
 	//common.HexToHash("426fcb404ab2d5d8e61a3d918108006bbb0a9be65e92235bb10eefbdb6dcd053"),
 	//common.HexToHash("48078cfed56339ea54962e72c37c7f588fc4f8e5bc173827ba75cb10a63a96a5"),
 	//common.HexToHash("5723d2c3a83af9b735e3b7f21531e5623d183a9095a56604ead41f3582fdfb75"),
 }
+
+func TestSubscribeMpcEvent(t *testing.T) {
+	mpcServer := &MpcDistributor{}
+	defer mpcServer.Stop()
+
+	// a subscriber keeping up gets every event in order
+	fast := make(chan MpcEvent, 8)
+	fastSub := mpcServer.SubscribeMpcEvent(fast)
+	defer fastSub.Unsubscribe()
+
+	// a subscriber not reading blocks nobody, its events beyond the queue are dropped
+	slow := make(chan MpcEvent)
+	slowSub := mpcServer.SubscribeMpcEvent(slow)
+	defer slowSub.Unsubscribe()
+
+	posted := MpcEventQueueSize + 10
+	for i := 0; i < posted; i++ {
+		done := make(chan struct{})
+		go func(i int) {
+			mpcServer.postEvent(MpcEvent{Type: MpcStepFinished, ContextID: uint64(i)})
+			close(done)
+		}(i)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("postEvent %d waits for the subscribers", i)
+		}
+
+		select {
+		case ev := <-fast:
+			if ev.ContextID != uint64(i) {
+				t.Fatalf("fast subscriber got event %d, want %d", ev.ContextID, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("fast subscriber missed event %d", i)
+		}
+	}
+
+	// the queue holds MpcEventQueueSize events, the subscription may hold one more being delivered
+	received := 0
+	for ; ; received++ {
+		select {
+		case ev := <-slow:
+			if ev.ContextID != uint64(received) {
+				t.Fatalf("slow subscriber got event %d, want %d", ev.ContextID, received)
+			}
+			continue
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+
+	if received < MpcEventQueueSize || received > MpcEventQueueSize+1 {
+		t.Errorf("slow subscriber got %d events, want %d", received, MpcEventQueueSize)
+	}
+
+	// the slow subscriber gets the events again once it reads
+	mpcServer.postEvent(MpcEvent{Type: MpcContextFinished, ContextID: uint64(posted)})
+	select {
+	case ev := <-slow:
+		if ev.ContextID != uint64(posted) {
+			t.Errorf("slow subscriber got event %d, want %d", ev.ContextID, posted)
+		}
+	case <-time.After(time.Second):
+		t.Error("slow subscriber missed the event after reading")
+	}
+
+	// the unsubscribed subscriber gets nothing
+	slowSub.Unsubscribe()
+	<-fast
+	mpcServer.postEvent(MpcEvent{Type: MpcContextFinished})
+	select {
+	case <-slow:
+		t.Error("unsubscribed subscriber got an event")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
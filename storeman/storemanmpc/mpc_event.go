@@ -0,0 +1,82 @@
+package storemanmpc
+
+import (
+	"encoding/json"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"time"
+)
+
+// MpcEventQueueSize is the number of events queued for a subscriber before the events are dropped.
+const MpcEventQueueSize = 256
+
+// MpcEventType is the type of mpc lifecycle event.
+type MpcEventType int
+
+const (
+	MpcContextCreated  MpcEventType = iota // the context is created
+	MpcStepStarted                         // a step begins
+	MpcStepFinished                        // a step finishes
+	MpcStepTimeout                         // a step times out, the context continues with partial responses or fails
+	MpcApprovalWaiting                     // the data is waiting for being approved
+	MpcApprovalDone                        // the data is approved
+	MpcApprovalFailed                      // the data is not approved in time or not consistent
+	MpcContextFinished                     // the context succeeds
+	MpcContextFailed                       // the context fails
+)
+
+var mpcEventNames = []string{
+	"created", "stepStarted", "stepFinished", "stepTimeout",
+	"approvalWaiting", "approvalDone", "approvalFailed", "finished", "failed",
+}
+
+func (t MpcEventType) String() string {
+	if t < 0 || int(t) >= len(mpcEventNames) {
+		return "unknown"
+	}
+
+	return mpcEventNames[t]
+}
+
+// MpcEvent is posted to the subscribers of MpcDistributor during the lifecycle of mpc contexts.
+type MpcEvent struct {
+	Type      MpcEventType
+	ContextID uint64
+	Protocol  string
+	Leader    bool
+	GPK       hexutil.Bytes
+	Step      int // -1 if the event is not about a step
+	Peers     []discover.NodeID
+	Err       error
+	Time      time.Time
+}
+
+// MarshalJSON encodes the event for the rpc subscribers, with the type and the error as strings.
+func (ev MpcEvent) MarshalJSON() ([]byte, error) {
+	type mpcEvent struct {
+		Type      string            `json:"type"`
+		ContextID uint64            `json:"contextId"`
+		Protocol  string            `json:"protocol"`
+		Leader    bool              `json:"leader"`
+		GPK       hexutil.Bytes     `json:"gpk,omitempty"`
+		Step      int               `json:"step"`
+		Peers     []discover.NodeID `json:"peers"`
+		Err       string            `json:"err,omitempty"`
+		Time      time.Time         `json:"time"`
+	}
+
+	enc := mpcEvent{
+		Type:      ev.Type.String(),
+		ContextID: ev.ContextID,
+		Protocol:  ev.Protocol,
+		Leader:    ev.Leader,
+		GPK:       ev.GPK,
+		Step:      ev.Step,
+		Peers:     ev.Peers,
+		Time:      ev.Time}
+	if ev.Err != nil {
+		enc.Err = ev.Err.Error()
+	}
+
+	return json.Marshal(&enc)
+}
@@ -0,0 +1,236 @@
+package storemanmpc
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// MpcGroup is a storeman group hosted by the node. Every group has its own members and threshold,
+// and the gpks created by the group can only be signed by its members.
+type MpcGroup struct {
+	ID        string
+	Members   []discover.NodeID // sorted node ids of the members
+	Threshold int
+	Total     int
+
+	index  map[discover.NodeID]int
+	gpkMu  sync.RWMutex
+	gpkSet map[string]bool
+}
+
+// MpcGroupInfo is the summary of a storeman group returned by rpc.
+type MpcGroupInfo struct {
+	ID        string            `json:"id"`
+	Members   []discover.NodeID `json:"members"`
+	Threshold int               `json:"threshold"`
+	Total     int               `json:"total"`
+	GPKs      []string          `json:"gpks"`
+}
+
+func NewMpcGroup(id string, members []discover.NodeID, threshold int, total int) (*MpcGroup, error) {
+	if id == "" {
+		return nil, mpcprotocol.ErrInvalidGroup
+	}
+
+	if total == 0 {
+		total = len(members)
+	}
+
+	if threshold <= 0 || total < threshold {
+		log.SyslogErr("NewMpcGroup fail, should: total nodes >= threshold",
+			"group", id,
+			"threshold", threshold,
+			"total", total,
+			"members", len(members))
+		return nil, mpcprotocol.ErrInvalidGroup
+	}
+
+	group := &MpcGroup{
+		ID:        id,
+		Members:   make([]discover.NodeID, len(members)),
+		Threshold: threshold,
+		Total:     total,
+		index:     make(map[discover.NodeID]int),
+		gpkSet:    make(map[string]bool),
+	}
+
+	copy(group.Members, members)
+	sort.Sort(mpcprotocol.SliceStoremanGroup(group.Members))
+	for i, item := range group.Members {
+		group.index[item] = i
+	}
+
+	return group, nil
+}
+
+func (group *MpcGroup) Degree() int {
+	return group.Threshold - 1
+}
+
+func (group *MpcGroup) IsMember(peerID *discover.NodeID) bool {
+	_, exist := group.index[*peerID]
+	return exist
+}
+
+func (group *MpcGroup) addGPK(gpk []byte) {
+	group.gpkMu.Lock()
+	defer group.gpkMu.Unlock()
+	group.gpkSet[hexutil.Encode(gpk)] = true
+}
+
+func (group *MpcGroup) Info() MpcGroupInfo {
+	group.gpkMu.RLock()
+	defer group.gpkMu.RUnlock()
+
+	info := MpcGroupInfo{
+		ID:        group.ID,
+		Members:   group.Members,
+		Threshold: group.Threshold,
+		Total:     group.Total,
+		GPKs:      make([]string, 0, len(group.gpkSet)),
+	}
+
+	for gpk := range group.gpkSet {
+		info.GPKs = append(info.GPKs, gpk)
+	}
+	sort.Strings(info.GPKs)
+
+	return info
+}
+
+// MpcGroupRegistry keeps all the storeman groups hosted by the node, keyed by group id.
+type MpcGroupRegistry struct {
+	mu     sync.RWMutex
+	groups map[string]*MpcGroup
+}
+
+func NewMpcGroupRegistry() *MpcGroupRegistry {
+	return &MpcGroupRegistry{groups: make(map[string]*MpcGroup)}
+}
+
+func (registry *MpcGroupRegistry) Register(group *MpcGroup) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, exist := registry.groups[group.ID]; exist {
+		return mpcprotocol.ErrGroupExist
+	}
+
+	registry.groups[group.ID] = group
+	log.SyslogInfo("MpcGroupRegistry.Register",
+		"group", group.ID,
+		"threshold", group.Threshold,
+		"total", group.Total,
+		"members", group.Members)
+	return nil
+}
+
+func (registry *MpcGroupRegistry) Get(id string) (*MpcGroup, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	group, exist := registry.groups[id]
+	if !exist {
+		return nil, mpcprotocol.ErrGroupNotExist
+	}
+
+	return group, nil
+}
+
+func (registry *MpcGroupRegistry) Groups() []*MpcGroup {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	groups := make([]*MpcGroup, 0, len(registry.groups))
+	for _, group := range registry.groups {
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
+
+// IsMember returns true if the peer is a member of any group.
+func (registry *MpcGroupRegistry) IsMember(peerID *discover.NodeID) bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	for _, group := range registry.groups {
+		if group.IsMember(peerID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllMembers returns the members of all the groups, without duplicates.
+func (registry *MpcGroupRegistry) AllMembers() []discover.NodeID {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	found := make(map[discover.NodeID]bool)
+	members := make([]discover.NodeID, 0)
+	for _, group := range registry.groups {
+		for _, item := range group.Members {
+			if !found[item] {
+				found[item] = true
+				members = append(members, item)
+			}
+		}
+	}
+
+	return members
+}
+
+// groupValues returns the preset values which pass the group parameters to the mpc steps.
+func groupValues(id string, threshold int) []MpcValue {
+	return []MpcValue{
+		{mpcprotocol.MpcGroupID, nil, []byte(id)},
+		thresholdValue(threshold),
+	}
+}
+
+func thresholdValue(threshold int) MpcValue {
+	return MpcValue{mpcprotocol.MpcThreshold, []big.Int{*big.NewInt(int64(threshold))}, nil}
+}
+
+func findMpcValue(key string, preSetValue ...MpcValue) *MpcValue {
+	for i := range preSetValue {
+		if preSetValue[i].Key == key {
+			return &preSetValue[i]
+		}
+	}
+
+	return nil
+}
+
+// contextLeader returns the node id of the leader preset in the values.
+func contextLeader(preSetValue ...MpcValue) (discover.NodeID, error) {
+	var leaderID discover.NodeID
+	value := findMpcValue(mpcprotocol.MpcLeader, preSetValue...)
+	if value == nil || len(value.ByteValue) != len(leaderID) {
+		log.SyslogErr("contextLeader fail, leader is not preset")
+		return leaderID, mpcprotocol.ErrInvalidProtocol
+	}
+
+	copy(leaderID[:], value.ByteValue)
+	return leaderID, nil
+}
+
+// groupThreshold returns the threshold of the group preset in the values.
+func groupThreshold(preSetValue ...MpcValue) (int, error) {
+	value := findMpcValue(mpcprotocol.MpcThreshold, preSetValue...)
+	if value == nil || len(value.Value) == 0 || value.Value[0].Sign() <= 0 {
+		log.SyslogErr("groupThreshold fail, threshold is not preset")
+		return 0, mpcprotocol.ErrInvalidGroup
+	}
+
+	return int(value.Value[0].Int64()), nil
+}
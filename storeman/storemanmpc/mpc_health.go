@@ -0,0 +1,153 @@
+package storemanmpc
+
+import (
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/accounts/keystore"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+)
+
+// KeystoreCheckCycle is the interval of decrypting the keystore accounts which are not loaded yet.
+const KeystoreCheckCycle = 10 * time.Minute
+
+// MpcKeystoreStatus is the status of the storeman keystore returned by rpc.
+type MpcKeystoreStatus struct {
+	Accounts   int    `json:"accounts"`   // accounts in the keystore
+	Loaded     int    `json:"loaded"`     // accounts decrypted successfully
+	KmsEnabled bool   `json:"kmsEnabled"` // keystore files are encrypted by aws kms
+	Checked    int64  `json:"checked"`    // unix time of the last check, zero if the keystore is not checked yet
+	Err        string `json:"err,omitempty"`
+}
+
+// MpcGPKQuorum reports whether enough peers which created the gpk are live to sign with it.
+type MpcGPKQuorum struct {
+	GPK       string            `json:"gpk"`
+	Group     string            `json:"group"`
+	Threshold int               `json:"threshold"`
+	Live      int               `json:"live"`
+	Missing   []discover.NodeID `json:"missing"`
+	Reachable bool              `json:"reachable"`
+}
+
+func (mpcServer *MpcDistributor) keystore() *keystore.KeyStore {
+	if mpcServer.AccountManager == nil {
+		return nil
+	}
+
+	backends := mpcServer.AccountManager.Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return nil
+	}
+	return backends[0].(*keystore.KeyStore)
+}
+
+// CheckKeystore decrypts every account of the keystore which is not loaded yet, by the password or by aws
+// kms, and records the result as the status of the keystore. The decrypted accounts are loaded, so the
+// quorum of their gpks is known before they sign.
+func (mpcServer *MpcDistributor) CheckKeystore() error {
+	ks := mpcServer.keystore()
+	if ks == nil {
+		return nil
+	}
+
+	var lastErr error
+	for _, account := range ks.Accounts() {
+		mpcServer.accMu.Lock()
+		value, err := mpcServer.loadAccount(&account.Address)
+		mpcServer.accMu.Unlock()
+		if err != nil {
+			log.SyslogErr("MpcDistributor.CheckKeystore, decrypt account fail", "address", account.Address.String(), "err", err.Error())
+			lastErr = err
+			continue
+		}
+
+		if group, err := mpcServer.Groups.Get(value.groupID); err == nil {
+			if gpk, err := hex.DecodeString(value.externString); err == nil {
+				group.addGPK(gpk)
+			}
+		}
+	}
+
+	mpcServer.accMu.Lock()
+	mpcServer.keystoreErr, mpcServer.keystoreCheck = lastErr, time.Now()
+	mpcServer.accMu.Unlock()
+	return lastErr
+}
+
+// KeystoreStatus returns the status of the keystore found by the last CheckKeystore.
+func (mpcServer *MpcDistributor) KeystoreStatus() MpcKeystoreStatus {
+	var accounts int
+	if ks := mpcServer.keystore(); ks != nil {
+		accounts = len(ks.Accounts())
+	}
+
+	mpcServer.accMu.Lock()
+	defer mpcServer.accMu.Unlock()
+
+	status := MpcKeystoreStatus{Accounts: accounts, Loaded: len(mpcServer.mpcAccountMap), KmsEnabled: mpcServer.enableAwsKms}
+	if !mpcServer.keystoreCheck.IsZero() {
+		status.Checked = mpcServer.keystoreCheck.Unix()
+	}
+	if mpcServer.keystoreErr != nil {
+		status.Err = mpcServer.keystoreErr.Error()
+	}
+
+	return status
+}
+
+// GPKQuorums returns the quorum status of the gpk of every account loaded from the keystore, with the peers
+// which created the gpk, and of every other gpk known by the groups, with the members of its group.
+func (mpcServer *MpcDistributor) GPKQuorums(isLive func(*discover.NodeID) bool) []MpcGPKQuorum {
+	quorums := make([]MpcGPKQuorum, 0)
+	known := make(map[string]bool)
+	add := func(gpk, group string, threshold int, peers []discover.NodeID) {
+		quorum := MpcGPKQuorum{GPK: gpk, Group: group, Threshold: threshold, Missing: make([]discover.NodeID, 0)}
+		for i := range peers {
+			if (mpcServer.Self != nil && peers[i] == mpcServer.Self.ID) || isLive(&peers[i]) {
+				quorum.Live++
+			} else {
+				quorum.Missing = append(quorum.Missing, peers[i])
+			}
+		}
+
+		quorum.Reachable = quorum.Live >= quorum.Threshold
+		quorums = append(quorums, quorum)
+		known[gpk] = true
+	}
+
+	mpcServer.accMu.Lock()
+	accounts := make([]*mpcAccount, 0, len(mpcServer.mpcAccountMap))
+	for _, account := range mpcServer.mpcAccountMap {
+		accounts = append(accounts, account)
+	}
+	mpcServer.accMu.Unlock()
+
+	for _, account := range accounts {
+		gpk, err := hex.DecodeString(account.externString)
+		if err != nil {
+			continue
+		}
+
+		peers := make([]discover.NodeID, len(account.peers))
+		for i := range account.peers {
+			peers[i] = account.peers[i].PeerID
+		}
+		add(hexutil.Encode(gpk), account.groupID, account.threshold, peers)
+	}
+
+	for _, group := range mpcServer.Groups.Groups() {
+		info := group.Info()
+		for _, gpk := range info.GPKs {
+			if !known[gpk] {
+				add(gpk, info.ID, info.Threshold, info.Members)
+			}
+		}
+	}
+
+	sort.Slice(quorums, func(i, j int) bool { return quorums[i].GPK < quorums[j].GPK })
+	return quorums
+}
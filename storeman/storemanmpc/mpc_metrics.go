@@ -0,0 +1,141 @@
+// Contains the meters and timers of the mpc contexts.
+
+package storemanmpc
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/metrics"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+var approvalQueueGauge = metrics.NewGauge("storeman/mpc/approval/pending")
+
+// failReasons maps the errors failing the contexts to the reason label of the metrics, so the number of
+// series is bounded. The errors are matched by prefix, as they may be wrapped or received from peers.
+var failReasons = []struct {
+	err    error
+	reason string
+}{
+	{mpcprotocol.ErrTimeOut, "timeout"},
+	{mpcprotocol.ErrQuit, "quit"},
+	{mpcprotocol.ErrTooLessStoreman, "peers"},
+	{mpcprotocol.ErrTooLessDataCollected, "peers"},
+	{mpcprotocol.ErrNotMpcParticipant, "peers"},
+	{mpcprotocol.ErrPeerBanned, "peers"},
+	{mpcprotocol.ErrFailedDataVerify, "approval"},
+	{mpcprotocol.ErrFailedAddApproving, "approval"},
+	{mpcprotocol.ErrWaitApproved, "approval"},
+	{mpcprotocol.ErrGetApproved, "approval"},
+	{mpcprotocol.ErrApprovedNotConsistent, "approval"},
+	{mpcprotocol.ErrApprovalExpired, "approval"},
+	{mpcprotocol.ErrApprovalInUse, "approval"},
+	{mpcprotocol.ErrDataRejected, "rejected"},
+	{mpcprotocol.ErrPolicyRejected, "rejected"},
+	{mpcprotocol.ErrExternalRejected, "rejected"},
+	{mpcprotocol.ErrExternalValidator, "external"},
+	{mpcprotocol.ErrPayloadMismatch, "payload"},
+	{mpcprotocol.ErrLimitExceeded, "limit"},
+	{mpcprotocol.ErrUnknownValue, "limit"},
+	{mpcprotocol.ErrInvalidMPCR, "verify"},
+	{mpcprotocol.ErrInvalidMPCS, "verify"},
+	{mpcprotocol.ErrVerifyFailed, "verify"},
+	{mpcprotocol.ErrInvalidDealerSet, "protocol"},
+	{mpcprotocol.ErrInconsistentGPK, "protocol"},
+	{mpcprotocol.ErrInvalidMsgSignature, "protocol"},
+	{mpcprotocol.ErrInvalidProtocol, "protocol"},
+}
+
+// failReason returns the reason label of the error failing a context.
+func failReason(err error) string {
+	if err == nil {
+		return "unknown"
+	}
+
+	msg := err.Error()
+	for _, item := range failReasons {
+		if strings.HasPrefix(msg, item.err.Error()) {
+			return item.reason
+		}
+	}
+	return "other"
+}
+
+type stepKey struct {
+	contextID uint64
+	step      int
+}
+
+// mpcMetrics updates the metrics from the lifecycle events of the mpc contexts.
+type mpcMetrics struct {
+	mu        sync.Mutex
+	stepBegin map[stepKey]time.Time
+	approving int64
+}
+
+func newMpcMetrics() *mpcMetrics {
+	return &mpcMetrics{stepBegin: make(map[stepKey]time.Time)}
+}
+
+func (m *mpcMetrics) update(ev MpcEvent) {
+	if !metrics.Enabled {
+		return
+	}
+
+	switch ev.Type {
+	case MpcContextCreated:
+		metrics.NewCounter(metrics.LabeledName("storeman/mpc/contexts/started", "protocol", ev.Protocol)).Inc(1)
+	case MpcContextFinished:
+		metrics.NewCounter(metrics.LabeledName("storeman/mpc/contexts/succeeded", "protocol", ev.Protocol)).Inc(1)
+		m.clear(ev.ContextID)
+	case MpcContextFailed:
+		metrics.NewCounter(metrics.LabeledName("storeman/mpc/contexts/failed", "protocol", ev.Protocol, "reason", failReason(ev.Err))).Inc(1)
+		m.clear(ev.ContextID)
+	case MpcStepStarted:
+		m.mu.Lock()
+		m.stepBegin[stepKey{ev.ContextID, ev.Step}] = ev.Time
+		m.mu.Unlock()
+	case MpcStepFinished:
+		key := stepKey{ev.ContextID, ev.Step}
+		m.mu.Lock()
+		begin, exist := m.stepBegin[key]
+		delete(m.stepBegin, key)
+		m.mu.Unlock()
+		if exist {
+			metrics.NewTimer(metrics.LabeledName("storeman/mpc/step/duration",
+				"protocol", ev.Protocol,
+				"step", strconv.Itoa(ev.Step))).Update(ev.Time.Sub(begin))
+		}
+	case MpcStepTimeout:
+		metrics.NewCounter(metrics.LabeledName("storeman/mpc/step/timeouts",
+			"protocol", ev.Protocol,
+			"step", strconv.Itoa(ev.Step))).Inc(1)
+	case MpcApprovalWaiting:
+		approvalQueueGauge.Update(atomic.AddInt64(&m.approving, 1))
+	case MpcApprovalDone, MpcApprovalFailed:
+		approvalQueueGauge.Update(atomic.AddInt64(&m.approving, -1))
+	}
+}
+
+func (m *mpcMetrics) clear(contextID uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.stepBegin {
+		if key.contextID == contextID {
+			delete(m.stepBegin, key)
+		}
+	}
+}
+
+func (m *mpcMetrics) peerTimeout(peerID *discover.NodeID) {
+	if !metrics.Enabled {
+		return
+	}
+
+	metrics.NewCounter(metrics.LabeledName("storeman/mpc/peer/timeouts", "peer", peerID.TerminalString())).Inc(1)
+}
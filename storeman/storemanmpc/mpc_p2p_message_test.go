@@ -10,7 +10,7 @@ func testCreatep2pMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ..
 	result := createMpcBaseMpcResult()
 	result.InitializeValue(preSetValue...)
 	mpc := createMpcContext(mpcID, peers, result)
-	requestMpc := step.CreateRequestMpcStep(&mpc.peers, mpcprotocol.MpcGPKLeader)
+	requestMpc := step.CreateRequestMpcStep(&mpc.peers, mpcprotocol.MpcGPKProtocol, nil)
 	mpcReady := step.CreateMpcReadyStep(&mpc.peers)
 	return generateCreateTestMpc(mpc, requestMpc, mpcReady)
 }
@@ -32,7 +32,7 @@ func acknowledgeCreatep2pMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetV
 	result := createMpcBaseMpcResult()
 	result.InitializeValue(preSetValue...)
 	mpc := createMpcContext(mpcID, peers, result)
-	AcknowledgeMpc := step.CreateAckMpcStep(&mpc.peers, mpcprotocol.MpcGPKPeer)
+	AcknowledgeMpc := step.CreateAckMpcStep(&mpc.peers, mpcprotocol.MpcGPKProtocol)
 	mpcReady := step.CreateGetMpcReadyStep(&mpc.peers)
 	return generateCreateTestMpc(mpc, AcknowledgeMpc, mpcReady)
 }
@@ -43,7 +43,7 @@ func generateCreateTestMpc(mpc *MpcContext, firstStep MpcStepFunc, readyStep Mpc
 	mpcTest[0] = firstStep
 	mpcTest[1] = readyStep
 	for i := 0; i < test; i++ {
-		mpcTest[i+2] = step.CreateMpcSKShareStep(mpcprotocol.MPCDegree, &mpc.peers)
+		mpcTest[i+2] = step.CreateMpcSKShareStep(len(mpc.peers)/2, &mpc.peers)
 	}
 
 	mpc.setMpcStep(mpcTest...)
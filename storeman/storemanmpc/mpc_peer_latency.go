@@ -0,0 +1,140 @@
+package storemanmpc
+
+import (
+	"bytes"
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxPeerFailures   = 3 // peers failing to respond more times are not healthy
+	DefaultExtraPeers = 1 // peers selected besides the threshold to tolerate failures
+)
+
+type peerLatency struct {
+	rtt      time.Duration
+	failures int
+}
+
+// MpcPeerLatency tracks the round trip time of peers from keepalives, and the peers timed out in past contexts.
+type MpcPeerLatency struct {
+	mu    sync.RWMutex
+	peers map[discover.NodeID]*peerLatency
+}
+
+func NewMpcPeerLatency() *MpcPeerLatency {
+	return &MpcPeerLatency{peers: make(map[discover.NodeID]*peerLatency)}
+}
+
+// ReportRTT records a round trip time of the peer, the rtt is smoothed as tcp does.
+func (latency *MpcPeerLatency) ReportRTT(peerID *discover.NodeID, rtt time.Duration) {
+	latency.mu.Lock()
+	defer latency.mu.Unlock()
+
+	item, exist := latency.peers[*peerID]
+	if !exist {
+		latency.peers[*peerID] = &peerLatency{rtt: rtt}
+		return
+	}
+
+	item.rtt = (item.rtt*7 + rtt) / 8
+	item.failures = 0
+}
+
+// ReportTimeout records that the peer did not respond in time.
+func (latency *MpcPeerLatency) ReportTimeout(peerID *discover.NodeID) {
+	latency.mu.Lock()
+	defer latency.mu.Unlock()
+
+	item, exist := latency.peers[*peerID]
+	if !exist {
+		item = &peerLatency{rtt: mpcprotocol.MPCTimeOut}
+		latency.peers[*peerID] = item
+	}
+
+	item.failures++
+}
+
+// RTT returns the smoothed round trip time of the peer, false if it is unknown.
+func (latency *MpcPeerLatency) RTT(peerID *discover.NodeID) (time.Duration, bool) {
+	latency.mu.RLock()
+	defer latency.mu.RUnlock()
+
+	item, exist := latency.peers[*peerID]
+	if !exist {
+		return 0, false
+	}
+
+	return item.rtt, true
+}
+
+// selectPeers selects count peers to take part in the mpc context, self is always selected.
+// The selection is deterministic: peers are ordered by health, then by rtt, peers with unknown rtt
+// come after the measured ones, and the node id breaks the ties.
+func (latency *MpcPeerLatency) selectPeers(peers []mpcprotocol.PeerInfo,
+	self *discover.NodeID,
+	count int,
+	isActive func(*discover.NodeID) bool) []mpcprotocol.PeerInfo {
+
+	if count >= len(peers) {
+		return peers
+	}
+
+	type candidate struct {
+		peer     mpcprotocol.PeerInfo
+		self     bool
+		active   bool
+		healthy  bool
+		measured bool
+		rtt      time.Duration
+	}
+
+	latency.mu.RLock()
+	candidates := make([]candidate, len(peers))
+	for i, peer := range peers {
+		candidates[i].peer = peer
+		candidates[i].self = peer.PeerID == *self
+		candidates[i].active = candidates[i].self || isActive(&peer.PeerID)
+		candidates[i].healthy = true
+		if item, exist := latency.peers[peer.PeerID]; exist {
+			candidates[i].healthy = item.failures < maxPeerFailures
+			candidates[i].measured = true
+			candidates[i].rtt = item.rtt
+		}
+	}
+	latency.mu.RUnlock()
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := &candidates[i], &candidates[j]
+		switch {
+		case a.self != b.self:
+			return a.self
+		case a.active != b.active:
+			return a.active
+		case a.healthy != b.healthy:
+			return a.healthy
+		case a.measured != b.measured:
+			return a.measured
+		case a.rtt != b.rtt:
+			return a.rtt < b.rtt
+		}
+
+		return bytes.Compare(a.peer.PeerID[:], b.peer.PeerID[:]) < 0
+	})
+
+	selected := make([]mpcprotocol.PeerInfo, count)
+	for i := 0; i < count; i++ {
+		selected[i] = candidates[i].peer
+		log.SyslogInfo("selectPeers",
+			"peer", candidates[i].peer.PeerID.String(),
+			"active", candidates[i].active,
+			"healthy", candidates[i].healthy,
+			"rtt", candidates[i].rtt)
+	}
+
+	return selected
+}
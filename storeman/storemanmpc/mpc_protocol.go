@@ -0,0 +1,197 @@
+package storemanmpc
+
+import (
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"sort"
+	"sync"
+)
+
+// approval requirement of mpc protocol
+const (
+	ApprovalNone     = iota // the data is not validated
+	ApprovalOptional        // the data is validated, and approved by operators if the leader asks for it
+	ApprovalRequired        // the data is validated and always approved by operators
+)
+
+// MpcPipeline builds the steps of an mpc context.
+type MpcPipeline func(protocol *MpcProtocol, mpc *MpcContext, preSetValue ...MpcValue) ([]MpcStepFunc, error)
+
+// MpcPrepare returns the peers allowed to take part in the protocol and the extra preset values,
+// it is called by the leader before requesting mpc and by the peers before acknowledging it.
+type MpcPrepare func(mpcServer *MpcDistributor, preSetValue ...MpcValue) ([]mpcprotocol.PeerInfo, []MpcValue, error)
+
+// MpcProtocol declares an mpc protocol, the distributor negotiates it by name in RequestMPC.
+type MpcProtocol struct {
+	Name        string
+	Leader      MpcPipeline
+	Peer        MpcPipeline
+	Prepare     MpcPrepare
+	RequestKeys []string // byte preset values needed by the protocol, sent from leader to peers in RequestMPC
+	Approval    int
+	SelectPeers bool     // the leader selects threshold+k healthy peers instead of requesting all of them
+	Steps       []string // names of the pipeline steps in order, the step timeout overrides are keyed by them
+	ShareStep   string   // name of the step sending the signature share, the data is spent once the share is sent
+}
+
+// hasStep returns true if the pipeline of the protocol has the step.
+func (protocol *MpcProtocol) hasStep(name string) bool {
+	for _, item := range protocol.Steps {
+		if item == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (protocol *MpcProtocol) pipeline(leader bool) MpcPipeline {
+	if leader {
+		return protocol.Leader
+	}
+
+	return protocol.Peer
+}
+
+// needApproval returns true if the data must be approved by operators before signing.
+func (protocol *MpcProtocol) needApproval(byApprove int64) bool {
+	return protocol.Approval == ApprovalRequired || (protocol.Approval == ApprovalOptional && byApprove != 0)
+}
+
+// requestValues decodes the preset values carried by the RequestMPC message.
+func (protocol *MpcProtocol) requestValues(bytesData [][]byte) ([]MpcValue, error) {
+	if len(bytesData) != len(protocol.RequestKeys) {
+		log.SyslogErr("MpcProtocol.requestValues fail",
+			"protocol", protocol.Name,
+			"need", len(protocol.RequestKeys),
+			"got", len(bytesData))
+		return nil, mpcprotocol.ErrInvalidProtocol
+	}
+
+	values := make([]MpcValue, len(bytesData))
+	for i, key := range protocol.RequestKeys {
+		values[i] = MpcValue{key, nil, bytesData[i]}
+	}
+
+	return values, nil
+}
+
+type MpcProtocolRegistry struct {
+	mu        sync.RWMutex
+	protocols map[string]*MpcProtocol
+	timeouts  map[string]mpcprotocol.ProtocolTimeout
+}
+
+func NewMpcProtocolRegistry() *MpcProtocolRegistry {
+	return &MpcProtocolRegistry{
+		protocols: make(map[string]*MpcProtocol),
+		timeouts:  make(map[string]mpcprotocol.ProtocolTimeout)}
+}
+
+func (registry *MpcProtocolRegistry) Register(protocol *MpcProtocol) error {
+	if protocol.Name == "" || protocol.Leader == nil || protocol.Peer == nil || protocol.Prepare == nil {
+		log.SyslogErr("MpcProtocolRegistry.Register fail", "protocol", protocol.Name,
+			"err", mpcprotocol.ErrInvalidProtocol.Error())
+		return mpcprotocol.ErrInvalidProtocol
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, exist := registry.protocols[protocol.Name]; exist {
+		log.SyslogErr("MpcProtocolRegistry.Register fail", "protocol", protocol.Name,
+			"err", mpcprotocol.ErrProtocolExist.Error())
+		return mpcprotocol.ErrProtocolExist
+	}
+
+	registry.protocols[protocol.Name] = protocol
+	return nil
+}
+
+func (registry *MpcProtocolRegistry) Get(name string) (*MpcProtocol, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	protocol, exist := registry.protocols[name]
+	if !exist {
+		return nil, mpcprotocol.ErrProtocolNotExist
+	}
+
+	return protocol, nil
+}
+
+// SetTimeout replaces the timeout config of the protocol.
+func (registry *MpcProtocolRegistry) SetTimeout(name string, cfg mpcprotocol.ProtocolTimeout) error {
+	err := cfg.Validate()
+	if err != nil {
+		log.SyslogErr("MpcProtocolRegistry.SetTimeout fail", "protocol", name, "err", err.Error())
+		return err
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	protocol, exist := registry.protocols[name]
+	if !exist {
+		return mpcprotocol.ErrProtocolNotExist
+	}
+
+	for _, item := range cfg.Steps {
+		if !protocol.hasStep(item.Step) {
+			log.SyslogErr("MpcProtocolRegistry.SetTimeout fail, unknown step", "protocol", name, "step", item.Step)
+			return mpcprotocol.ErrInvalidTimeout
+		}
+	}
+
+	cfg.Steps = append([]mpcprotocol.StepTimeout(nil), cfg.Steps...)
+	registry.timeouts[name] = cfg
+	log.SyslogInfo("MpcProtocolRegistry.SetTimeout", "protocol", name, "timeout", cfg.Timeout, "steps", len(cfg.Steps))
+	return nil
+}
+
+// Timeout returns the timeout config of the protocol, the zero config means the defaults.
+func (registry *MpcProtocolRegistry) Timeout(name string) *mpcprotocol.ProtocolTimeout {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	cfg := registry.timeouts[name]
+	return &cfg
+}
+
+// Timeouts returns the timeout configs of all the registered protocols.
+func (registry *MpcProtocolRegistry) Timeouts() map[string]mpcprotocol.ProtocolTimeout {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	timeouts := make(map[string]mpcprotocol.ProtocolTimeout, len(registry.protocols))
+	for name := range registry.protocols {
+		timeouts[name] = registry.timeouts[name]
+	}
+
+	return timeouts
+}
+
+// Names returns the names of the registered protocols in order.
+func (registry *MpcProtocolRegistry) Names() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.protocols))
+	for name := range registry.protocols {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func containsPeer(peers []mpcprotocol.PeerInfo, peerID *discover.NodeID) bool {
+	for _, item := range peers {
+		if item.PeerID == *peerID {
+			return true
+		}
+	}
+
+	return false
+}
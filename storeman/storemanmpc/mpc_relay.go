@@ -0,0 +1,184 @@
+package storemanmpc
+
+import (
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	MaxQueuedRelays    = 256                    // relay messages waiting for a storeman to relay them
+	RelayRetryInterval = 500 * time.Millisecond // interval to retry sending the queued relay messages
+)
+
+// queuedRelay is a relay message waiting until a storeman can relay it, or its deadline.
+type queuedRelay struct {
+	relay    *mpcprotocol.RelayMessage
+	sender   *discover.NodeID
+	deadline time.Time
+}
+
+// sendToPeer sends the message to the peer directly, or through another storeman if the peer
+// is not connected.
+func (mpcServer *MpcDistributor) sendToPeer(peerID *discover.NodeID, code uint64, msg interface{}) error {
+	if mpcServer.P2pMessager.IsActivePeer(peerID) {
+		return mpcServer.P2pMessager.SendToPeer(peerID, code, msg)
+	}
+
+	mpcMessage, ok := msg.(*mpcprotocol.MpcMessage)
+	if !ok {
+		log.SyslogErr("peer not find", "peer", peerID.String())
+		return nil
+	}
+
+	relay, err := mpcprotocol.NewRelayMessage(mpcServer.Self.ID, *peerID, code, mpcMessage)
+	if err != nil {
+		log.SyslogErr("MpcDistributor, create relay message fail", "peer", peerID.String(), "err", err.Error())
+		return err
+	}
+
+	return mpcServer.forwardRelay(relay, nil)
+}
+
+// forwardRelay sends the relay message, if no storeman can relay it now, the message is queued and
+// retried until the default step timeout, after which the step waiting for it has given up.
+func (mpcServer *MpcDistributor) forwardRelay(relay *mpcprotocol.RelayMessage, sender *discover.NodeID) error {
+	err := mpcServer.sendRelay(relay, sender)
+	if err != mpcprotocol.ErrNoRelayPeer || relay.TTL == 0 {
+		return err
+	}
+
+	return mpcServer.queueRelay(relay, sender, time.Now().Add(mpcprotocol.MPCTimeOut))
+}
+
+// sendRelay sends the relay message to the target if it is connected, otherwise to the storeman with
+// the lowest rtt which is neither the origin nor the sender.
+func (mpcServer *MpcDistributor) sendRelay(relay *mpcprotocol.RelayMessage, sender *discover.NodeID) error {
+	if mpcServer.P2pMessager.IsActivePeer(&relay.Target) {
+		return mpcServer.P2pMessager.SendToPeer(&relay.Target, mpcprotocol.RelayMPC, relay)
+	}
+
+	if relay.TTL == 0 {
+		log.SyslogWarning("MpcDistributor, relay message dropped, too many hops", "target", relay.Target.String())
+		return mpcprotocol.ErrNoRelayPeer
+	}
+
+	var (
+		next    *discover.NodeID
+		nextRTT time.Duration
+	)
+	for _, member := range mpcServer.Groups.AllMembers() {
+		member := member
+		if member == mpcServer.Self.ID || member == relay.Origin || member == relay.Target ||
+			(sender != nil && member == *sender) || !mpcServer.P2pMessager.IsActivePeer(&member) {
+			continue
+		}
+
+		rtt, exist := mpcServer.Latency.RTT(&member)
+		if !exist {
+			rtt = mpcprotocol.MPCTimeOut
+		}
+
+		if next == nil || rtt < nextRTT {
+			next, nextRTT = &member, rtt
+		}
+	}
+
+	if next == nil {
+		log.SyslogWarning("MpcDistributor, no storeman can relay the message", "target", relay.Target.String())
+		return mpcprotocol.ErrNoRelayPeer
+	}
+
+	log.SyslogInfo("MpcDistributor, relay message", "target", relay.Target.String(), "relay", next.String())
+	forward := *relay
+	forward.TTL--
+	return mpcServer.P2pMessager.SendToPeer(next, mpcprotocol.RelayMPC, &forward)
+}
+
+// queueRelay queues the relay message to retry, at most MaxQueuedRelays messages are queued.
+func (mpcServer *MpcDistributor) queueRelay(relay *mpcprotocol.RelayMessage, sender *discover.NodeID, deadline time.Time) error {
+	mpcServer.relayMu.Lock()
+	defer mpcServer.relayMu.Unlock()
+
+	if len(mpcServer.relayQueue) >= MaxQueuedRelays {
+		log.SyslogErr("MpcDistributor, relay queue is full, drop message", "target", relay.Target.String())
+		return mpcprotocol.ErrNoRelayPeer
+	}
+
+	mpcServer.relayQueue = append(mpcServer.relayQueue, queuedRelay{relay, sender, deadline})
+	if !mpcServer.relayRetrying {
+		mpcServer.relayRetrying = true
+		go mpcServer.retryRelays()
+	}
+
+	return nil
+}
+
+// retryRelays retries the queued relay messages until they are sent or expired, it exits once the queue is empty.
+func (mpcServer *MpcDistributor) retryRelays() {
+	ticker := time.NewTicker(RelayRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// the queue only grows meanwhile, the messages are removed here
+		mpcServer.relayMu.Lock()
+		queue := append([]queuedRelay(nil), mpcServer.relayQueue...)
+		mpcServer.relayMu.Unlock()
+
+		now := time.Now()
+		pending := make([]queuedRelay, 0, len(queue))
+		for _, item := range queue {
+			if mpcServer.sendRelay(item.relay, item.sender) == nil {
+				continue
+			}
+
+			if now.After(item.deadline) {
+				log.SyslogErr("MpcDistributor, relay message dropped, no storeman relayed it in time", "target", item.relay.Target.String())
+				continue
+			}
+
+			pending = append(pending, item)
+		}
+
+		mpcServer.relayMu.Lock()
+		mpcServer.relayQueue = append(pending, mpcServer.relayQueue[len(queue):]...)
+		if len(mpcServer.relayQueue) == 0 {
+			mpcServer.relayRetrying = false
+			mpcServer.relayMu.Unlock()
+			return
+		}
+		mpcServer.relayMu.Unlock()
+	}
+}
+
+// handleRelay opens the relay message sent to this node, or forwards it to the target.
+func (mpcServer *MpcDistributor) handleRelay(PeerID *discover.NodeID, relay *mpcprotocol.RelayMessage) error {
+	if !mpcServer.isStoremanNode(&relay.Origin) || !mpcServer.isStoremanNode(&relay.Target) {
+		log.SyslogErr("MpcDistributor.handleRelay fail", "peer", PeerID.String(), "err", mpcprotocol.ErrNotMpcParticipant.Error())
+		return mpcprotocol.ErrNotMpcParticipant
+	}
+
+	if relay.Target != mpcServer.Self.ID {
+		return mpcServer.forwardRelay(relay, PeerID)
+	}
+
+	switch relay.Code {
+	case mpcprotocol.MPCError, mpcprotocol.RequestMPC, mpcprotocol.MPCMessage:
+	default:
+		log.SyslogErr("MpcDistributor.handleRelay fail, invalid message code", "code", relay.Code)
+		return mpcprotocol.ErrInvalidRelay
+	}
+
+	mpcMessage, err := relay.Open(mpcServer.nodeKey)
+	if err != nil {
+		log.SyslogErr("MpcDistributor.handleRelay, open relay message fail", "origin", relay.Origin.String(), "err", err.Error())
+		mpcServer.ReportPeerMisbehaviour(PeerID, mpcprotocol.ErrInvalidRelay)
+		return err
+	}
+
+	log.SyslogInfo("MpcDistributor, relayed message received", "origin", relay.Origin.String(), "relay", PeerID.String())
+	origin := relay.Origin
+	return mpcServer.dispatchMessage(&origin, relay.Code, mpcMessage)
+}
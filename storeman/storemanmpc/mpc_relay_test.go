@@ -0,0 +1,100 @@
+package storemanmpc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+type relayTestMessager struct {
+	mu     sync.Mutex
+	active map[discover.NodeID]bool
+	sent   chan discover.NodeID
+}
+
+func (msger *relayTestMessager) SendToPeer(peerID *discover.NodeID, code uint64, msg interface{}) error {
+	msger.sent <- *peerID
+	return nil
+}
+
+func (msger *relayTestMessager) IsActivePeer(peerID *discover.NodeID) bool {
+	msger.mu.Lock()
+	defer msger.mu.Unlock()
+	return msger.active[*peerID]
+}
+
+func (msger *relayTestMessager) setActive(peerID discover.NodeID) {
+	msger.mu.Lock()
+	defer msger.mu.Unlock()
+	msger.active[peerID] = true
+}
+
+func TestRelayQueue(t *testing.T) {
+	self, target, relayer := discover.NodeID{1}, discover.NodeID{2}, discover.NodeID{3}
+	msger := &relayTestMessager{active: make(map[discover.NodeID]bool), sent: make(chan discover.NodeID, MaxQueuedRelays+1)}
+	mpcServer := &MpcDistributor{
+		Self:        &discover.Node{ID: self},
+		Groups:      NewMpcGroupRegistry(),
+		Latency:     NewMpcPeerLatency(),
+		P2pMessager: msger}
+
+	group, err := NewMpcGroup("relay", []discover.NodeID{self, target, relayer}, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpcServer.Groups.Register(group)
+
+	// no storeman is connected, the message waits for one
+	relay := &mpcprotocol.RelayMessage{Origin: self, Target: target, Code: mpcprotocol.MPCMessage, TTL: 2}
+	if err := mpcServer.forwardRelay(relay, nil); err != nil {
+		t.Fatalf("relay message is not queued: %v", err)
+	}
+
+	// the queue is bounded
+	for i := 1; i < MaxQueuedRelays; i++ {
+		mpcServer.queueRelay(relay, nil, time.Now().Add(time.Minute))
+	}
+	if err := mpcServer.forwardRelay(relay, nil); err != mpcprotocol.ErrNoRelayPeer {
+		t.Fatalf("relay message queued beyond the bound: %v", err)
+	}
+
+	// the queued messages are relayed once a storeman is connected
+	msger.setActive(relayer)
+	for i := 0; i < MaxQueuedRelays; i++ {
+		select {
+		case peerID := <-msger.sent:
+			if peerID != relayer {
+				t.Fatalf("relay message sent to %v", peerID)
+			}
+		case <-time.After(5 * RelayRetryInterval):
+			t.Fatalf("queued relay message %d is not sent", i)
+		}
+	}
+
+	// the message not relayed before its deadline is dropped
+	lonely := &mpcprotocol.RelayMessage{Origin: self, Target: discover.NodeID{4}, Code: mpcprotocol.MPCMessage, TTL: 0}
+	mpcServer.queueRelay(lonely, &relayer, time.Now())
+	deadline := time.Now().Add(5 * RelayRetryInterval)
+	for {
+		mpcServer.relayMu.Lock()
+		queued, retrying := len(mpcServer.relayQueue), mpcServer.relayRetrying
+		mpcServer.relayMu.Unlock()
+		if queued == 0 && !retrying {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expired relay message is still queued: %d", queued)
+		}
+		time.Sleep(RelayRetryInterval / 5)
+	}
+
+	select {
+	case peerID := <-msger.sent:
+		t.Fatalf("expired relay message sent to %v", peerID)
+	default:
+	}
+}
@@ -2,53 +2,107 @@ package storemanmpc
 
 import (
 	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/storeman/shcnorrmpc"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"github.com/wanchain/schnorr-mpc/storeman/storemanmpc/step"
 )
 
+func signProtocol() *MpcProtocol {
+	return &MpcProtocol{
+		Name:        mpcprotocol.MpcSignProtocol,
+		Leader:      reqSignMpc,
+		Peer:        ackSignMpc,
+		Prepare:     prepareSignMpc,
+		RequestKeys: []string{mpcprotocol.MpcM, mpcprotocol.MpcAddress, mpcprotocol.MpcExt},
+		Approval:    ApprovalOptional,
+		SelectPeers: true,
+		Steps:       []string{"request", "ready", "rskShare", "rskReport", "rskAgree", "r", "s", "ackRS"},
+		ShareStep:   "s"}
+}
+
+// prepareSignMpc loads the mpc account, the peers which created the gpk are used to sign the data.
+func prepareSignMpc(mpcServer *MpcDistributor, preSetValue ...MpcValue) ([]mpcprotocol.PeerInfo, []MpcValue, error) {
+	pkBytes := findMpcValue(mpcprotocol.MpcAddress, preSetValue...)
+	if pkBytes == nil {
+		return nil, nil, mpcprotocol.ErrInvalidMPCAddr
+	}
+
+	address, err := shcnorrmpc.PkToAddress(pkBytes.ByteValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accValues, peers, err := mpcServer.loadStoremanAddress(&address)
+	if err != nil {
+		log.SyslogErr("prepareSignMpc, loadStoremanAddress fail",
+			"address", address.String(),
+			"err", err.Error())
+		return nil, nil, err
+	}
+
+	return peers, accValues, nil
+}
+
 //send create LockAccount from leader
-func reqSignMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcValue) (*MpcContext, error) {
-	result := createMpcBaseMpcResult()
-	result.InitializeValue(preSetValue...)
-	mpc := createMpcContext(mpcID, peers, result)
-	reqMpc := step.CreateRequestMpcStep(&mpc.peers, mpcprotocol.MpcSignLeader)
-	reqMpc.SetWaiting(mpcprotocol.MpcSchnrThr)
+func reqSignMpc(protocol *MpcProtocol, mpc *MpcContext, preSetValue ...MpcValue) ([]MpcStepFunc, error) {
+	threshold, err := groupThreshold(preSetValue...)
+	if err != nil {
+		return nil, err
+	}
+
+	reqMpc := step.CreateRequestMpcStep(&mpc.peers, protocol.Name, protocol.RequestKeys)
+	reqMpc.SetWaiting(threshold)
 
 	mpcReady := step.CreateMpcReadyStep(&mpc.peers)
-	return generateTxSignMpc(mpc, reqMpc, mpcReady)
+	return generateTxSignMpc(mpc, reqMpc, mpcReady, true, threshold, preSetValue...)
 }
 
 //get message from leader and create Context
-func ackSignMpc(mpcID uint64, peers []mpcprotocol.PeerInfo, preSetValue ...MpcValue) (*MpcContext, error) {
-	result := createMpcBaseMpcResult()
-	result.InitializeValue(preSetValue...)
-	mpc := createMpcContext(mpcID, peers, result)
-	ackMpc := step.CreateAckMpcStep(&mpc.peers, mpcprotocol.MpcSignPeer)
+func ackSignMpc(protocol *MpcProtocol, mpc *MpcContext, preSetValue ...MpcValue) ([]MpcStepFunc, error) {
+	threshold, err := groupThreshold(preSetValue...)
+	if err != nil {
+		return nil, err
+	}
+
+	ackMpc := step.CreateAckMpcStep(&mpc.peers, protocol.Name)
 	mpcReady := step.CreateGetMpcReadyStep(&mpc.peers)
-	return generateTxSignMpc(mpc, ackMpc, mpcReady)
+	return generateTxSignMpc(mpc, ackMpc, mpcReady, false, threshold, preSetValue...)
 }
 
-func generateTxSignMpc(mpc *MpcContext, firstStep MpcStepFunc, readyStep MpcStepFunc) (*MpcContext, error) {
+func generateTxSignMpc(mpc *MpcContext,
+	firstStep MpcStepFunc,
+	readyStep MpcStepFunc,
+	leader bool,
+	threshold int,
+	preSetValue ...MpcValue) ([]MpcStepFunc, error) {
+
 	log.SyslogInfo("generateTxSignMpc begin")
 
+	leaderID, err := contextLeader(preSetValue...)
+	if err != nil {
+		return nil, err
+	}
+
 	accTypeStr := ""
-	skShare := step.CreateMpcRSKShareStep(mpcprotocol.MPCDegree, &mpc.peers)
-	// wait time out, in order for all node try best get most response, so each node can get the same poly value.
-	// It is not enough for node to wait only MPCDegree response, the reason is above.
+	skShare := step.CreateMpcRSKShareStep(threshold-1, &mpc.peers)
+	// nodes may receive different rsk shares, the leader agrees on the dealers
+	// and every node recomputes its rsk share from exactly these dealers.
+	reportStep := step.CreateMpcRSKReportStep(&mpc.peers, leaderID, leader, threshold)
+	agreeStep := step.CreateMpcRSKAgreeStep(&mpc.peers, leaderID, leader, threshold)
+
 	RStep := step.CreateMpcRStep(&mpc.peers, accTypeStr)
-	RStep.SetWaiting(mpcprotocol.MpcSchnrThr)
+	RStep.SetWaiting(threshold)
 
 	SStep := step.CreateMpcSStep(&mpc.peers, []string{mpcprotocol.MpcPrivateShare}, []string{mpcprotocol.MpcS})
-	SStep.SetWaiting(mpcprotocol.MpcSchnrThr)
+	SStep.SetWaiting(threshold)
 
 	ackRSStep := step.CreateAckMpcRSStep(&mpc.peers, accTypeStr)
-	ackRSStep.SetWaiting(mpcprotocol.MpcSchnrThr)
+	ackRSStep.SetWaiting(threshold)
 
-	mpc.setMpcStep(firstStep, readyStep, skShare, RStep, SStep, ackRSStep)
-
-	for stepId, stepItem := range mpc.MpcSteps {
+	steps := []MpcStepFunc{firstStep, readyStep, skShare, reportStep, agreeStep, RStep, SStep, ackRSStep}
+	for _, stepItem := range steps {
 		stepItem.SetWaitAll(false)
-		stepItem.SetStepId(stepId)
 	}
-	return mpc, nil
+
+	return steps, nil
 }
@@ -24,4 +24,43 @@ var (
 	ErrMarshal               = errors.New("marshal data failed")
 	ErrApprovedNotConsistent = errors.New("not equal,received  data and approved data in DB")
 	ErrTooLessDataCollected  = errors.New("not enough data collected")
+	ErrInvalidMsgSignature   = errors.New("invalid mpc message signature")
+	ErrNotMpcParticipant     = errors.New("peer is not a participant of the mpc context")
+	ErrInvalidKeystoreGroup  = errors.New("invalid storeman group in keystore")
+	ErrInvalidGroup          = errors.New("invalid storeman group")
+	ErrGroupExist            = errors.New("storeman group is already exist")
+	ErrGroupNotExist         = errors.New("storeman group is not exist")
+	ErrInvalidProtocol       = errors.New("invalid mpc protocol")
+	ErrProtocolExist         = errors.New("mpc protocol is already exist")
+	ErrProtocolNotExist      = errors.New("mpc protocol is not exist")
+	ErrInvalidTimeout        = errors.New("invalid mpc timeout config")
+	ErrInvalidDealerSet      = errors.New("invalid rsk share dealer set")
+	ErrInvalidEndpoint       = errors.New("invalid storeman endpoint record")
+	ErrEndpointExpired       = errors.New("storeman endpoint record is expired")
+	ErrInvalidRelay          = errors.New("invalid relayed mpc message")
+	ErrNoRelayPeer           = errors.New("no storeman can relay the mpc message")
+	ErrMalformedMessage      = errors.New("malformed storeman message")
+	ErrOversizedMessage      = errors.New("oversized storeman message")
+	ErrDuplicateStepMessage  = errors.New("duplicate mpc step message")
+	ErrInconsistentGPK       = errors.New("inconsistent gpk")
+	ErrPeerBanned            = errors.New("storeman peer is banned")
+	ErrInvalidPolicy         = errors.New("invalid approval policy")
+	ErrPolicyRejected        = errors.New("data is rejected by approval policy")
+	ErrDataRejected          = errors.New("data is rejected by operator")
+	ErrNotApproving          = errors.New("data is not waiting for approval")
+	ErrInvalidOperators      = errors.New("invalid approval operators config")
+	ErrNotOperator           = errors.New("signer is not an approval operator")
+	ErrDuplicateApproval     = errors.New("data is already approved by the operator")
+	ErrOperatorSigRequired   = errors.New("data must be approved by operator signatures")
+	ErrApprovalMismatch      = errors.New("approval hash does not match the data waiting for approval")
+	ErrInvalidRetention      = errors.New("invalid approval retention config")
+	ErrApprovalExpired       = errors.New("approval of the data is expired or used")
+	ErrApprovalInUse         = errors.New("approval of the data is in use by another context")
+	ErrInvalidExternal       = errors.New("invalid external validator config")
+	ErrExternalValidator     = errors.New("external validator is unavailable")
+	ErrExternalRejected      = errors.New("data is rejected by external validator")
+	ErrPayloadMismatch       = errors.New("decoded data does not match extern")
+	ErrInvalidLimit          = errors.New("invalid gpk limit config")
+	ErrLimitExceeded         = errors.New("gpk signing limit is exceeded")
+	ErrUnknownValue          = errors.New("value of the data is unknown")
 )
@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/rlp"
+)
+
+// SigHash returns the hash signed by the sender of the message. The message code is
+// part of the hash, so a signed MPCMessage can not be replayed as an MPCError.
+func (msg *MpcMessage) SigHash(code uint64) (common.Hash, error) {
+	enc, err := rlp.EncodeToBytes([]interface{}{
+		code,
+		msg.ContextID,
+		msg.StepID,
+		msg.Peers,
+		msg.Data,
+		msg.BytesData,
+		msg.ErrMsg,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return crypto.Keccak256Hash(enc), nil
+}
+
+// Sign signs the message with the node key of the sender.
+func (msg *MpcMessage) Sign(code uint64, prv *ecdsa.PrivateKey) error {
+	hash, err := msg.SigHash(code)
+	if err != nil {
+		return err
+	}
+
+	sig, err := crypto.Sign(hash[:], prv)
+	if err != nil {
+		return err
+	}
+
+	msg.Signature = sig
+	return nil
+}
+
+// Sender recovers the node id of the node which signed the message.
+func (msg *MpcMessage) Sender(code uint64) (discover.NodeID, error) {
+	if len(msg.Signature) != 65 {
+		return discover.NodeID{}, ErrInvalidMsgSignature
+	}
+
+	hash, err := msg.SigHash(code)
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+
+	pub, err := crypto.SigToPub(hash[:], msg.Signature)
+	if err != nil {
+		return discover.NodeID{}, ErrInvalidMsgSignature
+	}
+
+	return discover.PubkeyID(pub), nil
+}
+
+// Verify checks the message is signed by the node key of peerID.
+func (msg *MpcMessage) Verify(code uint64, peerID *discover.NodeID) error {
+	sender, err := msg.Sender(code)
+	if err != nil {
+		return err
+	}
+
+	if sender != *peerID {
+		return ErrInvalidMsgSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+)
+
+func TestMpcMessageSignature(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	sender := discover.PubkeyID(&prv.PublicKey)
+	otherID := discover.PubkeyID(&other.PublicKey)
+
+	msg := &MpcMessage{ContextID: 100, StepID: 2, Data: []big.Int{*big.NewInt(7)}}
+	if err := msg.Sign(MPCMessage, prv); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := msg.Verify(MPCMessage, &sender); err != nil {
+		t.Errorf("verify signed message fail: %v", err)
+	}
+
+	if err := msg.Verify(MPCMessage, &otherID); err != ErrInvalidMsgSignature {
+		t.Errorf("message verified with a wrong sender, err: %v", err)
+	}
+
+	if err := msg.Verify(MPCError, &sender); err != ErrInvalidMsgSignature {
+		t.Errorf("message verified with a wrong message code, err: %v", err)
+	}
+
+	msg.ContextID = 101
+	if err := msg.Verify(MPCMessage, &sender); err != ErrInvalidMsgSignature {
+		t.Errorf("message verified with a changed context id, err: %v", err)
+	}
+
+	unsigned := &MpcMessage{ContextID: 100, ErrMsg: []byte("quit")}
+	if err := unsigned.Verify(MPCError, &sender); err != ErrInvalidMsgSignature {
+		t.Errorf("unsigned message verified, err: %v", err)
+	}
+}
@@ -7,18 +7,15 @@ import (
 	"time"
 )
 
-var (
-	MpcSchnrThr        = 26 // MpcSchnrThr >= number(storeman )/2 +1
-	MPCDegree          = MpcSchnrThr - 1
-	MpcSchnrNodeNumber = 50 // At least MpcSchnrNodeNumber MPC nodes
+const (
+	DefaultGroupID = "default" // id of the group built from the configured storeman nodes
 )
 
 const (
-	MpcGPKLeader = iota + 0
-	MpcGPKPeer
-	MpcSignLeader
-	MpcSignPeer
+	MpcGPKProtocol  = "gpk"  // create group public key
+	MpcSignProtocol = "sign" // sign data by group private key
 )
+
 const (
 	StatusCode = iota + 10 // used by storeman protocol
 	KeepaliveCode
@@ -30,14 +27,15 @@ const (
 	KeepaliveCycle
 	CheckAllPeerConnected
 	BuildStoremanGroup
-	AllPeersInfo
-	GetPeersInfo
+	EndpointRecords // signed endpoint records of the storeman nodes
+	RelayMPC        // an encrypted mpc message relayed to a storeman which is not connected directly
 	NumberOfMessageCodes
 	//MPCTimeOut = time.Second * 100
 	//MPCTimeOut = time.Second * 10
 	MPCTimeOut = time.Second * 20
 	PName      = "storeman"
-	PVer       = uint64(10)
+	PAdminName = "storemanadmin" // namespace of the private admin api
+	PVer       = uint64(15)
 	PVerStr    = "1.1"
 )
 const (
@@ -52,8 +50,18 @@ const (
 	MpcM             = "MpcM"             // M
 	MpcS             = "MpcS"             // S: s
 
-	MpcExt = "MpcExtern" // extern
-	MpcByApprove = "MpcByApprove" // by approve
+	MpcExt              = "MpcExtern"           // extern
+	MpcByApprove        = "MpcByApprove"        // by approve
+	MpcApprovalDeadline = "MpcApprovalDeadline" // unix time until which the peers wait for the data being approved
+
+	MpcGroupID   = "MpcGroupID"   // id of the storeman group
+	MpcThreshold = "MpcThreshold" // threshold of the storeman group
+	MpcLeader    = "MpcLeader"    // node id of the leader of the context
+
+	RMpcShareDealers  = "RMpcShareDealers"  // seeds of the dealers whose rsk shares are received
+	RMpcShareValues   = "RMpcShareValues"   // rsk shares received, in the order of RMpcShareDealers
+	RMpcAgreedDealers = "RMpcAgreedDealers" // seeds of the dealers agreed by the leader
+	RMpcSitOut        = "RMpcSitOut"        // 1 if the node misses a share of the agreed dealers and sits out the R and S steps
 
 	MpcTxHash  = "MpcTxHash"
 	MpcAddress = "MpcAddress"
@@ -63,7 +71,9 @@ const (
 const (
 	MpcApproving     = "MpcApproving"
 	MpcApproved      = "MpcApproved"
-	MpcApprovingKeys = "MpcApprovingKeys" // key : MpcApprovingKeys, value: array of the key of the data.
+	MpcRejected      = "MpcRejected"
+	MpcApprovals     = "MpcApprovals"     // signatures of the operators who have approved the data
+	MpcApprovingKeys = "MpcApprovingKeys" // legacy key : MpcApprovingKeys, value: array of the key of the data, migrated to the indexes.
 )
 
 type PeerInfo struct {
@@ -98,8 +108,10 @@ type StepMessage struct {
 type MpcMessage struct {
 	ContextID uint64
 	StepID    uint64
-	Peers     []byte
+	Peers     []PeerInfo
 	Data      []big.Int //message data
 	BytesData [][]byte
+	ErrMsg    []byte // error text, only used by MPCError message
+	Signature []byte // signature of the sender's node key
 }
 
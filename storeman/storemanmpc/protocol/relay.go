@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+
+	"github.com/wanchain/schnorr-mpc/crypto/ecies"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	"github.com/wanchain/schnorr-mpc/rlp"
+)
+
+// MaxRelayHops is the number of storemen a relayed message may pass through.
+const MaxRelayHops = 2
+
+// RelayMessage carries an mpc message through the storemen between the origin and the target.
+// The message is encrypted to the node key of the target, so the relays learn nothing of it,
+// and it keeps the signature of the origin, so the relays can not change it.
+type RelayMessage struct {
+	Origin  discover.NodeID
+	Target  discover.NodeID
+	Code    uint64
+	TTL     uint64 // remaining hops
+	Payload []byte // rlp encoded MpcMessage encrypted by ecies
+}
+
+// NewRelayMessage encrypts the signed message to the target.
+func NewRelayMessage(origin, target discover.NodeID, code uint64, msg *MpcMessage) (*RelayMessage, error) {
+	pub, err := target.Pubkey()
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pub), enc, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RelayMessage{Origin: origin, Target: target, Code: code, TTL: MaxRelayHops, Payload: payload}, nil
+}
+
+// Open decrypts the message by the node key of the target, and checks it is signed by the origin.
+func (relay *RelayMessage) Open(prv *ecdsa.PrivateKey) (*MpcMessage, error) {
+	enc, err := ecies.ImportECDSA(prv).Decrypt(rand.Reader, relay.Payload, nil, nil)
+	if err != nil {
+		return nil, ErrInvalidRelay
+	}
+
+	var msg MpcMessage
+	if err = rlp.DecodeBytes(enc, &msg); err != nil {
+		return nil, ErrInvalidRelay
+	}
+
+	if err = msg.Verify(relay.Code, &relay.Origin); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
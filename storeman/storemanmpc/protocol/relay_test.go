@@ -0,0 +1,58 @@
+package protocol
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+)
+
+func TestRelayMessage(t *testing.T) {
+	originKey, _ := crypto.GenerateKey()
+	targetKey, _ := crypto.GenerateKey()
+	relayKey, _ := crypto.GenerateKey()
+	origin := discover.PubkeyID(&originKey.PublicKey)
+	target := discover.PubkeyID(&targetKey.PublicKey)
+
+	share := []byte("secret share of the target")
+	msg := &MpcMessage{ContextID: 9, StepID: 1, Data: []big.Int{*big.NewInt(3)}, BytesData: [][]byte{share}}
+	if err := msg.Sign(MPCMessage, originKey); err != nil {
+		t.Fatal(err)
+	}
+
+	relay, err := NewRelayMessage(origin, target, MPCMessage, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(relay.Payload, share) {
+		t.Fatal("relayed payload is not encrypted")
+	}
+
+	opened, err := relay.Open(targetKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opened.ContextID != msg.ContextID || !bytes.Equal(opened.BytesData[0], share) {
+		t.Errorf("opened message mismatch: %+v", opened)
+	}
+
+	if _, err := relay.Open(relayKey); err != ErrInvalidRelay {
+		t.Errorf("relay opened the message, err: %v", err)
+	}
+
+	// a relay can not pass the message off as sent by another node
+	relay.Origin = discover.PubkeyID(&relayKey.PublicKey)
+	if _, err := relay.Open(targetKey); err != ErrInvalidMsgSignature {
+		t.Errorf("message with a forged origin opened, err: %v", err)
+	}
+
+	relay.Origin = origin
+	relay.Code = MPCError
+	if _, err := relay.Open(targetKey); err != ErrInvalidMsgSignature {
+		t.Errorf("message with a changed code opened, err: %v", err)
+	}
+}
@@ -10,4 +10,12 @@ type StoremanManager interface {
 	SetMessagePeers(*MpcMessage, *[]PeerInfo)
 	SelfNodeId() *discover.NodeID
 	CreateKeystore(MpcResultInterface, *[]PeerInfo, string) error
+	ReportPeerTimeout(*discover.NodeID)
+	ReportPeerMisbehaviour(*discover.NodeID, error)
+}
+
+// PeerMisbehaviour is a misbehaviour of a peer found by an mpc step, err tells what the peer did.
+type PeerMisbehaviour struct {
+	PeerID discover.NodeID
+	Err    error
 }
@@ -0,0 +1,70 @@
+package protocol
+
+import "time"
+
+// StepTimeout overrides the waiting behaviour of one step of an mpc protocol.
+type StepTimeout struct {
+	Step              string        // name of the step in the protocol pipeline
+	Timeout           time.Duration // zero: use the timeout of the protocol
+	MinResponses      int           // zero: use the default of the step
+	ContinueOnPartial *bool         // nil: use the default of the step
+}
+
+// ProtocolTimeout configures the waiting behaviour of an mpc protocol.
+type ProtocolTimeout struct {
+	Timeout         time.Duration // timeout of every step, zero: MPCTimeOut
+	ApprovalTimeout time.Duration // time to wait for the data being approved, zero: MPCTimeOut
+	Steps           []StepTimeout
+}
+
+func (cfg *ProtocolTimeout) Validate() error {
+	if cfg.Timeout < 0 || cfg.ApprovalTimeout < 0 {
+		return ErrInvalidTimeout
+	}
+
+	found := make(map[string]bool)
+	for _, item := range cfg.Steps {
+		if item.Step == "" || item.Timeout < 0 || item.MinResponses < 0 || found[item.Step] {
+			return ErrInvalidTimeout
+		}
+
+		found[item.Step] = true
+	}
+
+	return nil
+}
+
+// StepTimeout returns the timeout of the step.
+func (cfg *ProtocolTimeout) StepTimeout(step string) time.Duration {
+	for _, item := range cfg.Steps {
+		if item.Step == step && item.Timeout > 0 {
+			return item.Timeout
+		}
+	}
+
+	if cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
+
+	return MPCTimeOut
+}
+
+// StepOverride returns the override of the step, nil if the step keeps its default.
+func (cfg *ProtocolTimeout) StepOverride(step string) *StepTimeout {
+	for i := range cfg.Steps {
+		if cfg.Steps[i].Step == step {
+			return &cfg.Steps[i]
+		}
+	}
+
+	return nil
+}
+
+// WaitApprovedTimeout returns the time to wait for the data being approved.
+func (cfg *ProtocolTimeout) WaitApprovedTimeout() time.Duration {
+	if cfg.ApprovalTimeout > 0 {
+		return cfg.ApprovalTimeout
+	}
+
+	return MPCTimeOut
+}
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProtocolTimeout(t *testing.T) {
+	partial := true
+	cfg := ProtocolTimeout{
+		Timeout: 5 * time.Second,
+		Steps: []StepTimeout{
+			{Step: "r", Timeout: time.Minute, MinResponses: 3},
+			{Step: "s", ContinueOnPartial: &partial},
+		},
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate fail: %v", err)
+	}
+
+	if timeout := cfg.StepTimeout("r"); timeout != time.Minute {
+		t.Errorf("step r timeout is %v, want %v", timeout, time.Minute)
+	}
+
+	if timeout := cfg.StepTimeout("s"); timeout != 5*time.Second {
+		t.Errorf("step s timeout is %v, want %v", timeout, 5*time.Second)
+	}
+
+	if override := cfg.StepOverride("s"); override == nil || !*override.ContinueOnPartial {
+		t.Error("step s should continue on partial responses")
+	}
+
+	if override := cfg.StepOverride("request"); override != nil {
+		t.Error("step request should keep its default")
+	}
+
+	var empty ProtocolTimeout
+	if empty.StepTimeout("request") != MPCTimeOut || empty.WaitApprovedTimeout() != MPCTimeOut {
+		t.Error("empty config should use the default timeout")
+	}
+
+	invalid := []ProtocolTimeout{
+		{Timeout: -time.Second},
+		{ApprovalTimeout: -time.Second},
+		{Steps: []StepTimeout{{Step: ""}}},
+		{Steps: []StepTimeout{{Step: "r", MinResponses: -1}}},
+		{Steps: []StepTimeout{{Step: "r"}, {Step: "r"}}},
+	}
+
+	for i, item := range invalid {
+		if err := item.Validate(); err != ErrInvalidTimeout {
+			t.Errorf("invalid config %d validated, err: %v", i, err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package step
 import (
 	"bytes"
 	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
 	"github.com/wanchain/schnorr-mpc/log"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
@@ -31,7 +32,10 @@ func (ack *AckMpcGPKStep) InitStep(result mpcprotocol.MpcResultInterface) error
 		return err
 	}
 
-	// Check valid of PK ?
+	if crypto.ToECDSAPub(mpcGpk) == nil {
+		log.SyslogErr("AckMpcGPKStep::InitStep", "ack mpc account step, init fail. invalid gpk", common.ToHex(mpcGpk))
+		return mpcprotocol.ErrInvalidMPCAddr
+	}
 
 	ack.mpcGPK = mpcGpk
 	return nil
@@ -59,6 +63,8 @@ func (ack *AckMpcGPKStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc
 		return mpcprotocol.ErrInvalidMPCAddr
 	}
 
+	gpks := make(map[discover.NodeID]string, len(ack.remoteMpcGPKs))
+	consistent := true
 	for peerID, mpcGpk := range ack.remoteMpcGPKs {
 		if mpcGpk == nil {
 			log.SyslogErr("AckMpcGPKStep:FinishStep","ack mpc account step, finish, invalid remote mpc address: nil. peerID",
@@ -71,11 +77,22 @@ func (ack *AckMpcGPKStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc
 				common.ToHex(ack.mpcGPK),
 				common.ToHex(mpcGpk),
 				peerID.String())
-			return mpcprotocol.ErrInvalidMPCAddr
+			consistent = false
 		}
+		gpks[peerID] = string(mpcGpk)
 	}
 
-	return nil
+	if consistent {
+		return nil
+	}
+
+	// the local gpk may be the wrong one, only the peers disagreeing with the majority are blamed
+	for _, peerID := range majorityOutliers(gpks) {
+		peerID := peerID
+		ack.reportMisbehaviour(&peerID, mpcprotocol.ErrInconsistentGPK)
+	}
+
+	return mpcprotocol.ErrInvalidMPCAddr
 }
 
 func (ack *AckMpcGPKStep) HandleMessage(msg *mpcprotocol.StepMessage) bool {
@@ -2,12 +2,15 @@ package step
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"errors"
 	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"math/big"
 	"testing"
+	"time"
 )
 
 var peers []mpcprotocol.PeerInfo
@@ -21,10 +24,16 @@ var msgWrong2 mpcprotocol.StepMessage
 type tmpMpcResult struct {
 }
 
-var mpcAddr = "0x0000000000000000000000000000000000000055"
-var mpcAddrBytes = common.FromHex(mpcAddr)
-var wrongMpcAddrBytes1 = common.FromHex("0x0000000000000000000000000000000000000044")
-var wrongMpcAddrBytes2 = common.FromHex("0x00000000000000000000000000000000000044")
+var mpcAddrBytes = gpkBytes(0x55)
+var wrongMpcAddrBytes1 = gpkBytes(0x44)
+var wrongMpcAddrBytes2 = common.FromHex("0x00000000000000000055")
+
+func gpkBytes(sk int64) []byte {
+	var gpk ecdsa.PublicKey
+	gpk.Curve = crypto.S256()
+	gpk.X, gpk.Y = crypto.S256().ScalarBaseMult(big.NewInt(sk).Bytes())
+	return crypto.FromECDSAPub(&gpk)
+}
 
 func (ret *tmpMpcResult) Initialize() error {
 	return nil
@@ -83,27 +92,32 @@ func Init() {
 	nodeId2, _ := discover.HexID("0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002")
 	nodeId3, _ := discover.HexID("0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000003")
 
-	peers = append(peers, mpcprotocol.PeerInfo{nodeId1, 1})
-	peers = append(peers, mpcprotocol.PeerInfo{nodeId2, 2})
-	peers = append(peers, mpcprotocol.PeerInfo{nodeId3, 3})
+	peers = append(peers, mpcprotocol.PeerInfo{PeerID: nodeId1, Seed: 1})
+	peers = append(peers, mpcprotocol.PeerInfo{PeerID: nodeId2, Seed: 2})
+	peers = append(peers, mpcprotocol.PeerInfo{PeerID: nodeId3, Seed: 3})
 
-	bytesData := make([][]byte, 1, 1)
-	bytesData[0] = mpcAddrBytes
-	msg1 = mpcprotocol.StepMessage{mpcprotocol.MPCMessage, &peers[0].PeerID, &peers, nil, bytesData}
-	msg2 = mpcprotocol.StepMessage{mpcprotocol.MPCMessage, &peers[1].PeerID, &peers, nil, bytesData}
-	msg3 = mpcprotocol.StepMessage{mpcprotocol.MPCMessage, &peers[2].PeerID, &peers, nil, bytesData}
+	bytesData := [][]byte{mpcAddrBytes}
+	msg1 = mpcprotocol.StepMessage{MsgCode: mpcprotocol.MPCMessage, PeerID: &peers[0].PeerID, Peers: &peers, BytesData: bytesData}
+	msg2 = mpcprotocol.StepMessage{MsgCode: mpcprotocol.MPCMessage, PeerID: &peers[1].PeerID, Peers: &peers, BytesData: bytesData}
+	msg3 = mpcprotocol.StepMessage{MsgCode: mpcprotocol.MPCMessage, PeerID: &peers[2].PeerID, Peers: &peers, BytesData: bytesData}
 
-	bytesData[0] = wrongMpcAddrBytes1
-	msgWrong1 = mpcprotocol.StepMessage{mpcprotocol.MPCMessage, &peers[0].PeerID, &peers, nil, bytesData}
+	msgWrong1 = mpcprotocol.StepMessage{MsgCode: mpcprotocol.MPCMessage, PeerID: &peers[2].PeerID, Peers: &peers, BytesData: [][]byte{wrongMpcAddrBytes1}}
+	msgWrong2 = mpcprotocol.StepMessage{MsgCode: mpcprotocol.MPCMessage, PeerID: &peers[2].PeerID, Peers: &peers, BytesData: [][]byte{wrongMpcAddrBytes2}}
+}
 
-	bytesData[0] = wrongMpcAddrBytes2
-	msgWrong2 = mpcprotocol.StepMessage{mpcprotocol.MPCMessage, &peers[0].PeerID, &peers, nil, bytesData}
+// sendMessages feeds the messages to the message loop of the step, as the mpc context does.
+func sendMessages(step *AckMpcGPKStep, msgs ...*mpcprotocol.StepMessage) {
+	step.SetTimeout(time.Second)
+	step.InitMessageLoop(step)
+	for _, msg := range msgs {
+		step.GetMessageChan() <- msg
+	}
 }
 
 func TestInitStep(t *testing.T) {
 
 	Init()
-	step := CreateAckMpcAccountStep(&peers)
+	step := CreateAckMpcGPKStep(&peers)
 
 	err := step.InitStep(&mpcResultWrong1)
 	if err == nil {
@@ -120,14 +134,14 @@ func TestInitStep(t *testing.T) {
 		t.Error("InitStep should succeed")
 	}
 
-	if !bytes.Equal(step.mpcAddr, mpcAddrBytes) {
+	if !bytes.Equal(step.mpcGPK, mpcAddrBytes) {
 		t.Error("invalid step's mpcAddr")
 	}
 }
 
 func TestHandleMessage(t *testing.T) {
 	Init()
-	step := CreateAckMpcAccountStep(&peers)
+	step := CreateAckMpcGPKStep(&peers)
 	step.InitStep(&mpcResult)
 
 	bSuc := step.HandleMessage(&msg1)
@@ -150,12 +164,10 @@ func TestFinishStep(t *testing.T) {
 	Init()
 
 	{
-		step := CreateAckMpcAccountStep(&peers)
+		step := CreateAckMpcGPKStep(&peers)
 		step.InitStep(&mpcResult)
 
-		step.HandleMessage(&msg1)
-		step.HandleMessage(&msg2)
-		step.HandleMessage(&msg3)
+		sendMessages(step, &msg1, &msg2, &msg3)
 
 		err := step.FinishStep(&mpcResult, nil)
 		if err != nil {
@@ -164,12 +176,10 @@ func TestFinishStep(t *testing.T) {
 	}
 
 	{
-		step := CreateAckMpcAccountStep(&peers)
+		step := CreateAckMpcGPKStep(&peers)
 		step.InitStep(&mpcResult)
 
-		step.HandleMessage(&msg1)
-		step.HandleMessage(&msg2)
-		step.HandleMessage(&msgWrong1)
+		sendMessages(step, &msg1, &msg2, &msgWrong1)
 
 		err := step.FinishStep(&mpcResult, nil)
 		if err == nil {
@@ -178,12 +188,10 @@ func TestFinishStep(t *testing.T) {
 	}
 
 	{
-		step := CreateAckMpcAccountStep(&peers)
+		step := CreateAckMpcGPKStep(&peers)
 		step.InitStep(&mpcResult)
 
-		step.HandleMessage(&msg1)
-		step.HandleMessage(&msg2)
-		step.HandleMessage(&msgWrong2)
+		sendMessages(step, &msg1, &msg2, &msgWrong2)
 
 		err := step.FinishStep(&mpcResult, nil)
 		if err == nil {
@@ -191,3 +199,45 @@ func TestFinishStep(t *testing.T) {
 		}
 	}
 }
+
+func TestFinishStepBlame(t *testing.T) {
+	Init()
+
+	// the peer disagreeing with the majority is blamed
+	step := CreateAckMpcGPKStep(&peers)
+	step.InitStep(&mpcResult)
+	sendMessages(step, &msg1, &msg2, &msgWrong1)
+	if err := step.FinishStep(&mpcResult, nil); err != mpcprotocol.ErrInvalidMPCAddr {
+		t.Fatalf("step FinishStep should fail: %v", err)
+	}
+
+	blamed := step.Misbehaviours()
+	if len(blamed) != 1 || blamed[0].PeerID != peers[2].PeerID || blamed[0].Err != mpcprotocol.ErrInconsistentGPK {
+		t.Fatalf("invalid misbehaviours: %v", blamed)
+	}
+
+	// the local gpk differs from the majority, the agreeing peers are not blamed
+	wrong1 := msg1
+	wrong1.BytesData = [][]byte{wrongMpcAddrBytes1}
+	wrong2 := msg2
+	wrong2.BytesData = [][]byte{wrongMpcAddrBytes1}
+	step = CreateAckMpcGPKStep(&peers)
+	step.InitStep(&mpcResult)
+	sendMessages(step, &wrong1, &wrong2, &msgWrong1)
+	if err := step.FinishStep(&mpcResult, nil); err != mpcprotocol.ErrInvalidMPCAddr {
+		t.Fatalf("step FinishStep should fail: %v", err)
+	}
+	if blamed := step.Misbehaviours(); len(blamed) != 0 {
+		t.Fatalf("agreeing peers blamed: %v", blamed)
+	}
+
+	// without a strict majority no peer is blamed
+	wrong2.BytesData = [][]byte{gpkBytes(0x33)}
+	step = CreateAckMpcGPKStep(&peers)
+	step.InitStep(&mpcResult)
+	sendMessages(step, &msg1, &wrong2, &msgWrong1)
+	step.FinishStep(&mpcResult, nil)
+	if blamed := step.Misbehaviours(); len(blamed) != 0 {
+		t.Fatalf("peers blamed without a majority: %v", blamed)
+	}
+}
@@ -36,6 +36,10 @@ func CreateAckMpcRSStep(peers *[]mpcprotocol.PeerInfo, accType string) *MpcAckRS
 
 func (mars *MpcAckRSStep) InitStep(result mpcprotocol.MpcResultInterface) error {
 	log.SyslogInfo("MpcAckRSStep.InitStep begin")
+	if mars.checkSitOut(result) {
+		return nil
+	}
+
 	value, err := result.GetValue(mpcprotocol.RPublicKeyResult)
 	if err != nil {
 		log.SyslogErr("MpcAckRSStep::InitStep","ack mpc account step, init fail. err", err.Error())
@@ -53,6 +57,10 @@ func (mars *MpcAckRSStep) InitStep(result mpcprotocol.MpcResultInterface) error
 }
 
 func (mars *MpcAckRSStep) CreateMessage() []mpcprotocol.StepMessage {
+	if mars.sitOut {
+		return nil
+	}
+
 	return []mpcprotocol.StepMessage{mpcprotocol.StepMessage{
 		MsgCode:   mpcprotocol.MPCMessage,
 		PeerID:    nil,
@@ -63,6 +71,10 @@ func (mars *MpcAckRSStep) CreateMessage() []mpcprotocol.StepMessage {
 
 func (mars *MpcAckRSStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
 	log.SyslogInfo("MpcAckRSStep.FinishStep begin")
+	if mars.sitOut {
+		return mars.finishSitOut()
+	}
+
 	err := mars.BaseStep.FinishStep()
 	if err != nil {
 		return err
@@ -107,20 +119,34 @@ func (mars *MpcAckRSStep) HandleMessage(msg *mpcprotocol.StepMessage) bool {
 
 func (mars *MpcAckRSStep) verifyRS(result mpcprotocol.MpcResultInterface) error {
 	// check R
-	for _, mpcR := range mars.remoteMpcR {
+	rs := make(map[discover.NodeID]string, len(mars.remoteMpcR))
+	consistent := true
+	for peerID, mpcR := range mars.remoteMpcR {
 		if mpcR == nil {
 			return mpcprotocol.ErrInvalidMPCR
 		}
 
 		if mars.mpcR[0].Cmp(&mpcR[0]) != 0 || mars.mpcR[1].Cmp(&mpcR[1]) != 0 {
-			return mpcprotocol.ErrInvalidMPCR
+			consistent = false
 		}
+		rs[peerID] = mpcR[0].String() + "," + mpcR[1].String()
 	}
+	if !consistent {
+		mars.blameOutliers(rs, mpcprotocol.ErrInvalidMPCR)
+		return mpcprotocol.ErrInvalidMPCR
+	}
+
 	// check S
-	for _, mpcS := range mars.remoteMpcS {
+	ss := make(map[discover.NodeID]string, len(mars.remoteMpcS))
+	for peerID, mpcS := range mars.remoteMpcS {
 		if mars.mpcS.Cmp(&mpcS) != 0 {
-			return mpcprotocol.ErrInvalidMPCS
+			consistent = false
 		}
+		ss[peerID] = mpcS.String()
+	}
+	if !consistent {
+		mars.blameOutliers(ss, mpcprotocol.ErrInvalidMPCS)
+		return mpcprotocol.ErrInvalidMPCS
 	}
 
 	// check signVerify
@@ -192,3 +218,11 @@ func (mars *MpcAckRSStep) verifyRS(result mpcprotocol.MpcResultInterface) error
 	}
 	return nil
 }
+
+// blameOutliers reports the peers disagreeing with the majority, the local value may be the wrong one.
+func (mars *MpcAckRSStep) blameOutliers(values map[discover.NodeID]string, err error) {
+	for _, peerID := range majorityOutliers(values) {
+		peerID := peerID
+		mars.reportMisbehaviour(&peerID, err)
+	}
+}
@@ -3,19 +3,18 @@ package step
 import (
 	"github.com/wanchain/schnorr-mpc/log"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
-	"math/big"
 )
 
 type AckMpcStep struct {
 	BaseStep
-	messageType int64
+	protocol string
 }
 
-func CreateAckMpcStep(peers *[]mpcprotocol.PeerInfo, messageType int64) *AckMpcStep {
+func CreateAckMpcStep(peers *[]mpcprotocol.PeerInfo, protocol string) *AckMpcStep {
 	log.SyslogInfo("CreateAcknowledgeMpcStep begin")
 
 	return &AckMpcStep{
-		*CreateBaseStep(peers, 0), messageType}
+		*CreateBaseStep(peers, 0), protocol}
 }
 
 func (ack *AckMpcStep) InitStep(mpcprotocol.MpcResultInterface) error {
@@ -25,14 +24,12 @@ func (ack *AckMpcStep) InitStep(mpcprotocol.MpcResultInterface) error {
 func (ack *AckMpcStep) CreateMessage() []mpcprotocol.StepMessage {
 	log.SyslogInfo("AcknowledgeMpcStep.CreateMessage begin")
 
-	data := make([]big.Int, 1)
-	data[0].SetInt64(ack.messageType)
 	return []mpcprotocol.StepMessage{mpcprotocol.StepMessage{
 		MsgCode:   mpcprotocol.MPCMessage,
 		PeerID:    nil,
 		Peers:     nil,
-		Data:      data,
-		BytesData: nil}}
+		Data:      nil,
+		BytesData: [][]byte{[]byte(ack.protocol)}}}
 }
 
 func (ack *AckMpcStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
@@ -43,9 +40,7 @@ func (ack *AckMpcStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpc
 		return err
 	}
 
-	data := make([]big.Int, 1)
-	data[0].SetInt64(ack.messageType)
-	result.SetValue(mpcprotocol.MPCAction, data)
+	result.SetByteValue(mpcprotocol.MPCAction, []byte(ack.protocol))
 
 	log.SyslogInfo("AcknowledgeMpcStep.FinishStep succeed")
 	return nil
@@ -4,6 +4,7 @@ import (
 	"github.com/wanchain/schnorr-mpc/log"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"sync"
 	"time"
 )
 
@@ -13,15 +14,21 @@ type BaseStep struct {
 	finish  chan error
 	waiting int
 	waitAll bool // true: wait all
+	timeout time.Duration
+	timedOut bool
 	stepId  int
+	peerMu  *sync.Mutex // guards notRecvPeers and misbehaviours written by the message loop, a pointer as the step is copied by value
 	notRecvPeers map[discover.NodeID]*discover.NodeID
+	misbehaviours []mpcprotocol.PeerMisbehaviour
+	sitOut  bool // the node does not take part in the step
 }
 
 func CreateBaseStep(peers *[]mpcprotocol.PeerInfo, wait int) *BaseStep {
 	step := &BaseStep{
 		peers:   peers,
 		msgChan: make(chan *mpcprotocol.StepMessage, len(*peers)+3),
-		finish:  make(chan error, 3)}
+		finish:  make(chan error, 3),
+		peerMu:  new(sync.Mutex)}
 
 	if wait >= 0 {
 		step.waiting = wait
@@ -29,6 +36,7 @@ func CreateBaseStep(peers *[]mpcprotocol.PeerInfo, wait int) *BaseStep {
 		step.waiting = len(*peers)
 	}
 	step.waitAll = true
+	step.timeout = mpcprotocol.MPCTimeOut
 
 	step.notRecvPeers = make(map[discover.NodeID]*discover.NodeID)
 	for _, peer := range *peers {
@@ -76,9 +84,10 @@ func (step *BaseStep) FinishStep() error {
 
 		step.msgChan <- nil
 		return err
-	case <-time.After(mpcprotocol.MPCTimeOut):
+	case <-time.After(step.timeout):
 		log.SyslogErr("BaseStep.FinishStep, wait step finish timeout")
 		step.msgChan <- nil
+		step.timedOut = true
 
 		if !step.waitAll {
 			return nil
@@ -105,9 +114,14 @@ func (step *BaseStep) HandleMessage(msger mpcprotocol.GetMessageInterface) error
 				"should step", step.stepId,
 				"receive step", msg.StepId)
 		} else {
-			if step.waiting > 0 && msger.HandleMessage(msg) {
+			if _, waiting := step.notRecvPeers[*msg.PeerID]; !waiting && step.getPeerIndex(msg.PeerID) >= 0 {
+				log.SyslogErr("BaseStep.HandleMessage, duplicate message", "peer", msg.PeerID.String(), "step", step.stepId)
+				step.reportMisbehaviour(msg.PeerID, mpcprotocol.ErrDuplicateStepMessage)
+			} else if step.waiting > 0 && msger.HandleMessage(msg) {
 
+				step.peerMu.Lock()
 				delete(step.notRecvPeers, *msg.PeerID)
+				step.peerMu.Unlock()
 
 				step.waiting--
 				if step.waiting <= 0 {
@@ -120,6 +134,32 @@ func (step *BaseStep) HandleMessage(msger mpcprotocol.GetMessageInterface) error
 	return nil
 }
 
+// checkSitOut returns true if the node sits out the R and S steps, see MpcRSKAgreeStep.
+func (step *BaseStep) checkSitOut(result mpcprotocol.MpcResultInterface) bool {
+	value, err := result.GetValue(mpcprotocol.RMpcSitOut)
+	step.sitOut = err == nil && len(value) != 0 && value[0].Sign() != 0
+	return step.sitOut
+}
+
+// finishSitOut stops the message loop of the step the node sits out.
+func (step *BaseStep) finishSitOut() error {
+	log.SyslogInfo("BaseStep.finishSitOut, the node sits out the step", "step", step.stepId)
+	step.msgChan <- nil
+	return nil
+}
+
+// notReceivedPeers returns the peers whose messages are not received yet, the message loop may be still running.
+func (step *BaseStep) notReceivedPeers() []discover.NodeID {
+	step.peerMu.Lock()
+	defer step.peerMu.Unlock()
+
+	peers := make([]discover.NodeID, 0, len(step.notRecvPeers))
+	for peerID := range step.notRecvPeers {
+		peers = append(peers, peerID)
+	}
+	return peers
+}
+
 func (step *BaseStep) getPeerIndex(peerID *discover.NodeID) int {
 	for i, item := range *step.peers {
 		if item.PeerID == *peerID {
@@ -148,6 +188,53 @@ func (step *BaseStep) SetWaiting(waiting int) {
 	step.waiting = waiting
 }
 
+func (step *BaseStep) SetTimeout(timeout time.Duration) {
+	step.timeout = timeout
+}
+
+// TimedOut returns true if the step finished by timeout.
+func (step *BaseStep) TimedOut() bool {
+	return step.timedOut
+}
+
+func (step *BaseStep) reportMisbehaviour(peerID *discover.NodeID, err error) {
+	step.peerMu.Lock()
+	defer step.peerMu.Unlock()
+	step.misbehaviours = append(step.misbehaviours, mpcprotocol.PeerMisbehaviour{PeerID: *peerID, Err: err})
+}
+
+// Misbehaviours returns a copy of the misbehaviours of the peers found by the step.
+func (step *BaseStep) Misbehaviours() []mpcprotocol.PeerMisbehaviour {
+	step.peerMu.Lock()
+	defer step.peerMu.Unlock()
+	return append([]mpcprotocol.PeerMisbehaviour(nil), step.misbehaviours...)
+}
+
+// majorityOutliers returns the peers whose value differs from the value sent by a strict majority
+// of the peers. A disagreement without a strict majority can't be blamed on any peer, nil is returned.
+func majorityOutliers(values map[discover.NodeID]string) []discover.NodeID {
+	counts := make(map[string]int)
+	for _, value := range values {
+		counts[value]++
+	}
+
+	for majority, count := range counts {
+		if 2*count <= len(values) {
+			continue
+		}
+
+		var outliers []discover.NodeID
+		for peerID, value := range values {
+			if value != majority {
+				outliers = append(outliers, peerID)
+			}
+		}
+		return outliers
+	}
+
+	return nil
+}
+
 func (step *BaseStep) SetStepId(stepId int) {
 	step.stepId = stepId
 }
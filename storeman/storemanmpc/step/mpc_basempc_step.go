@@ -54,8 +54,8 @@ func (mpcStep *BaseMpcStep) FinishStep() error {
 }
 
 func (mpcStep *BaseMpcStep) ShowNotArriveNodes(hash common.Hash, selfNodeId *discover.NodeID){
-	if len(mpcStep.notRecvPeers) != 0 {
-		for peerId,_ := range mpcStep.notRecvPeers {
+	if peers := mpcStep.notReceivedPeers(); len(peers) != 0 {
+		for _, peerId := range peers {
 			if peerId != *selfNodeId {
 				//log.SyslogErr(fmt.Sprintf("Not received data from %v", peerId.String()))
 				log.SyslogErr("ShowNotArriveNodes","hash(signedData)",hash.String(),"Not received data from ", peerId.String())
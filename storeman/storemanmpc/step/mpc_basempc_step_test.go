@@ -4,7 +4,6 @@ import (
 	"github.com/wanchain/schnorr-mpc/crypto"
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	"github.com/wanchain/schnorr-mpc/storeman/shcnorrmpc"
-	mpccrypto "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/crypto"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"math/big"
 	"testing"
@@ -197,5 +196,5 @@ func testLagrange(seed []int, peerInfo []mpcprotocol.PeerInfo, jrssResult []big.
 		fx[i] = jrssResult[seed[i]]
 	}
 
-	return shcnorrmpc.Lagrange(fx, x)
+	return shcnorrmpc.Lagrange(fx, x, len(seed)-1)
 }
@@ -15,6 +15,7 @@ type mpcPointGenerator struct {
 	message     map[uint64][2]big.Int
 	result      [2]big.Int
 	preValueKey string
+	threshold   int
 }
 
 func createPointGenerator(preValueKey string) *mpcPointGenerator {
@@ -24,6 +25,14 @@ func createPointGenerator(preValueKey string) *mpcPointGenerator {
 func (point *mpcPointGenerator) initialize(peers *[]mpcprotocol.PeerInfo, result mpcprotocol.MpcResultInterface) error {
 	log.SyslogInfo("mpcPointGenerator.initialize begin ")
 
+	threshold, err := result.GetValue(mpcprotocol.MpcThreshold)
+	if err != nil {
+		log.SyslogErr("mpcPointGenerator.initialize get MpcThreshold fail")
+		return err
+	}
+
+	point.threshold = int(threshold[0].Int64())
+
 	value, err := result.GetValue(point.preValueKey)
 	log.SyslogInfo("public share mpcPointGenerator.initialize GetValue ",
 		"key", point.preValueKey,
@@ -71,13 +80,13 @@ func (point *mpcPointGenerator) calculateResult() error {
 
 	// lagrangeEcc
 	log.SyslogInfo("all public",
-		"Need nodes number:", mpcprotocol.MpcSchnrThr,
+		"Need nodes number:", point.threshold,
 		"Now nodes number:", len(gpkshares))
-	if len(gpkshares) < mpcprotocol.MpcSchnrThr {
+	if point.threshold <= 0 || len(gpkshares) < point.threshold {
 		return mpcprotocol.ErrTooLessDataCollected
 	}
 
-	result := shcnorrmpc.LagrangeECC(gpkshares, seeds[:], mpcprotocol.MPCDegree)
+	result := shcnorrmpc.LagrangeECC(gpkshares, seeds[:], point.threshold-1)
 
 	if !shcnorrmpc.ValidatePublicKey(result) {
 		log.SyslogErr("mpcPointGenerator::calculateResult","mpcPointGenerator.ValidatePublicKey fail. err", mpcprotocol.ErrPointZero.Error())
@@ -26,9 +26,9 @@ func (ctx *mpcPointGeneratorTestContext) Init()  {
 	nodeId3, _ := discover.HexID("0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000003")
 
 	ctx.peers = make([]mpcprotocol.PeerInfo, 0, 3)
-	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{nodeId1, 1})
-	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{nodeId2, 2})
-	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{nodeId3, 3})
+	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{PeerID: nodeId1, Seed: 1})
+	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{PeerID: nodeId2, Seed: 2})
+	ctx.peers = append(ctx.peers, mpcprotocol.PeerInfo{PeerID: nodeId3, Seed: 3})
 
 }
 
@@ -37,31 +37,22 @@ func TestPotGeneratorCalculateResult(t *testing.T)  {
 	ctx.Init()
 
 	point := createPointGenerator(ctx.preValueKey)
+	point.threshold = 2
 
+	// shares of the polynomial f(x) = 55 + 2x, the result is f(0)*G
 	curve := crypto.S256()
 	for i := 1; i <= 10; i++ {
-		xi, yi := curve.ScalarBaseMult(big.NewInt(int64(i)).Bytes())
+		xi, yi := curve.ScalarBaseMult(big.NewInt(int64(55 + 2*i)).Bytes())
 		point.message[uint64(i)] = [2]big.Int{*xi, *yi}
 	}
 
-	resultX, resultY := curve.ScalarBaseMult(big.NewInt(int64(1)).Bytes())
-	for i := 2; i <= 10; i++ {
-		xi, yi := curve.ScalarBaseMult(big.NewInt(int64(i)).Bytes())
-		resultXTmp, resultYTmp := curve.Add(resultX, resultY, xi, yi)
-		resultX = resultXTmp
-		resultY = resultYTmp
-		t.Logf("resultX:%s, resultY:%s", resultX.String(), resultY.String())
-	}
-
-	t.Logf("resultX:%s, resultY:%s", resultX.String(), resultY.String())
-
 	err := point.calculateResult()
 	if err != nil {
 		t.Error("point calculateResult fail")
 	}
 
 	x55, y55 := curve.ScalarBaseMult(big.NewInt(55).Bytes())
-	t.Logf("x10:%s, y10:%s", x55.String(), y55.String())
+	t.Logf("x55:%s, y55:%s", x55.String(), y55.String())
 	t.Logf("point.x:%s, point.y:%s", point.result[0].String(), point.result[1].String())
 
 	if x55.Cmp(&point.result[0]) != 0 || y55.Cmp(&point.result[1]) != 0 {
@@ -69,6 +60,3 @@ func TestPotGeneratorCalculateResult(t *testing.T)  {
 	}
 
 }
-
-
-
@@ -15,7 +15,27 @@ func CreateMpcRStep(peers *[]mpcprotocol.PeerInfo, accType string) *MpcRStep {
 	return mpc
 }
 
+func (addStep *MpcRStep) InitStep(result mpcprotocol.MpcResultInterface) error {
+	if addStep.checkSitOut(result) {
+		return nil
+	}
+
+	return addStep.MpcPointStep.InitStep(result)
+}
+
+func (addStep *MpcRStep) CreateMessage() []mpcprotocol.StepMessage {
+	if addStep.sitOut {
+		return nil
+	}
+
+	return addStep.MpcPointStep.CreateMessage()
+}
+
 func (addStep *MpcRStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
+	if addStep.sitOut {
+		return addStep.finishSitOut()
+	}
+
 	err := addStep.MpcPointStep.FinishStep(result, mpc)
 
 	if err != nil {
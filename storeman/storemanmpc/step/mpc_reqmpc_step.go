@@ -5,78 +5,55 @@ import (
 	"github.com/wanchain/schnorr-mpc/p2p/discover"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"math/big"
-	"math/rand"
 	"time"
 )
 
 type RequestMpcStep struct {
 	BaseStep
-	messageType int64
-	mpcSignByApprove []big.Int
-	address     []byte
-	mpcM        []byte
-	mpcExt      []byte
+	protocol    string
+	requestKeys []string
+	requestData [][]byte
+	byApprove   big.Int
+	deadline    big.Int // approval deadline of the request, zero if the data needs no approval
 	message     map[discover.NodeID]bool
 }
 
-func CreateRequestMpcStep(peers *[]mpcprotocol.PeerInfo, messageType int64) *RequestMpcStep {
+func CreateRequestMpcStep(peers *[]mpcprotocol.PeerInfo, protocol string, requestKeys []string) *RequestMpcStep {
 
 	return &RequestMpcStep{
 		BaseStep:    *CreateBaseStep(peers, len(*peers)-1),
-		messageType: messageType,
+		protocol:    protocol,
+		requestKeys: requestKeys,
 		message:     make(map[discover.NodeID]bool)}
 }
 
 func (req *RequestMpcStep) InitStep(result mpcprotocol.MpcResultInterface) error {
-	log.SyslogInfo("RequestMpcStep.InitStep begin")
-
-	if req.messageType == mpcprotocol.MpcGPKLeader {
-		findMap := make(map[uint64]bool)
-		rand.Seed(time.Now().UnixNano())
-		for i := 0; i < len(*req.peers); i++ {
-			for {
-				(*req.peers)[i].Seed = (uint64)(rand.Intn(0x0FFFFFE) + 1)
-				_, exist := findMap[(*req.peers)[i].Seed]
-				if exist {
-					continue
-				}
-
-				findMap[(*req.peers)[i].Seed] = true
-				break
-			}
-		}
-
-		for index, peer := range *req.peers {
-			log.Info("RequestMpcStep::InitStep ",
-				"index", index,
-				"peerID", peer.PeerID.String(),
-				"seed", peer.Seed)
-		}
+	log.SyslogInfo("RequestMpcStep.InitStep begin", "protocol", req.protocol)
 
-	} else if req.messageType == mpcprotocol.MpcSignLeader {
-
-		var err error
-		req.address, err = result.GetByteValue(mpcprotocol.MpcAddress)
+	req.requestData = make([][]byte, len(req.requestKeys))
+	for i, key := range req.requestKeys {
+		value, err := result.GetByteValue(key)
 		if err != nil {
+			log.SyslogErr("RequestMpcStep.InitStep get request value fail", "key", key)
 			return err
 		}
 
-		req.mpcM, err = result.GetByteValue(mpcprotocol.MpcM)
-		if err != nil {
-			return err
-		}
+		req.requestData[i] = value
+	}
 
-		req.mpcExt, err = result.GetByteValue(mpcprotocol.MpcExt)
-		if err != nil {
-			return err
-		}
+	// by approve is optional
+	byApprove, err := result.GetValue(mpcprotocol.MpcByApprove)
+	if err == nil && len(byApprove) > 0 {
+		req.byApprove = byApprove[0]
+	}
 
-		req.mpcSignByApprove, err = result.GetValue(mpcprotocol.MpcByApprove)
-		if err != nil {
-			return err
+	// the peers acknowledge after the data is approved, so wait for them until the approval deadline
+	deadline, err := result.GetValue(mpcprotocol.MpcApprovalDeadline)
+	if err == nil && len(deadline) > 0 {
+		req.deadline = deadline[0]
+		if untilDeadline := time.Until(time.Unix(req.deadline.Int64(), 0)); untilDeadline > 0 {
+			req.SetTimeout(req.timeout + untilDeadline)
 		}
-
-
 	}
 
 	return nil
@@ -92,32 +69,35 @@ func (req *RequestMpcStep) CreateMessage() []mpcprotocol.StepMessage {
 		Data:      nil,
 		BytesData: nil}
 
-	msg.Data = make([]big.Int, 2)
-	msg.Data[0].SetInt64(req.messageType)
-	if req.messageType == mpcprotocol.MpcSignLeader {
-
-		msg.Data[1] = req.mpcSignByApprove[0]
-
-		msg.BytesData = make([][]byte, 3)
-		msg.BytesData[0] = req.mpcM
-		msg.BytesData[1] = req.address
-		msg.BytesData[2] = req.mpcExt
-	} else if req.messageType == mpcprotocol.MpcGPKLeader {
-		//todo  do nothing?
+	msg.Data = make([]big.Int, 1, 2)
+	msg.Data[0] = req.byApprove
+	if req.deadline.Sign() > 0 {
+		msg.Data = append(msg.Data, req.deadline)
 	}
 
+	msg.BytesData = make([][]byte, 0, len(req.requestData)+1)
+	msg.BytesData = append(msg.BytesData, []byte(req.protocol))
+	msg.BytesData = append(msg.BytesData, req.requestData...)
+
 	return []mpcprotocol.StepMessage{msg}
 }
 
 func (req *RequestMpcStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
 	err := req.BaseStep.FinishStep()
+
+	// the peers acknowledge after the data is approved, so the acknowledge latency is not a round trip time,
+	// and the peers left out once enough peers acknowledged did not time out
+	if req.TimedOut() {
+		for _, peerID := range req.notReceivedPeers() {
+			mpc.ReportPeerTimeout(&peerID)
+		}
+	}
+
 	if err != nil {
 		return err
 	}
 
-	data := make([]big.Int, 1)
-	data[0].SetInt64(req.messageType)
-	result.SetValue(mpcprotocol.MPCAction, data)
+	result.SetByteValue(mpcprotocol.MPCAction, []byte(req.protocol))
 	return nil
 }
 
@@ -0,0 +1,151 @@
+package step
+
+import (
+	"crypto/ecdsa"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"math/big"
+)
+
+// MpcRSKAgreeStep broadcasts the dealers agreed by the leader, every node recomputes its rsk share
+// from the shares of exactly these dealers, so all the nodes share the same polynomial.
+// A node missing one of the shares sits out the R and S steps, the other nodes still reach the threshold.
+type MpcRSKAgreeStep struct {
+	BaseStep
+	leader    bool
+	leaderID  discover.NodeID
+	threshold int
+	agreed    []big.Int
+	received  []big.Int
+}
+
+// the leader id is known at creation, the dealer set may arrive before the step is initialized.
+// The dealer set must hold at least threshold dealers of the peers, so at least one of them is honest.
+func CreateMpcRSKAgreeStep(peers *[]mpcprotocol.PeerInfo, leaderID discover.NodeID, leader bool, threshold int) *MpcRSKAgreeStep {
+	return &MpcRSKAgreeStep{
+		BaseStep:  *CreateBaseStep(peers, 1),
+		leader:    leader,
+		leaderID:  leaderID,
+		threshold: threshold}
+}
+
+func (agree *MpcRSKAgreeStep) InitStep(result mpcprotocol.MpcResultInterface) error {
+	if agree.leader {
+		var err error
+		agree.agreed, err = result.GetValue(mpcprotocol.RMpcAgreedDealers)
+		if err != nil {
+			log.SyslogErr("MpcRSKAgreeStep.InitStep get RMpcAgreedDealers fail")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (agree *MpcRSKAgreeStep) CreateMessage() []mpcprotocol.StepMessage {
+	if !agree.leader {
+		return nil
+	}
+
+	return []mpcprotocol.StepMessage{mpcprotocol.StepMessage{
+		MsgCode:   mpcprotocol.MPCMessage,
+		PeerID:    nil,
+		Peers:     nil,
+		Data:      agree.agreed,
+		BytesData: nil}}
+}
+
+func (agree *MpcRSKAgreeStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
+	err := agree.BaseStep.FinishStep()
+	if err != nil {
+		return err
+	}
+
+	if len(agree.received) == 0 || len(agree.received) < agree.threshold {
+		log.SyslogErr("MpcRSKAgreeStep.FinishStep, too less dealers agreed by leader",
+			"dealers", len(agree.received),
+			"threshold", agree.threshold)
+		return mpcprotocol.ErrInvalidDealerSet
+	}
+
+	seeds := make(map[uint64]bool)
+	for _, peer := range *agree.peers {
+		seeds[peer.Seed] = true
+	}
+
+	dealers, err := result.GetValue(mpcprotocol.RMpcShareDealers)
+	if err != nil {
+		return err
+	}
+
+	shares, err := result.GetValue(mpcprotocol.RMpcShareValues)
+	if err != nil {
+		return err
+	}
+
+	shareMap := make(map[uint64]*big.Int)
+	for i := range dealers {
+		shareMap[dealers[i].Uint64()] = &shares[i]
+	}
+
+	rskShare := big.NewInt(0)
+	used := make(map[uint64]bool)
+	missing := 0
+	for _, dealer := range agree.received {
+		seed := dealer.Uint64()
+		if !dealer.IsUint64() || !seeds[seed] || used[seed] {
+			log.SyslogErr("MpcRSKAgreeStep.FinishStep, agreed dealer is not a peer or repeated", "dealer", dealer.String())
+			return mpcprotocol.ErrInvalidDealerSet
+		}
+
+		used[seed] = true
+		share, exist := shareMap[seed]
+		if !exist {
+			log.SyslogWarning("MpcRSKAgreeStep.FinishStep, share of the agreed dealer is missing", "seed", seed)
+			missing++
+			continue
+		}
+
+		rskShare.Add(rskShare, share)
+		rskShare.Mod(rskShare, crypto.S256().Params().N)
+	}
+
+	// without all the agreed shares the rsk share is not on the agreed polynomial,
+	// the node sits out the R and S steps and leaves the signature to the other nodes.
+	if missing != 0 {
+		log.SyslogWarning("MpcRSKAgreeStep.FinishStep, sit out the R and S steps", "missing", missing)
+		return result.SetValue(mpcprotocol.RMpcSitOut, []big.Int{*big.NewInt(1)})
+	}
+
+	err = result.SetValue(mpcprotocol.RMpcSitOut, []big.Int{*big.NewInt(0)})
+	if err != nil {
+		return err
+	}
+
+	err = result.SetValue(mpcprotocol.RMpcPrivateShare, []big.Int{*rskShare})
+	if err != nil {
+		return err
+	}
+
+	var rpkShare ecdsa.PublicKey
+	rpkShare.X, rpkShare.Y = crypto.S256().ScalarBaseMult(rskShare.Bytes())
+	err = result.SetValue(mpcprotocol.RMpcPublicShare, []big.Int{*rpkShare.X, *rpkShare.Y})
+	if err != nil {
+		return err
+	}
+
+	log.SyslogInfo("MpcRSKAgreeStep.FinishStep succeed", "dealers", len(agree.received))
+	return nil
+}
+
+func (agree *MpcRSKAgreeStep) HandleMessage(msg *mpcprotocol.StepMessage) bool {
+	if *msg.PeerID != agree.leaderID {
+		log.SyslogErr("MpcRSKAgreeStep.HandleMessage, dealer set is not sent by leader", "peer", msg.PeerID.String())
+		return false
+	}
+
+	agree.received = msg.Data
+	return true
+}
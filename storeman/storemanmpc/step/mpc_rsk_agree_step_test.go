@@ -0,0 +1,134 @@
+package step
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+type agreeMpcResult struct {
+	values map[string][]big.Int
+}
+
+func (ret *agreeMpcResult) Initialize() error {
+	return nil
+}
+
+func (ret *agreeMpcResult) SetByteValue(key string, value []byte) error {
+	return nil
+}
+
+func (ret *agreeMpcResult) GetByteValue(key string) ([]byte, error) {
+	return nil, nil
+}
+
+func (ret *agreeMpcResult) SetValue(key string, value []big.Int) error {
+	ret.values[key] = value
+	return nil
+}
+
+func (ret *agreeMpcResult) GetValue(key string) ([]big.Int, error) {
+	return ret.values[key], nil
+}
+
+func TestRSKAgreeStepDealerSet(t *testing.T) {
+	agreePeers := []mpcprotocol.PeerInfo{{PeerID: discover.NodeID{1}, Seed: 11}, {PeerID: discover.NodeID{2}, Seed: 12}, {PeerID: discover.NodeID{3}, Seed: 13}}
+	leaderID := agreePeers[0].PeerID
+
+	tests := []struct {
+		dealers []int64
+		err     error
+		sitOut  bool
+	}{
+		{[]int64{11, 12}, nil, false},
+		{[]int64{11, 12, 13}, nil, true},                      // the share of 13 is not received
+		{[]int64{11}, mpcprotocol.ErrInvalidDealerSet, false}, // the leader alone knows the nonce
+		{[]int64{11, 99}, mpcprotocol.ErrInvalidDealerSet, false},
+		{[]int64{11, 11}, mpcprotocol.ErrInvalidDealerSet, false},
+	}
+
+	for i, test := range tests {
+		agree := CreateMpcRSKAgreeStep(&agreePeers, leaderID, false, 2)
+
+		dealers := make([]big.Int, len(test.dealers))
+		for j, seed := range test.dealers {
+			dealers[j].SetInt64(seed)
+		}
+		if !agree.HandleMessage(&mpcprotocol.StepMessage{PeerID: &leaderID, Data: dealers}) {
+			t.Fatalf("test %d: dealer set of the leader is refused", i)
+		}
+		agree.finish <- nil
+
+		result := &agreeMpcResult{values: map[string][]big.Int{
+			mpcprotocol.RMpcShareDealers: {*big.NewInt(11), *big.NewInt(12), *big.NewInt(99)},
+			mpcprotocol.RMpcShareValues:  {*big.NewInt(1), *big.NewInt(2), *big.NewInt(4)},
+		}}
+		if err := agree.FinishStep(result, nil); err != test.err {
+			t.Errorf("test %d: got %v, want %v", i, err, test.err)
+		}
+
+		if test.err != nil {
+			continue
+		}
+
+		var step BaseStep
+		if step.checkSitOut(result) != test.sitOut {
+			t.Errorf("test %d: sit out %v, want %v", i, !test.sitOut, test.sitOut)
+		}
+
+		if _, exist := result.values[mpcprotocol.RMpcPrivateShare]; exist == test.sitOut {
+			t.Errorf("test %d: rsk share is set %v while sitting out %v", i, exist, test.sitOut)
+		}
+	}
+}
+
+func TestRSStepsSitOut(t *testing.T) {
+	sitOutPeers := []mpcprotocol.PeerInfo{{PeerID: discover.NodeID{1}, Seed: 11}, {PeerID: discover.NodeID{2}, Seed: 12}, {PeerID: discover.NodeID{3}, Seed: 13}}
+	result := &agreeMpcResult{values: map[string][]big.Int{
+		mpcprotocol.RMpcSitOut: {*big.NewInt(1)},
+	}}
+
+	steps := map[string]interface {
+		InitMessageLoop(mpcprotocol.GetMessageInterface) error
+		InitStep(mpcprotocol.MpcResultInterface) error
+		CreateMessage() []mpcprotocol.StepMessage
+		FinishStep(mpcprotocol.MpcResultInterface, mpcprotocol.StoremanManager) error
+		HandleMessage(*mpcprotocol.StepMessage) bool
+		SetTimeout(time.Duration)
+	}{
+		"r":     CreateMpcRStep(&sitOutPeers, ""),
+		"s":     CreateMpcSStep(&sitOutPeers, []string{mpcprotocol.MpcPrivateShare}, []string{mpcprotocol.MpcS}),
+		"ackRS": CreateAckMpcRSStep(&sitOutPeers, ""),
+	}
+
+	for name, step := range steps {
+		// a step waiting for the peers would fail by timeout
+		step.SetTimeout(time.Minute)
+		step.InitMessageLoop(step)
+
+		if err := step.InitStep(result); err != nil {
+			t.Errorf("%s: InitStep fail, %v", name, err)
+		}
+
+		if msg := step.CreateMessage(); msg != nil {
+			t.Errorf("%s: the node sitting out sends %d messages", name, len(msg))
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- step.FinishStep(result, nil)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("%s: FinishStep fail, %v", name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: FinishStep waits for the peers", name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package step
+
+import (
+	"github.com/wanchain/schnorr-mpc/log"
+	"github.com/wanchain/schnorr-mpc/p2p/discover"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"math/big"
+)
+
+// MpcRSKReportStep reports the dealers whose rsk shares are received to the leader,
+// the leader agrees on the dealers received by all the reporters.
+type MpcRSKReportStep struct {
+	BaseStep
+	leader    bool
+	threshold int
+	leaderID  discover.NodeID
+	dealers   []big.Int
+	reports   map[discover.NodeID][]big.Int
+}
+
+func CreateMpcRSKReportStep(peers *[]mpcprotocol.PeerInfo, leaderID discover.NodeID, leader bool, threshold int) *MpcRSKReportStep {
+	wait := 0
+	if leader {
+		wait = threshold
+	}
+
+	return &MpcRSKReportStep{
+		BaseStep:  *CreateBaseStep(peers, wait),
+		leader:    leader,
+		threshold: threshold,
+		leaderID:  leaderID,
+		reports:   make(map[discover.NodeID][]big.Int)}
+}
+
+func (report *MpcRSKReportStep) InitStep(result mpcprotocol.MpcResultInterface) error {
+	var err error
+	report.dealers, err = result.GetValue(mpcprotocol.RMpcShareDealers)
+	if err != nil {
+		log.SyslogErr("MpcRSKReportStep.InitStep get RMpcShareDealers fail")
+		return err
+	}
+
+	return nil
+}
+
+func (report *MpcRSKReportStep) CreateMessage() []mpcprotocol.StepMessage {
+	return []mpcprotocol.StepMessage{mpcprotocol.StepMessage{
+		MsgCode:   mpcprotocol.MPCMessage,
+		PeerID:    &report.leaderID,
+		Peers:     nil,
+		Data:      report.dealers,
+		BytesData: nil}}
+}
+
+func (report *MpcRSKReportStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
+	err := report.BaseStep.FinishStep()
+	if err != nil {
+		return err
+	}
+
+	if !report.leader {
+		return nil
+	}
+
+	// intersection of the reported dealers
+	count := make(map[uint64]int)
+	for _, dealers := range report.reports {
+		for _, dealer := range dealers {
+			count[dealer.Uint64()]++
+		}
+	}
+
+	agreed := make([]big.Int, 0, len(report.dealers))
+	for _, dealer := range report.dealers {
+		if count[dealer.Uint64()] == len(report.reports) {
+			agreed = append(agreed, dealer)
+		}
+	}
+
+	// at least threshold dealers, so at least one of them is honest
+	if len(agreed) < report.threshold {
+		log.SyslogErr("MpcRSKReportStep.FinishStep, too less dealers agreed",
+			"agreed", len(agreed),
+			"reports", len(report.reports),
+			"threshold", report.threshold)
+		return mpcprotocol.ErrInvalidDealerSet
+	}
+
+	log.SyslogInfo("MpcRSKReportStep.FinishStep", "agreed dealers", len(agreed), "reports", len(report.reports))
+	return result.SetValue(mpcprotocol.RMpcAgreedDealers, agreed)
+}
+
+func (report *MpcRSKReportStep) HandleMessage(msg *mpcprotocol.StepMessage) bool {
+	if !report.leader {
+		return false
+	}
+
+	_, exist := report.reports[*msg.PeerID]
+	if exist {
+		log.SyslogErr("MpcRSKReportStep.HandleMessage, get duplicate report", "peer", msg.PeerID.String())
+		return false
+	}
+
+	report.reports[*msg.PeerID] = msg.Data
+	return true
+}
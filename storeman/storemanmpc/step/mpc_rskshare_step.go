@@ -6,6 +6,7 @@ import (
 	"github.com/wanchain/schnorr-mpc/log"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
 	"math/big"
+	"sort"
 )
 
 type MpcRSKShare_Step struct {
@@ -37,8 +38,32 @@ func (jrss *MpcRSKShare_Step) FinishStep(result mpcprotocol.MpcResultInterface,
 		return err
 	}
 
-	// gskshare
+	// the received shares are kept, so the share can be recomputed from the agreed dealers
 	JRSSvalue := jrss.messages[0].(*RandomPolynomialValue)
+	seeds := make([]uint64, 0, len(JRSSvalue.message))
+	for seed := range JRSSvalue.message {
+		seeds = append(seeds, seed)
+	}
+
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i] < seeds[j] })
+	dealers := make([]big.Int, len(seeds))
+	shares := make([]big.Int, len(seeds))
+	for i, seed := range seeds {
+		dealers[i].SetUint64(seed)
+		shares[i] = JRSSvalue.message[seed]
+	}
+
+	err = result.SetValue(mpcprotocol.RMpcShareDealers, dealers)
+	if err != nil {
+		return err
+	}
+
+	err = result.SetValue(mpcprotocol.RMpcShareValues, shares)
+	if err != nil {
+		return err
+	}
+
+	// gskshare
 	err = result.SetValue(mpcprotocol.RMpcPrivateShare, []big.Int{*JRSSvalue.result})
 	if err != nil {
 		return err
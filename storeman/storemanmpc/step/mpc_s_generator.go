@@ -17,6 +17,7 @@ type mpcSGenerator struct {
 	message     map[uint64]big.Int
 	result      big.Int
 	preValueKey string
+	threshold   int
 }
 
 func createSGenerator(preValueKey string) *mpcSGenerator {
@@ -26,6 +27,14 @@ func createSGenerator(preValueKey string) *mpcSGenerator {
 func (msg *mpcSGenerator) initialize(peers *[]mpcprotocol.PeerInfo, result mpcprotocol.MpcResultInterface) error {
 	log.SyslogInfo("mpcSGenerator.initialize begin")
 
+	threshold, err := result.GetValue(mpcprotocol.MpcThreshold)
+	if err != nil {
+		log.SyslogErr("mpcSGenerator.initialize get MpcThreshold fail")
+		return err
+	}
+
+	msg.threshold = int(threshold[0].Int64())
+
 	// rgpk R
 	rgpkValue, err := result.GetValue(mpcprotocol.RPublicKeyResult)
 
@@ -91,12 +100,12 @@ func (msg *mpcSGenerator) calculateResult() error {
 
 	// Lagrange
 	log.SyslogInfo("all signature share",
-		"Need nodes number:", mpcprotocol.MpcSchnrThr,
+		"Need nodes number:", msg.threshold,
 		"Now nodes number:", len(sigshares))
-	if len(sigshares) < mpcprotocol.MpcSchnrThr {
+	if msg.threshold <= 0 || len(sigshares) < msg.threshold {
 		return mpcprotocol.ErrTooLessDataCollected
 	}
-	result := shcnorrmpc.Lagrange(sigshares, seeds[:], mpcprotocol.MPCDegree)
+	result := shcnorrmpc.Lagrange(sigshares, seeds[:], msg.threshold-1)
 	msg.result = result
 	log.SyslogInfo("mpcSGenerator.calculateResult succeed")
 
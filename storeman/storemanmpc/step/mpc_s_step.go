@@ -28,8 +28,19 @@ func CreateMpcSStep(peers *[]mpcprotocol.PeerInfo, preValueKeys []string, result
 	return mpc
 }
 
+func (msStep *MpcSStep) InitStep(result mpcprotocol.MpcResultInterface) error {
+	if msStep.checkSitOut(result) {
+		return nil
+	}
+
+	return msStep.BaseMpcStep.InitStep(result)
+}
+
 func (msStep *MpcSStep) CreateMessage() []mpcprotocol.StepMessage {
 	log.SyslogInfo("MpcSStep.CreateMessage begin")
+	if msStep.sitOut {
+		return nil
+	}
 
 	message := make([]mpcprotocol.StepMessage, 1)
 	message[0].MsgCode = mpcprotocol.MPCMessage
@@ -72,6 +83,10 @@ func (msStep *MpcSStep) HandleMessage(msg *mpcprotocol.StepMessage) bool {
 
 func (msStep *MpcSStep) FinishStep(result mpcprotocol.MpcResultInterface, mpc mpcprotocol.StoremanManager) error {
 	log.SyslogInfo("MpcSStep.FinishStep begin")
+	if msStep.sitOut {
+		return msStep.finishSitOut()
+	}
+
 	err := msStep.BaseMpcStep.FinishStep()
 	if err != nil {
 		_,retHash := msStep.BaseMpcStep.GetSignedDataHash(result)
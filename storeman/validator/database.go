@@ -5,6 +5,7 @@ import (
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/wanchain/schnorr-mpc/log"
 )
 
@@ -15,9 +16,18 @@ type Database interface {
 	Get(key []byte) ([]byte, error)
 	Delete(key []byte) error
 	Has(key []byte) (bool, error)
+	Iterate(prefix []byte, fn func(key, value []byte) bool) error // fn returns false to stop the iteration
+	NewBatch() Batch
 	Close()
 }
 
+// Batch collects writes which are applied to the database atomically.
+type Batch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+	Write() error
+}
+
 // Level db implementation
 type storemanDB struct {
 	fn string
@@ -43,7 +53,7 @@ func NewDatabase(file string) error {
 		db: db,
 	}
 
-	return nil
+	return migrateApprovingKeys(dbInstance)
 }
 
 func (db *storemanDB) Put(key []byte, value []byte) error {
@@ -67,6 +77,40 @@ func (db *storemanDB) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
 }
 
+func (db *storemanDB) Iterate(prefix []byte, fn func(key, value []byte) bool) error {
+	it := db.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		if !fn(it.Key(), it.Value()) {
+			break
+		}
+	}
+
+	return it.Error()
+}
+
+type storemanBatch struct {
+	db    *leveldb.DB
+	batch leveldb.Batch
+}
+
+func (db *storemanDB) NewBatch() Batch {
+	return &storemanBatch{db: db.db}
+}
+
+func (b *storemanBatch) Put(key []byte, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *storemanBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *storemanBatch) Write() error {
+	return b.db.Write(&b.batch, nil)
+}
+
 func (db *storemanDB) Close() {
 	err := db.db.Close()
 	if err == nil {
@@ -87,3 +131,14 @@ func GetDB() (Database, error) {
 
 	return dbInstance, nil
 }
+
+// Status checks that the storeman database is open and readable.
+func Status() error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Has([]byte("storeman-health"))
+	return err
+}
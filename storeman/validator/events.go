@@ -0,0 +1,93 @@
+package validator
+
+import (
+	"sync"
+
+	"github.com/wanchain/schnorr-mpc/event"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// ApprovalState is the state of a data requested to be approved.
+type ApprovalState string
+
+const (
+	ApprovalPending  ApprovalState = "pending"  // the data is added to the approving db
+	ApprovalApproved ApprovalState = "approved" // the data is approved by operators
+	ApprovalRejected ApprovalState = "rejected" // the data is rejected by an operator
+	ApprovalExpired  ApprovalState = "expired"  // the data is purged as nobody approved it in time
+	ApprovalTimeout  ApprovalState = "timeout"  // a context stopped waiting, the data is still pending
+)
+
+// ApprovalEvent is posted when a data waiting for approval changes its state.
+type ApprovalEvent struct {
+	State   ApprovalState        `json:"state"`
+	Data    mpcprotocol.SendData `json:"data"`
+	Reason  string               `json:"reason,omitempty"`
+	Payload map[string]string    `json:"payload,omitempty"` // fields decoded from the data
+}
+
+// ApprovalEventQueueSize is the number of approval events queued for a subscriber, the events are
+// dropped for a subscriber which falls further behind.
+const ApprovalEventQueueSize = 256
+
+var (
+	approvalEventMu     sync.Mutex
+	approvalEventQueues = make(map[chan ApprovalEvent]struct{})
+)
+
+// SubscribeApprovalEvents subscribes the state changes of the data waiting for approval.
+func SubscribeApprovalEvents(ch chan<- ApprovalEvent) event.Subscription {
+	queue := make(chan ApprovalEvent, ApprovalEventQueueSize)
+
+	approvalEventMu.Lock()
+	approvalEventQueues[queue] = struct{}{}
+	approvalEventMu.Unlock()
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			approvalEventMu.Lock()
+			delete(approvalEventQueues, queue)
+			approvalEventMu.Unlock()
+		}()
+
+		for {
+			select {
+			case ev := <-queue:
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// postApprovalEvent posts the event at once, the caller must not hold storeMu.
+func postApprovalEvent(state ApprovalState, data *mpcprotocol.SendData, reason string) {
+	sendApprovalEvent(newApprovalEvent(state, data, reason))
+}
+
+// queueApprovalEvent queues the event to be posted once storeMu is released. The caller must hold storeMu.
+func queueApprovalEvent(state ApprovalState, data *mpcprotocol.SendData, reason string) {
+	storeEvents = append(storeEvents, newApprovalEvent(state, data, reason))
+}
+
+func newApprovalEvent(state ApprovalState, data *mpcprotocol.SendData, reason string) ApprovalEvent {
+	return ApprovalEvent{State: state, Data: *data, Reason: reason, Payload: DecodePayload(data)}
+}
+
+func sendApprovalEvent(ev ApprovalEvent) {
+	approvalEventMu.Lock()
+	defer approvalEventMu.Unlock()
+	for queue := range approvalEventQueues {
+		select {
+		case queue <- ev:
+		default:
+			log.SyslogWarning("approval event subscriber is too slow, drop event", "state", string(ev.State))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package validator
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestApprovalEvents(t *testing.T) {
+	defer newTestDB(t)()
+
+	events := make(chan ApprovalEvent, 8)
+	sub := SubscribeApprovalEvents(events)
+	defer sub.Unsubscribe()
+
+	approved := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("approved")}
+	rejected := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("rejected")}
+	timeout := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("timeout")}
+	for _, data := range []*mpcprotocol.SendData{&approved, &rejected, &timeout} {
+		if err := AddApprovingData(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// adding the data again posts nothing
+	AddApprovingData(&approved)
+
+	ApproveData([]mpcprotocol.SendData{approved})
+	RejectData([]mpcprotocol.SendData{rejected}, "too much")
+	// the wait of a context times out, the data is not expired until it is purged
+	ValidateData(&timeout, time.Millisecond)
+
+	want := []ApprovalEvent{
+		{State: ApprovalPending, Data: approved},
+		{State: ApprovalPending, Data: rejected},
+		{State: ApprovalPending, Data: timeout},
+		{State: ApprovalApproved, Data: approved},
+		{State: ApprovalRejected, Data: rejected, Reason: "too much"},
+		{State: ApprovalTimeout, Data: timeout, Reason: mpcprotocol.ErrWaitApproved.Error()},
+	}
+	for i, w := range want {
+		select {
+		case ev := <-events:
+			if ev.State != w.State || string(ev.Data.Data) != string(w.Data.Data) || ev.Reason != w.Reason {
+				t.Fatalf("event %d: got %+v, want %+v", i, ev, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d is not posted", i)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestApprovalEventsSlowSubscriber(t *testing.T) {
+	defer newTestDB(t)()
+
+	// a subscriber which never reads does not block the state transitions
+	stalled := make(chan ApprovalEvent)
+	defer SubscribeApprovalEvents(stalled).Unsubscribe()
+
+	events := make(chan ApprovalEvent)
+	defer SubscribeApprovalEvents(events).Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < ApprovalEventQueueSize+8; i++ {
+			data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte(fmt.Sprintf("slow-%d", i))}
+			if err := AddApprovingData(&data); err != nil {
+				t.Error(err)
+				return
+			}
+
+			// the subscriber which reads gets every event
+			select {
+			case ev := <-events:
+				if string(ev.Data.Data) != string(data.Data) {
+					t.Errorf("event %d: got %q, want %q", i, ev.Data.Data, data.Data)
+					return
+				}
+			case <-time.After(time.Second):
+				t.Errorf("event %d is not posted", i)
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("store is blocked by a stalled subscriber")
+	}
+}
+
+// hasCountingDB counts the lookups of the keys, so a test can tell a waiter polling the db.
+type hasCountingDB struct {
+	Database
+	has int32
+}
+
+func (db *hasCountingDB) Has(key []byte) (bool, error) {
+	atomic.AddInt32(&db.has, 1)
+	return db.Database.Has(key)
+}
+
+func TestWaitKeyNotified(t *testing.T) {
+	defer newTestDB(t)()
+	db := &hasCountingDB{Database: dbInstance}
+	dbInstance = db
+	defer func() { dbInstance = db.Database }()
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("notified")}
+	if err := AddApprovingData(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	validated := make(chan error, 1)
+	go func() {
+		_, err := ValidateData(&data, 10*time.Second)
+		validated <- err
+	}()
+
+	// the waiter looks up the keys once, then sleeps until one of them is written
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		waitersMu.Lock()
+		waiting := len(waiters) != 0
+		waitersMu.Unlock()
+		if waiting {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("waiter is not registered")
+		}
+	}
+	lookups := atomic.LoadInt32(&db.has)
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt32(&db.has) - lookups; n != 0 {
+		t.Fatalf("waiter polls the db, %d lookups while waiting", n)
+	}
+
+	ApproveData([]mpcprotocol.SendData{data})
+	select {
+	case err := <-validated:
+		if err != nil {
+			t.Fatalf("data is not validated: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter is not woken by the approval")
+	}
+
+	waitersMu.Lock()
+	defer waitersMu.Unlock()
+	if len(waiters) != 0 {
+		t.Fatalf("waiters are leaked: %d", len(waiters))
+	}
+}
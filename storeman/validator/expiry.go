@@ -0,0 +1,299 @@
+package validator
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	DefaultApprovingTTL = 24 * time.Hour // how long a data waits for approval
+	DefaultApprovedTTL  = time.Hour      // how long an approval can be used to sign
+	DefaultRejectedTTL  = 24 * time.Hour // how long a rejected data stays rejected
+	DefaultCompactCycle = 10 * time.Minute
+
+	expiryPrefix = "MpcExpiry" // key: expiryPrefix || record key, value: json of expiryEntry
+)
+
+// Retention configures the lifetime of the approving, approved and rejected records, zero uses the default.
+type Retention struct {
+	ApprovingTTL time.Duration
+	ApprovedTTL  time.Duration
+	RejectedTTL  time.Duration
+}
+
+// CompactStats counts the records purged by the compaction since the node started.
+type CompactStats struct {
+	LastRun          int64  `json:"lastRun"`
+	ExpiredApproving uint64 `json:"expiredApproving"`
+	ExpiredApproved  uint64 `json:"expiredApproved"`
+	ExpiredRejected  uint64 `json:"expiredRejected"`
+	Consumed         uint64 `json:"consumed"`
+}
+
+// expiryEntry tracks the lifetime of an approving, approved or rejected record.
+type expiryEntry struct {
+	Status   string               `json:"status"`
+	Time     int64                `json:"time"` // unix time the record is written
+	Expiry   int64                `json:"expiry"`
+	Consumed bool                 `json:"consumed,omitempty"`
+	Claimed  bool                 `json:"claimed,omitempty"` // the approval is used by a running context
+	Data     mpcprotocol.SendData `json:"data"`
+}
+
+var (
+	retentionMu  sync.Mutex
+	retention    Retention
+	compactStats CompactStats
+)
+
+// SetRetention sets the lifetime of the approving, approved and rejected records.
+func SetRetention(r Retention) error {
+	if r.ApprovingTTL < 0 || r.ApprovedTTL < 0 || r.RejectedTTL < 0 {
+		return mpcprotocol.ErrInvalidRetention
+	}
+
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	retention = r
+	return nil
+}
+
+func recordTTL(status string) time.Duration {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
+	switch status {
+	case mpcprotocol.MpcApproving:
+		if retention.ApprovingTTL > 0 {
+			return retention.ApprovingTTL
+		}
+		return DefaultApprovingTTL
+	case mpcprotocol.MpcApproved:
+		if retention.ApprovedTTL > 0 {
+			return retention.ApprovedTTL
+		}
+		return DefaultApprovedTTL
+	default:
+		if retention.RejectedTTL > 0 {
+			return retention.RejectedTTL
+		}
+		return DefaultRejectedTTL
+	}
+}
+
+func expiryKey(recordKey []byte) []byte {
+	return append([]byte(expiryPrefix), recordKey...)
+}
+
+func getExpiry(sdb Database, recordKey []byte) (*expiryEntry, error) {
+	key := expiryKey(recordKey)
+	exist, err := sdb.Has(key)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	value, err := sdb.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry expiryEntry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// approvalExpired returns true if the approval of the data has expired or has been used to sign.
+func approvalExpired(sdb Database, approvedKey []byte, now time.Time) bool {
+	entry, err := getExpiry(sdb, approvedKey)
+	if err != nil || entry == nil {
+		// the approvals made before the expiry was tracked stay valid
+		return false
+	}
+
+	return entry.Consumed || entry.Expiry <= now.Unix()
+}
+
+// claimApproval marks the approval of the data as used by a context. The caller must hold storeMu.
+func claimApproval(sdb Database, data *mpcprotocol.SendData, approvedKey []byte, now time.Time) error {
+	entry, err := getExpiry(sdb, approvedKey)
+	if err != nil {
+		return err
+	}
+
+	if entry == nil {
+		// an approval made before the expiry was tracked
+		entry = &expiryEntry{Status: mpcprotocol.MpcApproved,
+			Time:   now.Unix(),
+			Expiry: now.Add(recordTTL(mpcprotocol.MpcApproved)).Unix(),
+			Data:   *data}
+	}
+
+	if entry.Claimed {
+		return mpcprotocol.ErrApprovalInUse
+	}
+
+	entry.Claimed = true
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return sdb.Put(expiryKey(approvedKey), value)
+}
+
+// ReleaseApprovedData returns the approval claimed by ValidateData when the context signing the data fails,
+// so the data can be signed by the approval again until it expires.
+func ReleaseApprovedData(data *mpcprotocol.SendData) error {
+	sdb, err := GetDB()
+	if err != nil {
+		return mpcprotocol.ErrGetDb
+	}
+
+	storeMu.Lock()
+	defer unlockStore()
+
+	approvedKey := buildKeyFromData(data, mpcprotocol.MpcApproved)
+	entry, err := getExpiry(sdb, approvedKey)
+	if err != nil || entry == nil || entry.Consumed || !entry.Claimed {
+		return err
+	}
+
+	entry.Claimed = false
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	log.SyslogInfo("ReleaseApprovedData", "data", data.String())
+	return sdb.Put(expiryKey(approvedKey), value)
+}
+
+// ConsumeApprovedData invalidates the approval of the data once it is signed, so the data can not be signed
+// again by the same approval. The compaction purges the consumed record.
+func ConsumeApprovedData(data *mpcprotocol.SendData) error {
+	sdb, err := GetDB()
+	if err != nil {
+		return mpcprotocol.ErrGetDb
+	}
+
+	storeMu.Lock()
+	defer unlockStore()
+
+	now := time.Now()
+	entry := expiryEntry{Status: mpcprotocol.MpcApproved, Time: now.Unix(), Expiry: now.Unix(), Consumed: true, Data: *data}
+	value, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+
+	b := sdb.NewBatch()
+	if err := deleteRecord(sdb, b, data, mpcprotocol.MpcApproved); err != nil {
+		log.SyslogErr("ConsumeApprovedData, delete approved data fail", "err", err.Error())
+		return err
+	}
+	b.Put(expiryKey(buildKeyFromData(data, mpcprotocol.MpcApproved)), value)
+
+	log.SyslogInfo("ConsumeApprovedData", "data", data.String())
+	return b.Write()
+}
+
+// Compact purges the expired and consumed records, and returns the counts purged so far.
+func Compact(now time.Time) (CompactStats, error) {
+	sdb, err := GetDB()
+	if err != nil {
+		return GetCompactStats(), mpcprotocol.ErrGetDb
+	}
+
+	storeMu.Lock()
+	defer unlockStore()
+
+	var purged []expiryEntry
+	var keys [][]byte
+	err = sdb.Iterate([]byte(expiryPrefix), func(key, value []byte) bool {
+		var entry expiryEntry
+		if json.Unmarshal(value, &entry) != nil || entry.Consumed || entry.Expiry <= now.Unix() {
+			purged = append(purged, entry)
+			keys = append(keys, common.CopyBytes(key))
+		}
+		return true
+	})
+	if err != nil {
+		log.SyslogErr("Compact, iterate expiry records fail", "err", err.Error())
+		return GetCompactStats(), err
+	}
+
+	var stats CompactStats
+	for i, entry := range purged {
+		if err := purgeRecord(sdb, keys[i], &entry); err != nil {
+			log.SyslogErr("Compact, purge record fail", "status", entry.Status, "err", err.Error())
+			continue
+		}
+
+		switch {
+		case entry.Consumed:
+			stats.Consumed++
+		case entry.Status == mpcprotocol.MpcApproving:
+			stats.ExpiredApproving++
+		case entry.Status == mpcprotocol.MpcApproved:
+			stats.ExpiredApproved++
+		default:
+			stats.ExpiredRejected++
+		}
+	}
+
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	compactStats.LastRun = now.Unix()
+	compactStats.ExpiredApproving += stats.ExpiredApproving
+	compactStats.ExpiredApproved += stats.ExpiredApproved
+	compactStats.ExpiredRejected += stats.ExpiredRejected
+	compactStats.Consumed += stats.Consumed
+	log.SyslogInfo("Compact approval records",
+		"expiredApproving", stats.ExpiredApproving,
+		"expiredApproved", stats.ExpiredApproved,
+		"expiredRejected", stats.ExpiredRejected,
+		"consumed", stats.Consumed)
+
+	return compactStats, nil
+}
+
+func purgeRecord(sdb Database, key []byte, entry *expiryEntry) error {
+	if entry.Status == "" || entry.Consumed {
+		// undecodable entry, or the record is deleted when it is consumed
+		return sdb.Delete(key)
+	}
+
+	b := sdb.NewBatch()
+	if err := deleteRecord(sdb, b, &entry.Data, entry.Status); err != nil {
+		return err
+	}
+
+	if entry.Status == mpcprotocol.MpcApproving {
+		b.Delete(buildKeyFromData(&entry.Data, mpcprotocol.MpcApprovals))
+	}
+
+	if err := b.Write(); err != nil {
+		return err
+	}
+
+	if entry.Status == mpcprotocol.MpcApproving {
+		queueApprovalEvent(ApprovalExpired, &entry.Data, mpcprotocol.ErrApprovalExpired.Error())
+	}
+
+	return nil
+}
+
+// GetCompactStats returns the counts of the records purged since the node started.
+func GetCompactStats() CompactStats {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+	return compactStats
+}
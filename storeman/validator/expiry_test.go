@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"testing"
+	"time"
+
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestConsumeApprovedData(t *testing.T) {
+	defer newTestDB(t)()
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("consumed")}
+	if err := AddValidData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := ValidateData(&data, time.Second); !ok {
+		t.Fatalf("valid data is not validated: %v", err)
+	}
+
+	if err := ConsumeApprovedData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := ValidateData(&data, 10*time.Millisecond); ok {
+		t.Fatal("consumed approval is used again")
+	}
+
+	before := GetCompactStats()
+	stats, err := Compact(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Consumed != before.Consumed+1 {
+		t.Fatalf("consumed record is not purged: %+v", stats)
+	}
+}
+
+func TestClaimApproval(t *testing.T) {
+	defer newTestDB(t)()
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("claimed")}
+	if err := AddValidData(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent contexts of the same data, only one claims the approval
+	results := make(chan error, 4)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			_, err := ValidateData(&data, time.Second)
+			results <- err
+		}()
+	}
+	claimed := 0
+	for i := 0; i < cap(results); i++ {
+		switch err := <-results; err {
+		case nil:
+			claimed++
+		case mpcprotocol.ErrApprovalInUse:
+		default:
+			t.Fatalf("claim of the approval in use: %v", err)
+		}
+	}
+	if claimed != 1 {
+		t.Fatalf("approval is claimed by %d contexts", claimed)
+	}
+
+	// the context fails, the approval is used by the next context
+	if err := ReleaseApprovedData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := ValidateData(&data, time.Second); !ok {
+		t.Fatalf("released approval is not claimed again: %v", err)
+	}
+
+	if err := ConsumeApprovedData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReleaseApprovedData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := ValidateData(&data, 10*time.Millisecond); ok {
+		t.Fatal("consumed approval is released")
+	}
+}
+
+func TestApprovalExpiry(t *testing.T) {
+	defer newTestDB(t)()
+	defer SetRetention(Retention{})
+
+	if err := SetRetention(Retention{ApprovedTTL: time.Nanosecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	approved := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("approved")}
+	if err := AddValidData(&approved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateData(&approved, time.Second); err != mpcprotocol.ErrApprovalExpired {
+		t.Fatalf("expired approval is used: %v", err)
+	}
+
+	approving := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("approving")}
+	rejected := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("rejected")}
+	for _, data := range []*mpcprotocol.SendData{&approving, &rejected} {
+		if err := AddApprovingData(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	RejectData([]mpcprotocol.SendData{rejected}, "no")
+
+	events := make(chan ApprovalEvent, 4)
+	sub := SubscribeApprovalEvents(events)
+	defer sub.Unsubscribe()
+
+	before := GetCompactStats()
+	stats, err := Compact(time.Now().Add(DefaultApprovingTTL + time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ExpiredApproved != before.ExpiredApproved+1 ||
+		stats.ExpiredApproving != before.ExpiredApproving+1 ||
+		stats.ExpiredRejected != before.ExpiredRejected+1 {
+		t.Fatalf("expired records are not purged: %+v", stats)
+	}
+
+	if pending, _ := GetDataForApprove(); len(pending) != 0 {
+		t.Fatalf("expired data is still pending: %v", pending)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.State != ApprovalExpired || string(ev.Data.Data) != "approving" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expired event is not posted")
+	}
+
+	sdb, _ := GetDB()
+	for _, key := range [][]byte{
+		buildKeyFromData(&approved, mpcprotocol.MpcApproved),
+		buildKeyFromData(&approving, mpcprotocol.MpcApproving),
+		buildKeyFromData(&rejected, mpcprotocol.MpcRejected),
+	} {
+		if exist, _ := sdb.Has(key); exist {
+			t.Fatal("expired record is not deleted")
+		}
+	}
+}
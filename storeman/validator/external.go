@@ -0,0 +1,255 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	DefaultExternalTimeout = 10 * time.Second
+	externalRetryDelay     = 500 * time.Millisecond
+	maxExternalResponse    = 1024 * 1024
+)
+
+// ExternalConfig configures the local service which approves or rejects the data requested to be signed.
+// The request is posted as json to URL, or sent as the only param of the json-rpc Method if Method is set.
+type ExternalConfig struct {
+	URL        string
+	Method     string
+	Timeout    time.Duration // timeout of every attempt, zero: DefaultExternalTimeout
+	Retries    int           // attempts after the first one failed to get an answer
+	CACert     string        // pem file of the CA which signed the service certificate
+	ClientCert string        // pem file of the certificate presented to the service
+	ClientKey  string        // pem file of the key of ClientCert
+}
+
+// ContextSummary describes the mpc context which requests the data to be signed.
+type ContextSummary struct {
+	ContextID uint64   `json:"contextId"`
+	Protocol  string   `json:"protocol"`
+	Leader    string   `json:"leader"`
+	Peers     []string `json:"peers"`
+	ByApprove bool     `json:"byApprove"`
+	Deadline  int64    `json:"deadline,omitempty"` // approval deadline in unix time
+}
+
+// ExternalRequest is sent to the external validator.
+type ExternalRequest struct {
+	Data    mpcprotocol.SendData `json:"data"`
+	Context ContextSummary       `json:"context"`
+}
+
+// ExternalResponse is the answer of the external validator.
+type ExternalResponse struct {
+	Approve bool   `json:"approve"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ExternalValidator asks a local service whether the data may be signed.
+type ExternalValidator struct {
+	cfg    ExternalConfig
+	client *http.Client
+	id     uint64 // id of the last json-rpc request
+}
+
+var (
+	externalMu sync.RWMutex
+	external   *ExternalValidator
+)
+
+// NewExternalValidator creates the client of the service, with mutual tls if the certificates are configured.
+func NewExternalValidator(cfg ExternalConfig) (*ExternalValidator, error) {
+	if cfg.URL == "" || cfg.Retries < 0 || cfg.Timeout < 0 || (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, mpcprotocol.ErrInvalidExternal
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultExternalTimeout
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.CACert != "" {
+		pem, err := ioutil.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%v: no certificate in %s", mpcprotocol.ErrInvalidExternal, cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	client := &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+
+	return &ExternalValidator{cfg: cfg, client: client}, nil
+}
+
+// Validate asks the service about the data, and retries if the service does not answer.
+func (v *ExternalValidator) Validate(req *ExternalRequest) (*ExternalResponse, error) {
+	var err error
+	for attempt := 0; attempt <= v.cfg.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * externalRetryDelay)
+		}
+
+		var resp *ExternalResponse
+		resp, err = v.call(req)
+		if err == nil {
+			return resp, nil
+		}
+
+		log.SyslogWarning("external validator call fail", "url", v.cfg.URL, "attempt", attempt, "err", err.Error())
+	}
+
+	return nil, fmt.Errorf("%v: %v", mpcprotocol.ErrExternalValidator, err)
+}
+
+type jsonrpcRequest struct {
+	Version string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type jsonrpcResponse struct {
+	Result *ExternalResponse `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (v *ExternalValidator) call(req *ExternalRequest) (*ExternalResponse, error) {
+	var body interface{} = req
+	if v.cfg.Method != "" {
+		body = &jsonrpcRequest{Version: "2.0", ID: atomic.AddUint64(&v.id, 1), Method: v.cfg.Method, Params: []interface{}{req}}
+	}
+
+	content, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.cfg.Timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequest("POST", v.cfg.URL, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := v.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxExternalResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(respBody) > maxExternalResponse {
+		return nil, errors.New("response is too large")
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %s", httpResp.Status)
+	}
+
+	if v.cfg.Method == "" {
+		var resp ExternalResponse
+		if err := json.Unmarshal(respBody, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	var rpcResp jsonrpcResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return nil, err
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("json-rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	if rpcResp.Result == nil {
+		return nil, errors.New("json-rpc response without result")
+	}
+
+	return rpcResp.Result, nil
+}
+
+// SetExternalValidator sets the service which validates the data, nil removes it.
+func SetExternalValidator(v *ExternalValidator) {
+	externalMu.Lock()
+	defer externalMu.Unlock()
+	external = v
+}
+
+// ValidateExternal asks the external validator about the data. It returns true if the service approves the
+// data, false if no service is configured, and an error carrying the reason if the service rejects the data
+// or can not be reached.
+func ValidateExternal(data *mpcprotocol.SendData, summary *ContextSummary) (bool, error) {
+	externalMu.RLock()
+	v := external
+	externalMu.RUnlock()
+	if v == nil {
+		return false, nil
+	}
+
+	resp, err := v.Validate(&ExternalRequest{Data: *data, Context: *summary})
+	if err != nil {
+		log.SyslogErr("ValidateExternal fail", "ctxId", summary.ContextID, "err", err.Error())
+		return false, err
+	}
+
+	log.SyslogInfo("ValidateExternal",
+		"ctxId", summary.ContextID,
+		"pk", hexutil.Encode(data.PKBytes),
+		"data", hexutil.Encode(data.Data),
+		"approve", resp.Approve,
+		"reason", resp.Reason)
+
+	if !resp.Approve {
+		if resp.Reason == "" {
+			return false, mpcprotocol.ErrExternalRejected
+		}
+		return false, fmt.Errorf("%v: %s", mpcprotocol.ErrExternalRejected, resp.Reason)
+	}
+
+	return true, nil
+}
+
+// ApproveByExternal adds the data approved by the external validator to the approved db.
+func ApproveByExternal(data *mpcprotocol.SendData) error {
+	return addValidDataOnce(data)
+}
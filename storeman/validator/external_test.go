@@ -0,0 +1,237 @@
+package validator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/rpc"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+var externalSummary = &ContextSummary{ContextID: 7, Protocol: "sign", Leader: "leader", Peers: []string{"a", "b"}}
+
+func TestValidateExternalHTTP(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req ExternalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if req.Context.ContextID != externalSummary.ContextID || len(req.Context.Peers) != 2 {
+			t.Errorf("context summary is not sent: %+v", req.Context)
+		}
+
+		resp := ExternalResponse{Approve: req.Data.Extern != "evil"}
+		if !resp.Approve {
+			resp.Reason = "evil extern"
+		}
+		json.NewEncoder(w).Encode(&resp)
+	}))
+	defer server.Close()
+
+	if ok, err := ValidateExternal(&mpcprotocol.SendData{}, externalSummary); ok || err != nil {
+		t.Fatalf("data validated without external validator: %v %v", ok, err)
+	}
+
+	if _, err := NewExternalValidator(ExternalConfig{}); err != mpcprotocol.ErrInvalidExternal {
+		t.Fatalf("empty url is accepted: %v", err)
+	}
+
+	v, err := NewExternalValidator(ExternalConfig{URL: server.URL, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExternalValidator(v)
+	defer SetExternalValidator(nil)
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("wanchain"), Extern: "cross"}
+	if ok, err := ValidateExternal(&data, externalSummary); !ok || err != nil {
+		t.Fatalf("data is not approved after a retry: %v %v", ok, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls: %d, want 2", n)
+	}
+
+	data.Extern = "evil"
+	ok, err := ValidateExternal(&data, externalSummary)
+	if ok || err == nil || !strings.Contains(err.Error(), "evil extern") {
+		t.Fatalf("rejection reason is lost: %v %v", ok, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("rejected data is retried, calls: %d", n)
+	}
+}
+
+func TestValidateExternalUnavailable(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	v, err := NewExternalValidator(ExternalConfig{URL: server.URL, Timeout: 50 * time.Millisecond, Retries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExternalValidator(v)
+	defer SetExternalValidator(nil)
+
+	ok, err := ValidateExternal(&mpcprotocol.SendData{}, externalSummary)
+	if ok || err == nil || !strings.Contains(err.Error(), mpcprotocol.ErrExternalValidator.Error()) {
+		t.Fatalf("unavailable validator approves data: %v %v", ok, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("calls: %d, want 2", n)
+	}
+}
+
+type MockValidatorService struct{}
+
+func (s *MockValidatorService) Validate(req ExternalRequest) ExternalResponse {
+	if req.Context.Protocol != "sign" {
+		return ExternalResponse{Reason: "unknown protocol"}
+	}
+	return ExternalResponse{Approve: true}
+}
+
+func TestValidateExternalJSONRPC(t *testing.T) {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("mock", new(MockValidatorService)); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(rpcServer)
+	defer server.Close()
+
+	v, err := NewExternalValidator(ExternalConfig{URL: server.URL, Method: "mock_validate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := v.Validate(&ExternalRequest{Context: *externalSummary})
+	if err != nil || !resp.Approve {
+		t.Fatalf("json-rpc approval: %+v %v", resp, err)
+	}
+
+	resp, err = v.Validate(&ExternalRequest{Context: ContextSummary{Protocol: "reshare"}})
+	if err != nil || resp.Approve || resp.Reason != "unknown protocol" {
+		t.Fatalf("json-rpc rejection: %+v %v", resp, err)
+	}
+
+	v.cfg.Method = "mock_missing"
+	if _, err := v.Validate(&ExternalRequest{}); err == nil {
+		t.Fatal("json-rpc error is ignored")
+	}
+}
+
+func TestValidateExternalMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "external-validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCert, caKey := newTestCert(t, nil, nil, "ca")
+	serverCert, serverKey := newTestCert(t, caCert, caKey, "server")
+	clientCert, clientKey := newTestCert(t, caCert, caKey, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&ExternalResponse{Approve: true})
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := writeTestPEM(t, dir, "ca.pem", "CERTIFICATE", caCert.Raw)
+	certFile := writeTestPEM(t, dir, "client.pem", "CERTIFICATE", clientCert.Raw)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := writeTestPEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+
+	v, err := NewExternalValidator(ExternalConfig{URL: server.URL, CACert: caFile, ClientCert: certFile, ClientKey: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := v.Validate(&ExternalRequest{}); err != nil || !resp.Approve {
+		t.Fatalf("mutual tls approval: %+v %v", resp, err)
+	}
+
+	v, err = NewExternalValidator(ExternalConfig{URL: server.URL, CACert: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Validate(&ExternalRequest{}); err == nil {
+		t.Fatal("service is reached without client certificate")
+	}
+}
+
+func newTestCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func writeTestPEM(t *testing.T, dir, name, typ string, der []byte) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,229 @@
+package validator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/common/math"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	SignWindow  = time.Hour      // rolling window of the signature count
+	ValueWindow = 24 * time.Hour // rolling window of the signed value
+
+	// key: limitPrefix || hash(gpk) || time || hash(data), value: the signed value
+	limitPrefix = "MpcLimit"
+)
+
+// Limit bounds the signatures of a gpk in the rolling windows, zero is unlimited.
+type Limit struct {
+	SignsPerHour uint64                `json:"signsPerHour,omitempty"`
+	ValuePerDay  *math.HexOrDecimal256 `json:"valuePerDay,omitempty"`
+}
+
+// LimitConfig is the limit of every gpk, unless the gpk has its own limit keyed by its hex.
+type LimitConfig struct {
+	Default Limit
+	GPKs    map[string]Limit
+}
+
+// LimitUsage is what a gpk has signed in the rolling windows.
+type LimitUsage struct {
+	GPK          hexutil.Bytes `json:"gpk"`
+	SignsInHour  uint64        `json:"signsInHour"`
+	ValueInDay   *hexutil.Big  `json:"valueInDay"`
+	SignsPerHour uint64        `json:"signsPerHour,omitempty"`
+	ValuePerDay  *hexutil.Big  `json:"valuePerDay,omitempty"`
+}
+
+var (
+	limitMu sync.Mutex // serializes the check and the record of the signatures
+	limits  LimitConfig
+)
+
+// SetLimits sets the limits of the gpks.
+func SetLimits(cfg LimitConfig) error {
+	gpks := make(map[string]Limit, len(cfg.GPKs))
+	for gpk, limit := range cfg.GPKs {
+		pk, err := hexutil.Decode(gpk)
+		if err != nil || !validLimit(limit) {
+			return fmt.Errorf("%v: gpk %s", mpcprotocol.ErrInvalidLimit, gpk)
+		}
+		gpks[hexutil.Encode(pk)] = limit
+	}
+
+	if !validLimit(cfg.Default) {
+		return mpcprotocol.ErrInvalidLimit
+	}
+
+	limitMu.Lock()
+	defer limitMu.Unlock()
+	limits = LimitConfig{Default: cfg.Default, GPKs: gpks}
+	return nil
+}
+
+func validLimit(limit Limit) bool {
+	return limit.ValuePerDay == nil || (*big.Int)(limit.ValuePerDay).Sign() >= 0
+}
+
+func limitOf(gpk []byte) Limit {
+	if limit, exist := limits.GPKs[hexutil.Encode(gpk)]; exist {
+		return limit
+	}
+	return limits.Default
+}
+
+func limitGPKPrefix(gpk []byte) []byte {
+	return append([]byte(limitPrefix), crypto.Keccak256(gpk)...)
+}
+
+// dataValue returns the value moved by the data, decoded from the payload or claimed by the extern, and
+// false if the value is unknown. A negative value is unknown, it would offset the value signed in the window.
+func dataValue(data *mpcprotocol.SendData) (*big.Int, bool) {
+	if decoded, exist := DecodePayload(data)[TxFieldValue]; exist {
+		if value, ok := math.ParseBig256(decoded); ok && value.Sign() >= 0 {
+			return value, true
+		}
+	}
+
+	if claimed, exist := parseExtern(data.Extern)[TxFieldValue]; exist {
+		if value, ok := math.ParseBig256(fmt.Sprint(claimed)); ok && value.Sign() >= 0 {
+			return value, true
+		}
+	}
+
+	return new(big.Int), false
+}
+
+// usage sums the signatures of the gpk in the windows, and returns the keys of the records out of the windows.
+func usage(sdb Database, gpk []byte, now time.Time) (uint64, *big.Int, [][]byte, error) {
+	prefix := limitGPKPrefix(gpk)
+	signSince, valueSince := now.Add(-SignWindow).UnixNano(), now.Add(-ValueWindow).UnixNano()
+
+	var signs uint64
+	value := new(big.Int)
+	var stale [][]byte
+	err := sdb.Iterate(prefix, func(key, v []byte) bool {
+		if len(key) < len(prefix)+8 {
+			return true
+		}
+
+		t := int64(binary.BigEndian.Uint64(key[len(prefix):]))
+		if t <= valueSince {
+			stale = append(stale, common.CopyBytes(key))
+			return true
+		}
+
+		value.Add(value, new(big.Int).SetBytes(v))
+		if t > signSince {
+			signs++
+		}
+		return true
+	})
+
+	return signs, value, stale, err
+}
+
+// ReserveLimit records the signature of the data against the limits of its gpk, or refuses the data with
+// ErrLimitExceeded if the signature would exceed them, or with ErrUnknownValue if the gpk has a value limit
+// and the value of the data is unknown. The signature counts once the storeman agrees to sign, it returns
+// the reservation which is released by ReleaseLimit if the data is not signed.
+func ReserveLimit(data *mpcprotocol.SendData) ([]byte, error) {
+	sdb, err := GetDB()
+	if err != nil {
+		return nil, mpcprotocol.ErrGetDb
+	}
+
+	limitMu.Lock()
+	defer limitMu.Unlock()
+
+	now := time.Now()
+	signs, spent, stale, err := usage(sdb, data.PKBytes, now)
+	if err != nil {
+		log.SyslogErr("ReserveLimit, sum usage fail", "err", err.Error())
+		return nil, err
+	}
+
+	value, known := dataValue(data)
+	limit := limitOf(data.PKBytes)
+	if limit.SignsPerHour != 0 && signs+1 > limit.SignsPerHour {
+		log.SyslogErr("ReserveLimit, signatures per hour exceeded", "pk", hexutil.Encode(data.PKBytes), "signs", signs)
+		return nil, fmt.Errorf("%v: %d signatures per hour", mpcprotocol.ErrLimitExceeded, limit.SignsPerHour)
+	}
+
+	maxValue := (*big.Int)(limit.ValuePerDay)
+	if maxValue != nil && maxValue.Sign() > 0 && !known {
+		// a data of unknown value could move anything, so it is not signed under a value limit
+		log.SyslogErr("ReserveLimit, value of the data is unknown", "pk", hexutil.Encode(data.PKBytes))
+		return nil, mpcprotocol.ErrUnknownValue
+	}
+
+	if maxValue != nil && maxValue.Sign() > 0 && new(big.Int).Add(spent, value).Cmp(maxValue) > 0 {
+		log.SyslogErr("ReserveLimit, value per day exceeded",
+			"pk", hexutil.Encode(data.PKBytes),
+			"spent", spent.String(),
+			"value", value.String())
+		return nil, fmt.Errorf("%v: value %s per day", mpcprotocol.ErrLimitExceeded, maxValue.String())
+	}
+
+	b := sdb.NewBatch()
+	for _, key := range stale {
+		b.Delete(key)
+	}
+
+	key := append(limitGPKPrefix(data.PKBytes), timeBytes(now.UnixNano())...)
+	key = append(key, buildKeyFromData(data, "")[:8]...)
+	b.Put(key, value.Bytes())
+
+	log.SyslogInfo("ReserveLimit", "pk", hexutil.Encode(data.PKBytes), "signs", signs+1, "value", value.String())
+	if err := b.Write(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ReleaseLimit removes the reservation of a data which is not signed, so it does not count against the limits.
+func ReleaseLimit(reservation []byte) error {
+	sdb, err := GetDB()
+	if err != nil {
+		return mpcprotocol.ErrGetDb
+	}
+
+	limitMu.Lock()
+	defer limitMu.Unlock()
+
+	log.SyslogInfo("ReleaseLimit", "reservation", hexutil.Encode(reservation))
+	return sdb.Delete(reservation)
+}
+
+// GetLimitUsage returns what the gpk has signed in the rolling windows, with its limits.
+func GetLimitUsage(gpk []byte) (*LimitUsage, error) {
+	sdb, err := GetDB()
+	if err != nil {
+		return nil, mpcprotocol.ErrGetDb
+	}
+
+	limitMu.Lock()
+	defer limitMu.Unlock()
+
+	signs, spent, _, err := usage(sdb, gpk, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := limitOf(gpk)
+	ret := &LimitUsage{GPK: gpk, SignsInHour: signs, ValueInDay: (*hexutil.Big)(spent), SignsPerHour: limit.SignsPerHour}
+	if limit.ValuePerDay != nil {
+		ret.ValuePerDay = (*hexutil.Big)(limit.ValuePerDay)
+	}
+
+	return ret, nil
+}
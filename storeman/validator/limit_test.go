@@ -0,0 +1,124 @@
+package validator
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/common/math"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestReserveLimit(t *testing.T) {
+	defer newTestDB(t)()
+
+	if err := SetLimits(LimitConfig{GPKs: map[string]Limit{"gpk": {}}}); err == nil {
+		t.Fatal("invalid gpk is accepted")
+	}
+
+	err := SetLimits(LimitConfig{
+		Default: Limit{SignsPerHour: 2},
+		GPKs:    map[string]Limit{"0x02": {ValuePerDay: (*math.HexOrDecimal256)(big.NewInt(100))}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetLimits(LimitConfig{})
+
+	data := mpcprotocol.SendData{PKBytes: []byte{1}, Extern: "cross"}
+	for i := 0; i < 3; i++ {
+		data.Data = []byte{byte(i)}
+		_, err := ReserveLimit(&data)
+		if i < 2 && err != nil {
+			t.Fatalf("signature %d is refused: %v", i, err)
+		}
+		if i == 2 && (err == nil || !strings.Contains(err.Error(), mpcprotocol.ErrLimitExceeded.Error())) {
+			t.Fatalf("signature over the hourly limit: %v", err)
+		}
+	}
+
+	// the value is claimed by the extern, or decoded from the transaction
+	valued := mpcprotocol.SendData{PKBytes: []byte{2}, Data: []byte("a"), Extern: `{"value":60}`}
+	if _, err := ReserveLimit(&valued); err != nil {
+		t.Fatal(err)
+	}
+	valued.Data = []byte("b")
+	if _, err := ReserveLimit(&valued); err == nil {
+		t.Fatal("value over the daily limit is accepted")
+	}
+	valued.Extern = `{"value":"0x28"}`
+	if _, err := ReserveLimit(&valued); err != nil {
+		t.Fatalf("value up to the daily limit is refused: %v", err)
+	}
+
+	valued.Data, valued.Extern = []byte("c"), "cross"
+	if _, err := ReserveLimit(&valued); err != mpcprotocol.ErrUnknownValue {
+		t.Fatalf("data of unknown value under a value limit: %v", err)
+	}
+	valued.Extern = `{"value":-50}`
+	if _, err := ReserveLimit(&valued); err != mpcprotocol.ErrUnknownValue {
+		t.Fatalf("data of negative value under a value limit: %v", err)
+	}
+
+	valued.Data, valued.Extern = hexutil.MustDecode(eip155Tx), ""
+	if _, err := ReserveLimit(&valued); err == nil {
+		t.Fatal("decoded transaction value over the daily limit is accepted")
+	}
+
+	usage, err := GetLimitUsage([]byte{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.SignsInHour != 2 || usage.ValueInDay.ToInt().Int64() != 100 || usage.ValuePerDay.ToInt().Int64() != 100 {
+		t.Fatalf("usage: %+v", usage)
+	}
+}
+
+func TestLimitWindow(t *testing.T) {
+	defer newTestDB(t)()
+
+	if err := SetLimits(LimitConfig{Default: Limit{SignsPerHour: 1, ValuePerDay: (*math.HexOrDecimal256)(big.NewInt(10))}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLimits(LimitConfig{})
+
+	sdb, _ := GetDB()
+	gpk := []byte{1}
+	old := func(age time.Duration, value int64) {
+		key := append(limitGPKPrefix(gpk), timeBytes(time.Now().Add(-age).UnixNano())...)
+		if err := sdb.Put(key, big.NewInt(value).Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// signed more than an hour ago, counts for the value only
+	old(2*time.Hour, 5)
+	// signed more than a day ago, pruned
+	old(25*time.Hour, 10)
+
+	data := mpcprotocol.SendData{PKBytes: gpk, Data: []byte("a"), Extern: `{"value":5}`}
+	reservation, err := ReserveLimit(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signs, spent, stale, err := usage(sdb, gpk, time.Now())
+	if err != nil || signs != 1 || spent.Int64() != 10 || len(stale) != 0 {
+		t.Fatalf("usage: %d %v %d %v", signs, spent, len(stale), err)
+	}
+
+	data.Data, data.Extern = []byte("b"), `{"value":0}`
+	if _, err := ReserveLimit(&data); err == nil {
+		t.Fatal("signature over the hourly limit is accepted")
+	}
+
+	// the first data is not signed, its reservation is released
+	if err := ReleaseLimit(reservation); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReserveLimit(&data); err != nil {
+		t.Fatalf("released reservation still counts: %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package validator
 
 import (
+	"bytes"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/wanchain/schnorr-mpc/common"
@@ -42,6 +44,69 @@ func (db *memDB) Get(key []byte) ([]byte, error) {
 	return nil, errors.New("not found")
 }
 
+func (db *memDB) Iterate(prefix []byte, fn func(key, value []byte) bool) error {
+	db.lock.RLock()
+	keys := make([]string, 0, len(db.db))
+	for key := range db.db {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+	db.lock.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := db.Get([]byte(key))
+		if err != nil {
+			continue
+		}
+
+		if !fn([]byte(key), value) {
+			break
+		}
+	}
+
+	return nil
+}
+
+type memBatch struct {
+	db     *memDB
+	keys   [][]byte
+	values [][]byte // nil value deletes the key
+}
+
+func (db *memDB) NewBatch() Batch {
+	return &memBatch{db: db}
+}
+
+func (b *memBatch) Put(key, value []byte) {
+	if value == nil {
+		value = []byte{}
+	}
+	b.keys = append(b.keys, common.CopyBytes(key))
+	b.values = append(b.values, common.CopyBytes(value))
+}
+
+func (b *memBatch) Delete(key []byte) {
+	b.keys = append(b.keys, common.CopyBytes(key))
+	b.values = append(b.values, nil)
+}
+
+func (b *memBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	for i, key := range b.keys {
+		if b.values[i] == nil {
+			delete(b.db.db, string(key))
+		} else {
+			b.db.db[string(key)] = b.values[i]
+		}
+	}
+
+	return nil
+}
+
 func (db *memDB) Close() {}
 
 func (db *memDB) Delete(key []byte) error {
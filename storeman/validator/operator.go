@@ -0,0 +1,235 @@
+package validator
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// OperatorApproval is the signature of an operator over the hash of a data waiting for approval.
+type OperatorApproval struct {
+	Operator  common.Address `json:"operator"`
+	Signature hexutil.Bytes  `json:"signature"`
+	Time      int64          `json:"time"`
+}
+
+// PendingApproval is a data waiting for approval with the operators who have approved it so far.
+type PendingApproval struct {
+	Data      mpcprotocol.SendData `json:"data"`
+	Hash      common.Hash          `json:"hash"`
+	Approvals []OperatorApproval   `json:"approvals"`
+	Threshold int                  `json:"threshold"`
+	Payload   map[string]string    `json:"payload,omitempty"` // fields decoded from the data
+}
+
+var (
+	operatorMu        sync.Mutex // guards the operators and the read-modify-write of the approvals
+	operators         map[common.Address]bool
+	operatorThreshold int
+)
+
+// SetOperators registers the operators whose signatures approve the data, the data is approved once
+// threshold distinct operators have signed it. No operators means any approval is accepted.
+func SetOperators(addrs []common.Address, threshold int) error {
+	set := make(map[common.Address]bool)
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+
+	if len(set) != len(addrs) || (len(set) != 0 && (threshold <= 0 || threshold > len(set))) {
+		return mpcprotocol.ErrInvalidOperators
+	}
+
+	operatorMu.Lock()
+	defer operatorMu.Unlock()
+	operators, operatorThreshold = set, threshold
+	return nil
+}
+
+func operatorQuorum() int {
+	operatorMu.Lock()
+	defer operatorMu.Unlock()
+	return operatorThreshold
+}
+
+// ApprovalDomain separates the approval hash from the hashes the operator keys sign for other purposes.
+const ApprovalDomain = "wanchain storeman approval v1"
+
+// ApprovalHash returns the hash of the data signed by the operators, which is
+//
+//	keccak256(rlp([ApprovalDomain, PKBytes, Data, Extern]))
+//
+// where every item of the rlp list is a byte string, so the fields can not be shifted into each other.
+// Operators sign the 32 bytes of the hash with secp256k1, the signature is R || S || V with V in {0, 1}.
+func ApprovalHash(data *mpcprotocol.SendData) common.Hash {
+	return rlpHash([][]byte{[]byte(ApprovalDomain), data.PKBytes, data.Data, []byte(data.Extern)})
+}
+
+// SignApproveData adds the operator signature to the data waiting for approval, and approves the data
+// when the signatures reach the threshold. The signature must be over the approval hash of the record
+// waiting for approval, as a data with another extern shares its key. It returns the number of operators
+// who have approved the data.
+func SignApproveData(data *mpcprotocol.SendData, signature []byte) (int, error) {
+	hash := ApprovalHash(data)
+	pub, err := crypto.SigToPub(hash[:], signature)
+	if err != nil {
+		return 0, mpcprotocol.ErrInvalidMsgSignature
+	}
+	operator := crypto.PubkeyToAddress(*pub)
+
+	operatorMu.Lock()
+	defer operatorMu.Unlock()
+
+	if !operators[operator] {
+		log.SyslogErr("SignApproveData, not an operator", "operator", operator.String())
+		return 0, mpcprotocol.ErrNotOperator
+	}
+
+	sdb, err := GetDB()
+	if err != nil {
+		return 0, mpcprotocol.ErrGetDb
+	}
+
+	record, err := getApprovingRecord(sdb, data)
+	if err != nil {
+		return 0, err
+	}
+	if ApprovalHash(record) != hash {
+		log.SyslogErr("SignApproveData, approval hash mismatch", "operator", operator.String(), "hash", hash.String())
+		return 0, mpcprotocol.ErrApprovalMismatch
+	}
+
+	approvals, err := getApprovals(sdb, record)
+	if err != nil {
+		return 0, err
+	}
+	approvals = approvalsOver(approvals, hash)
+
+	for _, approval := range approvals {
+		if approval.Operator == operator {
+			return len(approvals), mpcprotocol.ErrDuplicateApproval
+		}
+	}
+
+	approvals = append(approvals, OperatorApproval{Operator: operator, Signature: signature, Time: time.Now().Unix()})
+	value, err := json.Marshal(approvals)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = addKeyValueToDB(buildKeyFromData(record, mpcprotocol.MpcApprovals), value); err != nil {
+		return 0, err
+	}
+
+	log.SyslogInfo("SignApproveData",
+		"pk", hexutil.Encode(data.PKBytes),
+		"data", hexutil.Encode(data.Data),
+		"operator", operator.String(),
+		"approvals", len(approvals),
+		"threshold", operatorThreshold)
+
+	if len(approvals) < operatorThreshold {
+		return len(approvals), nil
+	}
+
+	return len(approvals), approveOneData(*record)
+}
+
+// getApprovingRecord returns the record waiting for approval under the key of the data.
+func getApprovingRecord(sdb Database, data *mpcprotocol.SendData) (*mpcprotocol.SendData, error) {
+	key := buildKeyFromData(data, mpcprotocol.MpcApproving)
+	exist, err := sdb.Has(key)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, mpcprotocol.ErrNotApproving
+	}
+
+	value, err := sdb.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var record mpcprotocol.SendData
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// approvalsOver returns the approvals of the current operators whose signature is over the hash.
+func approvalsOver(approvals []OperatorApproval, hash common.Hash) []OperatorApproval {
+	var valid []OperatorApproval
+	for _, approval := range approvals {
+		pub, err := crypto.SigToPub(hash[:], approval.Signature)
+		if err != nil || crypto.PubkeyToAddress(*pub) != approval.Operator || !operators[approval.Operator] {
+			continue
+		}
+		valid = append(valid, approval)
+	}
+
+	return valid
+}
+
+func getApprovals(sdb Database, data *mpcprotocol.SendData) ([]OperatorApproval, error) {
+	key := buildKeyFromData(data, mpcprotocol.MpcApprovals)
+	exist, err := sdb.Has(key)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	value, err := sdb.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var approvals []OperatorApproval
+	if err := json.Unmarshal(value, &approvals); err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// GetPendingApprovals returns the data waiting for approval with the operators who have approved them.
+func GetPendingApprovals(filter *ApprovingFilter) ([]PendingApproval, error) {
+	data, err := QueryDataForApprove(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sdb, err := GetDB()
+	if err != nil {
+		return nil, mpcprotocol.ErrGetDb
+	}
+
+	threshold := operatorQuorum()
+	pending := make([]PendingApproval, len(data))
+	for i := range data {
+		approvals, err := getApprovals(sdb, &data[i])
+		if err != nil {
+			log.SyslogErr("GetPendingApprovals, get approvals fail", "err", err.Error())
+		}
+		operatorMu.Lock()
+		approvals = approvalsOver(approvals, ApprovalHash(&data[i]))
+		operatorMu.Unlock()
+
+		pending[i] = PendingApproval{
+			Data:      data[i],
+			Hash:      ApprovalHash(&data[i]),
+			Approvals: approvals,
+			Threshold: threshold,
+			Payload:   DecodePayload(&data[i]),
+		}
+	}
+
+	return pending, nil
+}
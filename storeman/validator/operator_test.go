@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestSignApproveData(t *testing.T) {
+	defer newTestDB(t)()
+
+	keys := make([]common.Address, 3)
+	prvs := make([]*ecdsa.PrivateKey, len(keys))
+	for i := range keys {
+		prvs[i], _ = crypto.GenerateKey()
+		keys[i] = crypto.PubkeyToAddress(prvs[i].PublicKey)
+	}
+
+	if err := SetOperators(keys[:2], 3); err != mpcprotocol.ErrInvalidOperators {
+		t.Fatalf("threshold over the operators is accepted: %v", err)
+	}
+	if err := SetOperators(keys[:2], 2); err != nil {
+		t.Fatal(err)
+	}
+	defer SetOperators(nil, 0)
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("wanchain"), Extern: "cross"}
+	if err := AddApprovingData(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := ApproveData([]mpcprotocol.SendData{data}); errs[0] != mpcprotocol.ErrOperatorSigRequired {
+		t.Fatalf("data approved without operator signatures: %v", errs[0])
+	}
+	if err := AddValidData(&data); err != mpcprotocol.ErrOperatorSigRequired {
+		t.Fatalf("data added as valid without operator signatures: %v", err)
+	}
+
+	hash := ApprovalHash(&data)
+	sign := func(i int) []byte {
+		sig, err := crypto.Sign(hash[:], prvs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+
+	// the data with another extern shares the key of the record, but not its approval hash
+	forged := data
+	forged.Extern = "forged"
+	forgedHash := ApprovalHash(&forged)
+	forgedSig, err := crypto.Sign(forgedHash[:], prvs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignApproveData(&forged, forgedSig); err != mpcprotocol.ErrApprovalMismatch {
+		t.Fatalf("approval of another extern is accepted: %v", err)
+	}
+
+	if _, err := SignApproveData(&data, sign(2)); err != mpcprotocol.ErrNotOperator {
+		t.Fatalf("data approved by a non operator: %v", err)
+	}
+
+	if n, err := SignApproveData(&data, sign(0)); n != 1 || err != nil {
+		t.Fatalf("first approval: %d %v", n, err)
+	}
+	if n, err := SignApproveData(&data, sign(0)); n != 1 || err != mpcprotocol.ErrDuplicateApproval {
+		t.Fatalf("duplicate approval: %d %v", n, err)
+	}
+
+	pending, err := GetPendingApprovals(nil)
+	if err != nil || len(pending) != 1 || len(pending[0].Approvals) != 1 || pending[0].Approvals[0].Operator != keys[0] {
+		t.Fatalf("pending approvals: %+v %v", pending, err)
+	}
+
+	if ok, _ := ValidateData(&data, 10*time.Millisecond); ok {
+		t.Fatal("data approved below the threshold")
+	}
+
+	// a stored signature over another hash is not counted
+	approvals, _ := json.Marshal([]OperatorApproval{
+		{Operator: keys[0], Signature: sign(0)},
+		{Operator: keys[1], Signature: forgedSig}})
+	if err := addKeyValueToDB(buildKeyFromData(&data, mpcprotocol.MpcApprovals), approvals); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := SignApproveData(&data, sign(1)); n != 2 || err != nil {
+		t.Fatalf("second approval: %d %v", n, err)
+	}
+	if ok, err := ValidateData(&data, time.Second); !ok {
+		t.Fatalf("data not approved at the threshold: %v", err)
+	}
+}
+
+func TestApprovalHash(t *testing.T) {
+	data := mpcprotocol.SendData{PKBytes: []byte{0x04, 0x01}, Data: []byte{0xaa}, Extern: "cross"}
+
+	// keccak256(rlp([domain, gpk, data, extern]))
+	enc := append([]byte{0xe9, 0x9d}, ApprovalDomain...)
+	enc = append(enc, 0x82, 0x04, 0x01, 0x81, 0xaa, 0x85)
+	enc = append(enc, "cross"...)
+	if hash := ApprovalHash(&data); hash != crypto.Keccak256Hash(enc) {
+		t.Fatalf("approval hash %x is not of the documented encoding", hash)
+	}
+
+	// the boundaries of the fields are part of the hash
+	shifted := mpcprotocol.SendData{PKBytes: []byte{0x04}, Data: []byte{0x01, 0xaa}, Extern: "cross"}
+	if ApprovalHash(&data) == ApprovalHash(&shifted) {
+		t.Fatal("shifted fields have the same approval hash")
+	}
+}
@@ -0,0 +1,327 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/common/math"
+	"github.com/wanchain/schnorr-mpc/core/types"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/rlp"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const (
+	ChainWanchain = "wanchain"
+	ChainEthereum = "ethereum"
+
+	KindTx       = "tx"       // the data is a rlp encoded transaction
+	KindPreimage = "preimage" // the data is the rlp list hashed to sign a transaction
+	KindHash     = "hash"     // the data is the signing hash of the transaction in the extern
+
+	ExternTx = "tx" // extern field carrying the hex of the transaction whose signing hash is the data
+)
+
+// fields of the decoded transaction, matched by the policy rules as payload.<field>
+const (
+	TxFieldChain    = "chain"
+	TxFieldKind     = "kind"
+	TxFieldType     = "txType"
+	TxFieldNonce    = "nonce"
+	TxFieldTo       = "to"
+	TxFieldValue    = "value"
+	TxFieldChainID  = "chainId"
+	TxFieldSelector = "selector"
+	TxFieldHash     = "hash"
+)
+
+// fields the extern may claim, the numeric ones are compared as integers
+var claimFields = map[string]bool{
+	TxFieldChain:    false,
+	TxFieldType:     true,
+	TxFieldNonce:    true,
+	TxFieldTo:       false,
+	TxFieldValue:    true,
+	TxFieldChainID:  true,
+	TxFieldSelector: false,
+}
+
+// TxPayload is a transaction decoded from the data requested to be signed.
+type TxPayload struct {
+	Chain    string          `json:"chain"`
+	Kind     string          `json:"kind"`
+	TxType   uint64          `json:"txType,omitempty"` // wanchain only
+	Nonce    uint64          `json:"nonce"`
+	To       *common.Address `json:"to"` // nil for contract creation
+	Value    *big.Int        `json:"value"`
+	ChainID  *big.Int        `json:"chainId"` // nil if the transaction is not replay protected
+	Selector hexutil.Bytes   `json:"selector,omitempty"`
+	Hash     common.Hash     `json:"hash"` // signing hash
+}
+
+// Fields returns the fields of the transaction as strings.
+func (tx *TxPayload) Fields() map[string]string {
+	fields := map[string]string{
+		TxFieldChain: tx.Chain,
+		TxFieldKind:  tx.Kind,
+		TxFieldNonce: strconv.FormatUint(tx.Nonce, 10),
+		TxFieldValue: tx.Value.String(),
+		TxFieldHash:  tx.Hash.Hex(),
+	}
+
+	if tx.Chain == ChainWanchain {
+		fields[TxFieldType] = strconv.FormatUint(tx.TxType, 10)
+	}
+	if tx.To != nil {
+		fields[TxFieldTo] = tx.To.Hex()
+	}
+	if tx.ChainID != nil {
+		fields[TxFieldChainID] = tx.ChainID.String()
+	}
+	if len(tx.Selector) != 0 {
+		fields[TxFieldSelector] = tx.Selector.String()
+	}
+
+	return fields
+}
+
+// wanPreimage is hashed by types.HomesteadSigner to sign a wanchain transaction.
+type wanPreimage struct {
+	Txtype  uint64
+	Nonce   uint64
+	Price   *big.Int
+	Gas     *big.Int
+	To      *common.Address `rlp:"nil"`
+	Value   *big.Int
+	Payload []byte
+}
+
+// ethTx is an ethereum transaction, or the preimage hashed to sign it if R and S are zero.
+type ethTx struct {
+	Nonce   uint64
+	Price   *big.Int
+	Gas     *big.Int
+	To      *common.Address `rlp:"nil"`
+	Value   *big.Int
+	Payload []byte
+	V, R, S *big.Int
+}
+
+// ethPreimage is hashed to sign an ethereum transaction which is not replay protected.
+type ethPreimage struct {
+	Nonce   uint64
+	Price   *big.Int
+	Gas     *big.Int
+	To      *common.Address `rlp:"nil"`
+	Value   *big.Int
+	Payload []byte
+}
+
+// decodeTx decodes a rlp encoded wanchain or ethereum transaction, or its signing preimage.
+// The chain is told by the length of the list, as a wanchain transaction leads with its type.
+func decodeTx(data []byte) (*TxPayload, bool) {
+	var items []rlp.RawValue
+	if rlp.DecodeBytes(data, &items) != nil {
+		return nil, false
+	}
+
+	switch len(items) {
+	case 10:
+		var tx types.Transaction
+		if rlp.DecodeBytes(data, &tx) != nil {
+			return nil, false
+		}
+
+		payload := &TxPayload{Chain: ChainWanchain, TxType: tx.Txtype(), Nonce: tx.Nonce(), To: tx.To(), Value: tx.Value()}
+		v, r, s := tx.RawSignatureValues()
+		var signer types.Signer
+		switch {
+		case r.Sign() == 0 && s.Sign() == 0:
+			payload.Kind, payload.ChainID = KindPreimage, v
+			signer = types.NewEIP155Signer(v)
+		case tx.Protected():
+			payload.Kind, payload.ChainID = KindTx, tx.ChainId()
+			signer = types.NewEIP155Signer(payload.ChainID)
+		default:
+			payload.Kind = KindTx
+			signer = types.HomesteadSigner{}
+		}
+
+		payload.Hash, payload.Selector = signer.Hash(&tx), selector(tx.Data())
+		if payload.Kind == KindPreimage && payload.Hash != crypto.Keccak256Hash(data) {
+			// not the canonical encoding of the preimage
+			return nil, false
+		}
+		return payload, true
+
+	case 7:
+		var pre wanPreimage
+		if rlp.DecodeBytes(data, &pre) != nil {
+			return nil, false
+		}
+
+		tx := types.NewContractCreation(pre.Nonce, pre.Value, pre.Gas, pre.Price, pre.Payload)
+		if pre.To != nil {
+			tx = types.NewTransaction(pre.Nonce, *pre.To, pre.Value, pre.Gas, pre.Price, pre.Payload)
+		}
+		tx.SetTxtype(pre.Txtype)
+
+		hash := types.HomesteadSigner{}.Hash(tx)
+		if hash != crypto.Keccak256Hash(data) {
+			return nil, false
+		}
+
+		return &TxPayload{Chain: ChainWanchain, Kind: KindPreimage, TxType: pre.Txtype, Nonce: pre.Nonce, To: pre.To,
+			Value: pre.Value, Selector: selector(pre.Payload), Hash: hash}, true
+
+	case 9:
+		var tx ethTx
+		if rlp.DecodeBytes(data, &tx) != nil {
+			return nil, false
+		}
+
+		payload := &TxPayload{Chain: ChainEthereum, Nonce: tx.Nonce, To: tx.To, Value: tx.Value, Selector: selector(tx.Payload)}
+		pre := []interface{}{tx.Nonce, tx.Price, tx.Gas, tx.To, tx.Value, tx.Payload}
+		switch {
+		case tx.R.Sign() == 0 && tx.S.Sign() == 0:
+			payload.Kind, payload.ChainID = KindPreimage, tx.V
+			payload.Hash = crypto.Keccak256Hash(data)
+		case tx.V.Cmp(big.NewInt(35)) >= 0:
+			payload.Kind = KindTx
+			payload.ChainID = new(big.Int).Rsh(new(big.Int).Sub(tx.V, big.NewInt(35)), 1)
+			payload.Hash = rlpHash(append(pre, payload.ChainID, uint(0), uint(0)))
+		default:
+			payload.Kind = KindTx
+			payload.Hash = rlpHash(pre)
+		}
+		return payload, true
+
+	case 6:
+		var pre ethPreimage
+		if rlp.DecodeBytes(data, &pre) != nil {
+			return nil, false
+		}
+
+		return &TxPayload{Chain: ChainEthereum, Kind: KindPreimage, Nonce: pre.Nonce, To: pre.To, Value: pre.Value,
+			Selector: selector(pre.Payload), Hash: crypto.Keccak256Hash(data)}, true
+	}
+
+	return nil, false
+}
+
+func selector(payload []byte) hexutil.Bytes {
+	if len(payload) < 4 {
+		return nil
+	}
+	return common.CopyBytes(payload[:4])
+}
+
+func rlpHash(x interface{}) common.Hash {
+	enc, _ := rlp.EncodeToBytes(x)
+	return crypto.Keccak256Hash(enc)
+}
+
+// parseExtern returns the fields of the extern if it is a json object.
+func parseExtern(extern string) map[string]interface{} {
+	dec := json.NewDecoder(strings.NewReader(extern))
+	dec.UseNumber()
+
+	var fields map[string]interface{}
+	if dec.Decode(&fields) != nil {
+		return nil
+	}
+	return fields
+}
+
+// DecodeTransaction decodes the data as a wanchain or ethereum transaction or signing preimage, or, if the
+// data is a hash, as the signing hash of the transaction carried in the tx field of the extern. It returns
+// nil if the data is not a transaction, and ErrPayloadMismatch if the transaction of the extern does not
+// hash to the data.
+func DecodeTransaction(data *mpcprotocol.SendData) (*TxPayload, error) {
+	if tx, ok := decodeTx(data.Data); ok {
+		return tx, nil
+	}
+
+	raw, exist := parseExtern(data.Extern)[ExternTx]
+	if !exist {
+		return nil, nil
+	}
+
+	str, _ := raw.(string)
+	enc, err := hexutil.Decode(str)
+	if err != nil {
+		return nil, fmt.Errorf("%v: invalid %s in extern", mpcprotocol.ErrPayloadMismatch, ExternTx)
+	}
+
+	tx, ok := decodeTx(enc)
+	if !ok {
+		return nil, fmt.Errorf("%v: %s in extern is not a transaction", mpcprotocol.ErrPayloadMismatch, ExternTx)
+	}
+	if !bytes.Equal(tx.Hash[:], data.Data) {
+		return nil, fmt.Errorf("%v: data is not the signing hash of %s in extern", mpcprotocol.ErrPayloadMismatch, ExternTx)
+	}
+
+	tx.Kind = KindHash
+	return tx, nil
+}
+
+// DecodePayload returns the fields of the transaction decoded from the data, or the fields of the first
+// registered payload decoder which accepts the data.
+func DecodePayload(data *mpcprotocol.SendData) map[string]string {
+	if tx, err := DecodeTransaction(data); err == nil && tx != nil {
+		return tx.Fields()
+	}
+
+	policyMu.RLock()
+	decoders := payloadDecoders
+	policyMu.RUnlock()
+	for _, decode := range decoders {
+		if fields, ok := decode(data.Data); ok {
+			return fields
+		}
+	}
+
+	return nil
+}
+
+// CheckPayload refuses the data whose decoded transaction contradicts the fields claimed by the extern.
+func CheckPayload(data *mpcprotocol.SendData) error {
+	tx, err := DecodeTransaction(data)
+	if err != nil || tx == nil {
+		return err
+	}
+
+	decoded := tx.Fields()
+	for field, claimed := range parseExtern(data.Extern) {
+		numeric, known := claimFields[field]
+		if !known {
+			continue
+		}
+
+		value, exist := decoded[field]
+		if !exist || !claimMatch(fmt.Sprint(claimed), value, numeric) {
+			return fmt.Errorf("%v: extern claims %s %v, data has %q", mpcprotocol.ErrPayloadMismatch, field, claimed, value)
+		}
+	}
+
+	return nil
+}
+
+func claimMatch(claimed, value string, numeric bool) bool {
+	if !numeric {
+		return strings.EqualFold(claimed, value)
+	}
+
+	c, ok := math.ParseBig256(claimed)
+	if !ok {
+		return false
+	}
+	v, ok := math.ParseBig256(value)
+	return ok && c.Cmp(v) == 0
+}
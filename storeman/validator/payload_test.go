@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/core/types"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/rlp"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// the example transaction of EIP-155
+const (
+	eip155Tx   = "0xf86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	eip155Hash = "0xdaf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53"
+)
+
+var testTo = common.HexToAddress("0x3535353535353535353535353535353535353535")
+
+func TestDecodeEthereumTransaction(t *testing.T) {
+	tx, err := DecodeTransaction(&mpcprotocol.SendData{Data: hexutil.MustDecode(eip155Tx)})
+	if err != nil || tx == nil {
+		t.Fatalf("decode: %v %v", tx, err)
+	}
+
+	fields := tx.Fields()
+	want := map[string]string{
+		TxFieldChain:   ChainEthereum,
+		TxFieldKind:    KindTx,
+		TxFieldNonce:   "9",
+		TxFieldTo:      testTo.Hex(),
+		TxFieldValue:   "1000000000000000000",
+		TxFieldChainID: "1",
+		TxFieldHash:    eip155Hash,
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("%s: got %q, want %q", k, fields[k], v)
+		}
+	}
+
+	// the signing hash with the transaction in the extern
+	hash := &mpcprotocol.SendData{Data: hexutil.MustDecode(eip155Hash), Extern: `{"tx":"` + eip155Tx + `","value":"0xde0b6b3a7640000"}`}
+	if tx, err := DecodeTransaction(hash); err != nil || tx == nil || tx.Kind != KindHash {
+		t.Fatalf("decode hash: %v %v", tx, err)
+	}
+	if err := CheckPayload(hash); err != nil {
+		t.Fatal(err)
+	}
+
+	hash.Data = crypto.Keccak256([]byte("other"))
+	if err := CheckPayload(hash); err == nil || !strings.Contains(err.Error(), mpcprotocol.ErrPayloadMismatch.Error()) {
+		t.Fatalf("hash of another transaction is accepted: %v", err)
+	}
+}
+
+func TestDecodeWanchainTransaction(t *testing.T) {
+	prv, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(3))
+	tx := types.NewTransaction(5, testTo, big.NewInt(100), big.NewInt(21000), big.NewInt(180e9), []byte{0xa9, 0x05, 0x9c, 0xbb, 1})
+	signed, err := types.SignTx(tx, signer, prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeTransaction(&mpcprotocol.SendData{Data: enc})
+	if err != nil || decoded == nil {
+		t.Fatalf("decode: %v %v", decoded, err)
+	}
+	if decoded.Chain != ChainWanchain || decoded.Kind != KindTx || decoded.TxType != types.NORMAL_TX ||
+		decoded.Nonce != 5 || *decoded.To != testTo || decoded.Value.Int64() != 100 || decoded.ChainID.Int64() != 3 ||
+		decoded.Selector.String() != "0xa9059cbb" || decoded.Hash != signer.Hash(tx) {
+		t.Fatalf("decoded transaction: %+v", decoded)
+	}
+
+	// the preimage hashed by the signer
+	preimage, err := rlp.EncodeToBytes([]interface{}{uint64(types.NORMAL_TX), uint64(5), big.NewInt(180e9), big.NewInt(21000),
+		testTo, big.NewInt(100), []byte{0xa9, 0x05, 0x9c, 0xbb, 1}, big.NewInt(3), uint(0), uint(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err = DecodeTransaction(&mpcprotocol.SendData{Data: preimage})
+	if err != nil || decoded == nil || decoded.Kind != KindPreimage || decoded.Hash != signer.Hash(tx) || decoded.ChainID.Int64() != 3 {
+		t.Fatalf("decode preimage: %+v %v", decoded, err)
+	}
+
+	if tx, err := DecodeTransaction(&mpcprotocol.SendData{Data: []byte("wanchain"), Extern: `{"to":"0x01"}`}); tx != nil || err != nil {
+		t.Fatalf("opaque data is decoded: %v %v", tx, err)
+	}
+}
+
+func TestCheckPayload(t *testing.T) {
+	data := hexutil.MustDecode(eip155Tx)
+	tests := []struct {
+		extern string
+		ok     bool
+	}{
+		{"cross", true},
+		{`{"type":"transfer"}`, true},
+		{`{"to":"` + strings.ToLower(testTo.Hex()) + `","value":1000000000000000000,"nonce":"0x9","chainId":1}`, true},
+		{`{"chain":"wanchain"}`, false},
+		{`{"to":"0xdead000000000000000000000000000000000000"}`, false},
+		{`{"value":"999"}`, false},
+		{`{"selector":"0xa9059cbb"}`, false},
+		{`{"tx":"0x1234"}`, true}, // the data is a transaction, the extern tx is not used
+	}
+
+	for i, test := range tests {
+		err := CheckPayload(&mpcprotocol.SendData{Data: data, Extern: test.extern})
+		if (err == nil) != test.ok {
+			t.Errorf("test %d: %v", i, err)
+		}
+	}
+
+	if err := CheckPayload(&mpcprotocol.SendData{Data: crypto.Keccak256(), Extern: `{"tx":"0x1234"}`}); err == nil {
+		t.Error("invalid extern transaction is accepted")
+	}
+}
+
+func TestPolicyOnTransaction(t *testing.T) {
+	p := &Policy{Default: DecisionManual, Rules: []Rule{{
+		Name:   "eth-transfer",
+		Action: DecisionApprove,
+		Conditions: []Condition{
+			{Field: "payload.to", In: []string{strings.ToLower(testTo.Hex())}},
+			{Field: "payload.chainId", In: []string{"1"}},
+		},
+	}}}
+
+	data := mpcprotocol.SendData{Data: hexutil.MustDecode(eip155Tx)}
+	if decision, rule := p.Evaluate(&data); decision != DecisionApprove || rule != "eth-transfer" {
+		t.Fatalf("got %q by rule %q", decision, rule)
+	}
+
+	if fields := DecodePayload(&data); fields[TxFieldSelector] != "" || fields[TxFieldValue] != "1000000000000000000" {
+		t.Fatalf("decoded payload: %v", fields)
+	}
+}
@@ -0,0 +1,257 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/common/math"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// Decision is the verdict of the approval policy on a data requested to be signed.
+type Decision string
+
+const (
+	DecisionNone    Decision = ""        // no policy is loaded, the data is approved as requested by the leader
+	DecisionApprove Decision = "approve" // the data is approved without operators
+	DecisionReject  Decision = "reject"  // the data is refused
+	DecisionManual  Decision = "manual"  // the data waits to be approved by operators
+)
+
+const (
+	FieldGPK      = "gpk"      // hex of the group public key
+	FieldExtern   = "extern"   // the raw extern string
+	externPrefix  = "extern."  // fields of the extern, if it is a json object
+	payloadPrefix = "payload." // fields decoded from the data by the payload decoders
+)
+
+// Condition matches a field of the data. A missing field never matches.
+// In is the allow list and NotIn is the deny list of the field values, hex values are compared case insensitively.
+// Min and Max bound the field as an integer.
+type Condition struct {
+	Field string                `json:"field"`
+	In    []string              `json:"in,omitempty"`
+	NotIn []string              `json:"notIn,omitempty"`
+	Min   *math.HexOrDecimal256 `json:"min,omitempty"`
+	Max   *math.HexOrDecimal256 `json:"max,omitempty"`
+}
+
+// Rule decides the data matching all its conditions, and one of its gpks if any.
+type Rule struct {
+	Name       string          `json:"name"`
+	Action     Decision        `json:"action"`
+	GPKs       []hexutil.Bytes `json:"gpks,omitempty"`
+	Conditions []Condition     `json:"conditions,omitempty"`
+}
+
+// Policy decides the data by its first matching rule, or by Default if no rule matches.
+type Policy struct {
+	Default Decision `json:"default"`
+	Rules   []Rule   `json:"rules"`
+}
+
+// PayloadDecoder decodes the data requested to be signed into named fields, ok is false if the data is
+// not of the decoder's format.
+type PayloadDecoder func(data []byte) (fields map[string]string, ok bool)
+
+var (
+	policyMu        sync.RWMutex
+	policy          *Policy
+	payloadDecoders []PayloadDecoder
+)
+
+// LoadPolicy reads and checks the json policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := json.Unmarshal(content, &p); err != nil {
+		return nil, fmt.Errorf("%v: %v", mpcprotocol.ErrInvalidPolicy, err)
+	}
+
+	if p.Default == DecisionNone {
+		p.Default = DecisionManual
+	}
+
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) check() error {
+	if !validDecision(p.Default) {
+		return fmt.Errorf("%v: unknown default action %q", mpcprotocol.ErrInvalidPolicy, p.Default)
+	}
+
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("%v: rule %d has no name", mpcprotocol.ErrInvalidPolicy, i)
+		}
+
+		if !validDecision(rule.Action) {
+			return fmt.Errorf("%v: unknown action %q of rule %s", mpcprotocol.ErrInvalidPolicy, rule.Action, rule.Name)
+		}
+
+		for _, cond := range rule.Conditions {
+			if cond.Field == "" {
+				return fmt.Errorf("%v: condition without field in rule %s", mpcprotocol.ErrInvalidPolicy, rule.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validDecision(d Decision) bool {
+	return d == DecisionApprove || d == DecisionReject || d == DecisionManual
+}
+
+// Evaluate returns the decision on the data and the name of the rule which made it, the rule is empty
+// if the default decision is taken.
+func (p *Policy) Evaluate(data *mpcprotocol.SendData) (Decision, string) {
+	fields := dataFields(data)
+	for _, rule := range p.Rules {
+		if rule.match(data.PKBytes, fields) {
+			return rule.Action, rule.Name
+		}
+	}
+
+	return p.Default, ""
+}
+
+func (rule *Rule) match(gpk []byte, fields map[string]string) bool {
+	if len(rule.GPKs) != 0 {
+		found := false
+		for _, pk := range rule.GPKs {
+			if hexutil.Encode(pk) == hexutil.Encode(gpk) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	for i := range rule.Conditions {
+		if !rule.Conditions[i].match(fields) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cond *Condition) match(fields map[string]string) bool {
+	value, exist := fields[cond.Field]
+	if !exist {
+		return false
+	}
+
+	if len(cond.In) != 0 && !containsFold(cond.In, value) {
+		return false
+	}
+
+	if containsFold(cond.NotIn, value) {
+		return false
+	}
+
+	if cond.Min == nil && cond.Max == nil {
+		return true
+	}
+
+	n, ok := math.ParseBig256(value)
+	if !ok {
+		return false
+	}
+
+	if cond.Min != nil && n.Cmp((*big.Int)(cond.Min)) < 0 {
+		return false
+	}
+
+	return cond.Max == nil || n.Cmp((*big.Int)(cond.Max)) <= 0
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataFields collects the fields of the data which the conditions match on.
+func dataFields(data *mpcprotocol.SendData) map[string]string {
+	fields := map[string]string{
+		FieldGPK:    hexutil.Encode(data.PKBytes),
+		FieldExtern: data.Extern,
+	}
+
+	for k, v := range parseExtern(data.Extern) {
+		fields[externPrefix+k] = fmt.Sprint(v)
+	}
+
+	for k, v := range DecodePayload(data) {
+		fields[payloadPrefix+k] = v
+	}
+
+	return fields
+}
+
+// SetPolicy sets the policy which decides the data requested to be signed, nil removes the policy.
+func SetPolicy(p *Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// RegisterPayloadDecoder adds a decoder whose fields the policy rules can match on. The decoders are tried
+// in order on the data which is not a transaction.
+func RegisterPayloadDecoder(decode PayloadDecoder) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	payloadDecoders = append(payloadDecoders, decode)
+}
+
+// ApplyPolicy decides the data by the policy. The data approved by the policy is added to the approved db,
+// the data sent to manual review is added to the approving db.
+func ApplyPolicy(data *mpcprotocol.SendData) (Decision, error) {
+	policyMu.RLock()
+	p := policy
+	policyMu.RUnlock()
+	if p == nil {
+		return DecisionNone, nil
+	}
+
+	decision, rule := p.Evaluate(data)
+	log.SyslogInfo("ApplyPolicy",
+		"pk", hexutil.Encode(data.PKBytes),
+		"data", hexutil.Encode(data.Data),
+		"decision", string(decision),
+		"rule", rule)
+
+	switch decision {
+	case DecisionApprove:
+		return decision, addValidDataOnce(data)
+
+	case DecisionManual:
+		return decision, addApprovingData(data)
+
+	default:
+		return decision, nil
+	}
+}
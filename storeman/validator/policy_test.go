@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+const testPolicy = `{
+	"default": "manual",
+	"rules": [
+		{"name": "denied-destination", "action": "reject",
+			"conditions": [{"field": "extern.to", "in": ["0xDEAD000000000000000000000000000000000000"]}]},
+		{"name": "small-transfer", "action": "approve", "gpks": ["0x01"],
+			"conditions": [{"field": "payload.value", "max": "1000"}, {"field": "extern.type", "notIn": ["admin"]}]},
+		{"name": "small-amount", "action": "approve", "gpks": ["0x03"],
+			"conditions": [{"field": "extern.amount", "max": "1000000"}]}
+	]
+}`
+
+func TestPolicyEvaluate(t *testing.T) {
+	dir := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policy.json")
+	if err := ioutil.WriteFile(path, []byte(testPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(decoders []PayloadDecoder) { payloadDecoders = decoders }(payloadDecoders)
+	RegisterPayloadDecoder(func(data []byte) (map[string]string, bool) {
+		return map[string]string{"value": string(data)}, true
+	})
+
+	tests := []struct {
+		data     mpcprotocol.SendData
+		decision Decision
+		rule     string
+	}{
+		{mpcprotocol.SendData{PKBytes: []byte{1}, Data: []byte("10"), Extern: `{"to":"0xdead000000000000000000000000000000000000"}`}, DecisionReject, "denied-destination"},
+		{mpcprotocol.SendData{PKBytes: []byte{1}, Data: []byte("1000"), Extern: `{"type":"transfer"}`}, DecisionApprove, "small-transfer"},
+		{mpcprotocol.SendData{PKBytes: []byte{1}, Data: []byte("1001"), Extern: `{"type":"transfer"}`}, DecisionManual, ""},
+		{mpcprotocol.SendData{PKBytes: []byte{1}, Data: []byte("10"), Extern: `{"type":"admin"}`}, DecisionManual, ""},
+		{mpcprotocol.SendData{PKBytes: []byte{2}, Data: []byte("10"), Extern: `{"type":"transfer"}`}, DecisionManual, ""},
+		{mpcprotocol.SendData{PKBytes: []byte{1}, Data: []byte("10"), Extern: "not json"}, DecisionManual, ""},
+		{mpcprotocol.SendData{PKBytes: []byte{3}, Data: []byte("10"), Extern: `{"amount":1000000}`}, DecisionApprove, "small-amount"},
+		{mpcprotocol.SendData{PKBytes: []byte{3}, Data: []byte("10"), Extern: `{"amount":1000001}`}, DecisionManual, ""},
+		{mpcprotocol.SendData{PKBytes: []byte{3}, Data: []byte("10"), Extern: `{"amount":"1000000"}`}, DecisionApprove, "small-amount"},
+	}
+
+	for i, test := range tests {
+		decision, rule := p.Evaluate(&test.data)
+		if decision != test.decision || rule != test.rule {
+			t.Errorf("test %d: got %q by rule %q, want %q by rule %q", i, decision, rule, test.decision, test.rule)
+		}
+	}
+}
+
+func TestLoadInvalidPolicy(t *testing.T) {
+	dir := tmpKeyStore(t)
+	defer os.RemoveAll(dir)
+
+	for i, content := range []string{
+		`{"default": "sign"}`,
+		`{"rules": [{"action": "approve"}]}`,
+		`{"rules": [{"name": "r", "action": "allow"}]}`,
+		`{"rules": [{"name": "r", "action": "approve", "conditions": [{"in": ["1"]}]}]}`,
+	} {
+		path := filepath.Join(dir, "policy.json")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadPolicy(path); err == nil {
+			t.Errorf("test %d: invalid policy is loaded", i)
+		}
+	}
+}
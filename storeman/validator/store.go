@@ -0,0 +1,233 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/wanchain/schnorr-mpc/common"
+	"github.com/wanchain/schnorr-mpc/common/hexutil"
+	"github.com/wanchain/schnorr-mpc/crypto"
+	"github.com/wanchain/schnorr-mpc/log"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+// Every approving, approved or rejected data is kept as
+//
+//	record key (buildKeyFromData)                        -> record value
+//	expiryPrefix || record key                           -> json of expiryEntry
+//	statusIndexPrefix || status || time || record key    -> empty
+//	gpkIndexPrefix || hash(gpk) || status || time || key -> empty
+//
+// and all of them are written in one batch.
+const (
+	statusIndexPrefix = "MpcIdxStatus"
+	gpkIndexPrefix    = "MpcIdxGPK"
+
+	DefaultQueryLimit = 100 // records returned by a query without limit
+	MaxQueryLimit     = 1000
+)
+
+// storeMu serializes the state transitions of the approval records. The approval events of the
+// transitions are queued while it is held and posted by unlockStore, so no subscriber blocks the store.
+var (
+	storeMu     sync.Mutex
+	storeEvents []ApprovalEvent // guarded by storeMu
+)
+
+// unlockStore releases storeMu and then posts the approval events queued while it was held.
+func unlockStore() {
+	events := storeEvents
+	storeEvents = nil
+	storeMu.Unlock()
+
+	for _, ev := range events {
+		sendApprovalEvent(ev)
+	}
+}
+
+// ApprovingFilter selects and pages the data waiting for approval, which are ordered by the time they were added.
+type ApprovingFilter struct {
+	GPK    hexutil.Bytes `json:"gpk,omitempty"`
+	Since  int64         `json:"since,omitempty"` // unix time, inclusive
+	Until  int64         `json:"until,omitempty"` // unix time, inclusive, zero: no bound
+	Offset int           `json:"offset,omitempty"`
+	Limit  int           `json:"limit,omitempty"` // zero: DefaultQueryLimit
+}
+
+func timeBytes(t int64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], uint64(t))
+	return enc[:]
+}
+
+func statusIndexPrefixOf(status string) []byte {
+	return []byte(statusIndexPrefix + status + "/")
+}
+
+func gpkIndexPrefixOf(gpk []byte, status string) []byte {
+	return append(append([]byte(gpkIndexPrefix), crypto.Keccak256(gpk)...), []byte(status+"/")...)
+}
+
+func indexKeys(data *mpcprotocol.SendData, status string, t int64, recordKey []byte) [][]byte {
+	suffix := append(timeBytes(t), recordKey...)
+	return [][]byte{
+		append(statusIndexPrefixOf(status), suffix...),
+		append(gpkIndexPrefixOf(data.PKBytes, status), suffix...),
+	}
+}
+
+// putRecord writes the record of the data in the status, with its expiry entry and indexes, to the batch.
+func putRecord(b Batch, data *mpcprotocol.SendData, status string, value []byte, now time.Time) ([]byte, error) {
+	recordKey := buildKeyFromData(data, status)
+	entry := expiryEntry{Status: status, Time: now.Unix(), Expiry: now.Add(recordTTL(status)).Unix(), Data: *data}
+	entryBytes, err := json.Marshal(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Put(recordKey, value)
+	b.Put(expiryKey(recordKey), entryBytes)
+	for _, key := range indexKeys(data, status, entry.Time, recordKey) {
+		b.Put(key, []byte{})
+	}
+
+	return recordKey, nil
+}
+
+// deleteRecord removes the record of the data in the status, with its expiry entry and indexes, by the batch.
+func deleteRecord(sdb Database, b Batch, data *mpcprotocol.SendData, status string) error {
+	recordKey := buildKeyFromData(data, status)
+	entry, err := getExpiry(sdb, recordKey)
+	if err != nil {
+		return err
+	}
+
+	b.Delete(recordKey)
+	b.Delete(expiryKey(recordKey))
+	if entry != nil {
+		for _, key := range indexKeys(data, status, entry.Time, recordKey) {
+			b.Delete(key)
+		}
+	}
+
+	return nil
+}
+
+// writeBatch writes the batch, and wakes the waiters of the written records.
+func writeBatch(b Batch, written ...[]byte) error {
+	if err := b.Write(); err != nil {
+		log.SyslogErr("write approval records fail", "err", err.Error())
+		return err
+	}
+
+	for _, key := range written {
+		notifyKey(key)
+	}
+
+	return nil
+}
+
+// queryRecords returns the data in the status selected by the filter.
+func queryRecords(sdb Database, status string, filter *ApprovingFilter) ([]mpcprotocol.SendData, error) {
+	if filter == nil {
+		filter = &ApprovingFilter{}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		limit = MaxQueryLimit
+	}
+
+	prefix := statusIndexPrefixOf(status)
+	if len(filter.GPK) != 0 {
+		prefix = gpkIndexPrefixOf(filter.GPK, status)
+	}
+
+	var recordKeys [][]byte
+	skipped := 0
+	err := sdb.Iterate(prefix, func(key, value []byte) bool {
+		if len(key) < len(prefix)+8 {
+			return true
+		}
+
+		t := int64(binary.BigEndian.Uint64(key[len(prefix):]))
+		if t < filter.Since {
+			return true
+		}
+		if filter.Until != 0 && t > filter.Until {
+			return false
+		}
+
+		if skipped < filter.Offset {
+			skipped++
+			return true
+		}
+
+		recordKeys = append(recordKeys, common.CopyBytes(key[len(prefix)+8:]))
+		return len(recordKeys) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]mpcprotocol.SendData, 0, len(recordKeys))
+	for _, recordKey := range recordKeys {
+		entry, err := getExpiry(sdb, recordKey)
+		if err != nil || entry == nil {
+			log.SyslogErr("queryRecords, get record fail", "key", hexutil.Encode(recordKey))
+			continue
+		}
+
+		data = append(data, entry.Data)
+	}
+
+	return data, nil
+}
+
+// migrateApprovingKeys moves the data listed in the legacy MpcApprovingKeys json array into the indexed records.
+func migrateApprovingKeys(sdb Database) error {
+	exist, err := sdb.Has([]byte(mpcprotocol.MpcApprovingKeys))
+	if err != nil || !exist {
+		return err
+	}
+
+	ret, err := sdb.Get([]byte(mpcprotocol.MpcApprovingKeys))
+	if err != nil {
+		return err
+	}
+
+	var approvingKeys [][]byte
+	if err := json.Unmarshal(ret, &approvingKeys); err != nil {
+		return err
+	}
+
+	b := sdb.NewBatch()
+	now := time.Now()
+	migrated := 0
+	for _, approvingKey := range approvingKeys {
+		value, err := sdb.Get(approvingKey)
+		if err != nil {
+			continue
+		}
+
+		var data mpcprotocol.SendData
+		if err := json.Unmarshal(value, &data); err != nil || !bytes.Equal(buildKeyFromData(&data, mpcprotocol.MpcApproving), approvingKey) {
+			continue
+		}
+
+		if _, err := putRecord(b, &data, mpcprotocol.MpcApproving, value, now); err != nil {
+			return err
+		}
+		migrated++
+	}
+	b.Delete([]byte(mpcprotocol.MpcApprovingKeys))
+
+	log.SyslogInfo("migrate approving keys", "keys", len(approvingKeys), "migrated", migrated)
+	return b.Write()
+}
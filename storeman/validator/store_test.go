@@ -0,0 +1,127 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func TestQueryDataForApprove(t *testing.T) {
+	defer newTestDB(t)()
+
+	for i := 0; i < 10; i++ {
+		gpk := []byte("gpk-a")
+		if i%2 == 1 {
+			gpk = []byte("gpk-b")
+		}
+
+		data := mpcprotocol.SendData{PKBytes: gpk, Data: []byte(fmt.Sprintf("data-%d", i))}
+		if err := AddApprovingData(&data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := GetDataForApprove()
+	if err != nil || len(all) != 10 {
+		t.Fatalf("all approving data: %d %v", len(all), err)
+	}
+
+	byGPK, err := QueryDataForApprove(&ApprovingFilter{GPK: []byte("gpk-b")})
+	if err != nil || len(byGPK) != 5 {
+		t.Fatalf("approving data of gpk: %d %v", len(byGPK), err)
+	}
+	for _, data := range byGPK {
+		if string(data.PKBytes) != "gpk-b" {
+			t.Fatalf("data of another gpk is selected: %v", data)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for offset := 0; offset < 10; offset += 4 {
+		page, err := QueryDataForApprove(&ApprovingFilter{Offset: offset, Limit: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range page {
+			seen[string(data.Data)] = true
+		}
+	}
+	if len(seen) != 10 {
+		t.Fatalf("pages miss data: %d", len(seen))
+	}
+
+	if future, _ := QueryDataForApprove(&ApprovingFilter{Since: 1 << 40}); len(future) != 0 {
+		t.Fatalf("data added before since is selected: %d", len(future))
+	}
+}
+
+func TestConcurrentApprovals(t *testing.T) {
+	defer newTestDB(t)()
+
+	const count = 50
+	datas := make([]mpcprotocol.SendData, count)
+	for i := range datas {
+		datas[i] = mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte(fmt.Sprintf("data-%d", i))}
+	}
+
+	var wg sync.WaitGroup
+	for i := range datas {
+		wg.Add(1)
+		go func(data *mpcprotocol.SendData) {
+			defer wg.Done()
+			AddApprovingData(data)
+		}(&datas[i])
+	}
+	wg.Wait()
+
+	if pending, _ := QueryDataForApprove(&ApprovingFilter{Limit: count}); len(pending) != count {
+		t.Fatalf("approving data is lost: %d", len(pending))
+	}
+
+	for i := range datas {
+		wg.Add(1)
+		go func(data mpcprotocol.SendData) {
+			defer wg.Done()
+			if errs := ApproveData([]mpcprotocol.SendData{data}); errs[0] != nil {
+				t.Error(errs[0])
+			}
+		}(datas[i])
+	}
+	wg.Wait()
+
+	if pending, _ := GetDataForApprove(); len(pending) != 0 {
+		t.Fatalf("approved data is still pending: %d", len(pending))
+	}
+}
+
+func TestMigrateApprovingKeys(t *testing.T) {
+	defer newTestDB(t)()
+
+	sdb, _ := GetDB()
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("legacy")}
+	value, _ := json.Marshal(&data)
+	approvingKey := buildKeyFromData(&data, mpcprotocol.MpcApproving)
+	keys, _ := json.Marshal([][]byte{approvingKey})
+	sdb.Put(approvingKey, value)
+	sdb.Put([]byte(mpcprotocol.MpcApprovingKeys), keys)
+
+	if err := migrateApprovingKeys(sdb); err != nil {
+		t.Fatal(err)
+	}
+
+	if exist, _ := sdb.Has([]byte(mpcprotocol.MpcApprovingKeys)); exist {
+		t.Fatal("legacy approving keys are not removed")
+	}
+
+	pending, _ := GetDataForApprove()
+	if len(pending) != 1 || string(pending[0].Data) != "legacy" {
+		t.Fatalf("legacy approving data is not migrated: %v", pending)
+	}
+
+	if errs := ApproveData(pending); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	lvdberror "github.com/syndtr/goleveldb/leveldb/errors"
+	"fmt"
 	"github.com/wanchain/schnorr-mpc/common"
 	"github.com/wanchain/schnorr-mpc/common/hexutil"
 	"github.com/wanchain/schnorr-mpc/crypto"
 	"github.com/wanchain/schnorr-mpc/log"
 	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+	"sync"
 	"time"
 )
 
@@ -19,8 +20,9 @@ func init() {
 
 }
 
-// TODO add ValidateData
-func ValidateData(data *mpcprotocol.SendData) (bool, error) {
+// ValidateData waits for the data being approved or rejected, and claims the approval for the caller, which
+// must either consume it by ConsumeApprovedData once the data is signed or return it by ReleaseApprovedData.
+func ValidateData(data *mpcprotocol.SendData, timeout time.Duration) (bool, error) {
 
 	log.SyslogInfo("&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&& ValidateData, begin",
 		"pk", hexutil.Encode(data.PKBytes),
@@ -33,12 +35,29 @@ func ValidateData(data *mpcprotocol.SendData) (bool, error) {
 	}
 
 	approvedKey := buildKeyFromData(data, mpcprotocol.MpcApproved)
-	_, err = waitKeyFromDB([][]byte{approvedKey})
+	rejectedKey := buildKeyFromData(data, mpcprotocol.MpcRejected)
+	key, err := waitKeyFromDB([][]byte{approvedKey, rejectedKey}, timeout)
 	if err != nil {
 		log.SyslogErr("ValidateData, waitKeyFromDB has fail", "err", err.Error())
+		if approving, _ := sdb.Has(buildKeyFromData(data, mpcprotocol.MpcApproving)); approving {
+			postApprovalEvent(ApprovalTimeout, data, mpcprotocol.ErrWaitApproved.Error())
+		}
 		return false, mpcprotocol.ErrWaitApproved
 	}
 
+	if bytes.Equal(key, rejectedKey) {
+		return false, rejectedError(sdb, rejectedKey)
+	}
+
+	// the approval is checked and claimed at once, so concurrent contexts can not sign by the same approval
+	storeMu.Lock()
+	defer unlockStore()
+
+	if approvalExpired(sdb, approvedKey, time.Now()) {
+		log.SyslogErr("ValidateData, approval is expired", "data", data.String())
+		return false, mpcprotocol.ErrApprovalExpired
+	}
+
 	value, err := sdb.Get(approvedKey)
 	if err != nil {
 		log.SyslogErr("ValidateData, sdb.Get has fail", "err", err.Error())
@@ -63,10 +82,31 @@ func ValidateData(data *mpcprotocol.SendData) (bool, error) {
 		return false, mpcprotocol.ErrApprovedNotConsistent
 	}
 
+	if err := claimApproval(sdb, data, approvedKey, time.Now()); err != nil {
+		log.SyslogErr("ValidateData, claim approval fail", "data", data.String(), "err", err.Error())
+		return false, err
+	}
+
 	return true, nil
 
 }
 
+// GetApprovedData returns the approval record of the data stored in the approved db.
+func GetApprovedData(data *mpcprotocol.SendData) ([]byte, error) {
+	sdb, err := GetDB()
+	if err != nil {
+		log.SyslogErr("GetApprovedData, getting storeman database fail", "err", err.Error())
+		return nil, mpcprotocol.ErrGetDb
+	}
+
+	value, err := sdb.Get(buildKeyFromData(data, mpcprotocol.MpcApproved))
+	if err != nil {
+		return nil, mpcprotocol.ErrGetApproved
+	}
+
+	return value, nil
+}
+
 func AddApprovedData(data *mpcprotocol.SendData) error {
 	return addApprovedData(data)
 }
@@ -76,45 +116,24 @@ func AddApprovingData(data *mpcprotocol.SendData) error {
 }
 
 func GetDataForApprove() ([]mpcprotocol.SendData, error) {
-	log.SyslogInfo("GetDataForApprove, begin")
+	return QueryDataForApprove(nil)
+}
+
+// QueryDataForApprove returns the data waiting for approval selected and paged by the filter.
+func QueryDataForApprove(filter *ApprovingFilter) ([]mpcprotocol.SendData, error) {
+	log.SyslogInfo("QueryDataForApprove, begin")
 	sdb, err := GetDB()
 	if err != nil {
-		log.SyslogErr("GetDataForApprove, getting storeman database fail [GetDB()]", "err", err.Error())
-		return nil, nil
+		log.SyslogErr("QueryDataForApprove, getting storeman database fail [GetDB()]", "err", err.Error())
+		return nil, mpcprotocol.ErrGetDb
 	}
 
-	log.SyslogInfo("GetDataForApprove", "key", mpcprotocol.MpcApprovingKeys)
-	ret, err := sdb.Get([]byte(mpcprotocol.MpcApprovingKeys))
-	if err != nil {
-		log.SyslogErr("GetDataForApprove, getting storeman database fail [MpcApprovingKeys]", "err", err.Error())
-		return nil, nil
-	}
-	var approvingKeys [][]byte
-	err = json.Unmarshal(ret, &approvingKeys)
+	approvingData, err := queryRecords(sdb, mpcprotocol.MpcApproving, filter)
 	if err != nil {
-		log.SyslogErr("GetDataForApprove, Unmarshal", "err", err.Error())
-		return nil, nil
+		log.SyslogErr("QueryDataForApprove, query approving data fail", "err", err.Error())
+		return nil, err
 	}
 
-	var approvingData []mpcprotocol.SendData
-	for i := 0; i < len(approvingKeys); i++ {
-		approvingItem, err := sdb.Get(approvingKeys[i])
-		if err != nil {
-			log.SyslogErr("GetDataForApprove, getting storeman database fail [approvingKeys[i]]", "err", err.Error())
-			//return nil, err
-			continue
-		}
-
-		var approvingTemp mpcprotocol.SendData
-		err = json.Unmarshal(approvingItem, &approvingTemp)
-		if err != nil {
-			log.SyslogErr("GetDataForApprove, Unmarshal approvingTemp", "err", err.Error())
-			//return nil, err
-			continue
-		}
-		approvingData = append(approvingData, approvingTemp)
-	}
-	log.SyslogInfo("GetDataForApprove succeed to get data from level db after putting key-val pair", "ret", string(ret))
 	return approvingData, nil
 }
 
@@ -128,33 +147,19 @@ func approveOneData(approveData mpcprotocol.SendData) error {
 		return err
 	}
 
-	ret, err := sdb.Get([]byte(mpcprotocol.MpcApprovingKeys))
-	if err != nil {
-		log.SyslogErr("approveOneData, getting storeman database fail", "err", err.Error())
-		return err
-	}
-	var approvingKeys [][]byte
-	err = json.Unmarshal(ret, &approvingKeys)
-	if err != nil {
-		log.SyslogErr("approveOneData, Unmarshal fail", "err", err.Error())
-		return err
-	}
-	// check in approving keys
-	if !inByteArray(&approvingKey, &approvingKeys) {
-		log.SyslogErr("approveOneData, not in approving keys")
-		return err
-	}
-	// in approving keys
+	storeMu.Lock()
+	defer unlockStore()
+
 	// check in approving db
 	exist, err := sdb.Has(approvingKey)
-	if !exist {
-		log.SyslogErr("approveOneData, not in approving keys")
-		return errors.New("can not fond in approving db")
-	}
 	if err != nil {
 		log.SyslogErr("approveOneData, sdb.Has error", "err:", err.Error())
 		return err
 	}
+	if !exist {
+		log.SyslogErr("approveOneData, not in approving db")
+		return mpcprotocol.ErrNotApproving
+	}
 	// in approving db
 	// add in approved DB
 	log.SyslogInfo("&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&& approveOneData, begin",
@@ -168,54 +173,48 @@ func approveOneData(approveData mpcprotocol.SendData) error {
 		return err
 	}
 
-	key := buildKeyFromData(&approveData, mpcprotocol.MpcApproved)
-	log.SyslogInfo("=============== approveOneData", "data", approveData.String(), "approved key", hexutil.Encode(key))
-	err = addKeyValueToDB(key, val)
-	if err != nil {
-		log.SyslogErr("approveOneData, addKeyValueToDB fail", "err", err.Error())
-		return err
-	}
-	// in approving keys
-	// delete key in keys
-	newApprovingKeys := deleteInByteArray(&approvingKey, &approvingKeys)
-	newApprovingKeysBytes, err := json.Marshal(&newApprovingKeys)
-	if err != nil {
-		log.SyslogErr("approveOneData, Marshal fail", "err", err.Error())
+	// move the data from the approving db to the approved db at once
+	b := sdb.NewBatch()
+	if err = deleteRecord(sdb, b, &approveData, mpcprotocol.MpcApproving); err != nil {
+		log.SyslogErr("approveOneData, delete approving record fail", "err", err.Error())
 		return err
 	}
-	err = addKeyValueToDB([]byte(mpcprotocol.MpcApprovingKeys), newApprovingKeysBytes)
+
+	key, err := putRecord(b, &approveData, mpcprotocol.MpcApproved, val, time.Now())
 	if err != nil {
-		log.SyslogErr("approveOneData, addKeyValueToDB MpcApprovingKeys fail", "err", err.Error())
+		log.SyslogErr("approveOneData, put approved record fail", "err", err.Error())
 		return err
 	}
-	// in approving db
-	// delete in approving db
-	err = sdb.Delete(approvingKey)
-	if err != nil {
-		log.SyslogErr("approveOneData, Delete in approving db", "err", err.Error())
+
+	log.SyslogInfo("=============== approveOneData", "data", approveData.String(), "approved key", hexutil.Encode(key))
+	if err = writeBatch(b, key); err != nil {
 		return err
 	}
+
+	queueApprovalEvent(ApprovalApproved, &approveData, "")
 	return nil
 }
 func addOneValidData(approveData mpcprotocol.SendData) error {
 
-
 	sdb, err := GetDB()
 	if err != nil {
 		log.SyslogErr("addOneValidData, getting storeman database fail", "err", err.Error())
-		return err	}
+		return err
+	}
+
+	storeMu.Lock()
+	defer unlockStore()
 
 	key := buildKeyFromData(&approveData, mpcprotocol.MpcApproved)
 	exist, err := sdb.Has(key)
-	if exist {
-		log.SyslogErr("addOneValidData, has in approved db")
-		return errors.New("has in approved db")
-	}
 	if err != nil {
 		log.SyslogErr("addOneValidData, sdb.Has error", "err:", err.Error())
 		return err
 	}
-	// in approving db
+	if exist && !approvalExpired(sdb, key, time.Now()) {
+		log.SyslogErr("addOneValidData, has in approved db")
+		return errors.New("has in approved db")
+	}
 	// add in approved DB
 	log.SyslogInfo("&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&&& addOneValidData, begin",
 		"pk", hexutil.Encode(approveData.PKBytes),
@@ -228,17 +227,134 @@ func addOneValidData(approveData mpcprotocol.SendData) error {
 		return err
 	}
 
-	log.SyslogInfo("=============== approveOneData", "data", approveData.String(), "approved key", hexutil.Encode(key))
-	err = addKeyValueToDB(key, val)
+	b := sdb.NewBatch()
+	// an expired approval is replaced with its indexes
+	if err = deleteRecord(sdb, b, &approveData, mpcprotocol.MpcApproved); err != nil {
+		return err
+	}
+
+	if _, err = putRecord(b, &approveData, mpcprotocol.MpcApproved, val, time.Now()); err != nil {
+		log.SyslogErr("addOneValidData, put approved record fail", "err", err.Error())
+		return err
+	}
+
+	log.SyslogInfo("=============== addOneValidData", "data", approveData.String(), "approved key", hexutil.Encode(key))
+	return writeBatch(b, key)
+}
+
+// addValidDataOnce approves the data unless it already has an unexpired approval.
+func addValidDataOnce(data *mpcprotocol.SendData) error {
+	sdb, err := GetDB()
+	if err != nil {
+		return mpcprotocol.ErrGetDb
+	}
+
+	approvedKey := buildKeyFromData(data, mpcprotocol.MpcApproved)
+	exist, err := sdb.Has(approvedKey)
+	if err != nil || (exist && !approvalExpired(sdb, approvedKey, time.Now())) {
+		return err
+	}
+
+	return addOneValidData(*data)
+}
+
+// RejectedData is a data rejected by operators, kept in the rejected db.
+type RejectedData struct {
+	mpcprotocol.SendData
+	Reason string `json:"reason"`
+	Time   int64  `json:"time"`
+}
+
+func rejectedError(sdb Database, rejectedKey []byte) error {
+	value, err := sdb.Get(rejectedKey)
 	if err != nil {
-		log.SyslogErr("addOneValidData, addKeyValueToDB fail", "err", err.Error())
+		return mpcprotocol.ErrDataRejected
+	}
+
+	var rejected RejectedData
+	if err := json.Unmarshal(value, &rejected); err != nil || rejected.Reason == "" {
+		return mpcprotocol.ErrDataRejected
+	}
+
+	return fmt.Errorf("%v: %s", mpcprotocol.ErrDataRejected, rejected.Reason)
+}
+
+// rejectOneData moves the data from the approving db to the rejected db.
+func rejectOneData(rejectData mpcprotocol.SendData, reason string) error {
+	approvingKey := buildKeyFromData(&rejectData, mpcprotocol.MpcApproving)
+
+	sdb, err := GetDB()
+	if err != nil {
+		log.SyslogErr("rejectOneData, getting storeman database fail", "err", err.Error())
+		return err
+	}
+
+	storeMu.Lock()
+	defer unlockStore()
+
+	exist, err := sdb.Has(approvingKey)
+	if err != nil {
+		log.SyslogErr("rejectOneData, sdb.Has error", "err:", err.Error())
 		return err
 	}
+	if !exist {
+		log.SyslogErr("rejectOneData, not in approving db")
+		return mpcprotocol.ErrNotApproving
+	}
+
+	log.SyslogInfo("rejectOneData",
+		"pk", hexutil.Encode(rejectData.PKBytes),
+		"data", hexutil.Encode([]byte(rejectData.Data)),
+		"reason", reason)
+
+	val, err := json.Marshal(&RejectedData{SendData: rejectData, Reason: reason, Time: time.Now().Unix()})
+	if err != nil {
+		log.SyslogErr("rejectOneData, marshal fail", "err", err.Error())
+		return err
+	}
+
+	b := sdb.NewBatch()
+	if err = deleteRecord(sdb, b, &rejectData, mpcprotocol.MpcApproving); err != nil {
+		log.SyslogErr("rejectOneData, delete approving record fail", "err", err.Error())
+		return err
+	}
+
+	// the operator approvals so far are void
+	b.Delete(buildKeyFromData(&rejectData, mpcprotocol.MpcApprovals))
+
+	key, err := putRecord(b, &rejectData, mpcprotocol.MpcRejected, val, time.Now())
+	if err != nil {
+		log.SyslogErr("rejectOneData, put rejected record fail", "err", err.Error())
+		return err
+	}
+
+	if err = writeBatch(b, key); err != nil {
+		return err
+	}
+
+	queueApprovalEvent(ApprovalRejected, &rejectData, reason)
 	return nil
 }
 
+// RejectData rejects the data waiting for approval with the reason, the mpc contexts waiting for them fail at once.
+func RejectData(rejectData []mpcprotocol.SendData, reason string) []error {
+	retResult := make([]error, len(rejectData))
+	for i := 0; i < len(rejectData); i++ {
+		retResult[i] = rejectOneData(rejectData[i], reason)
+	}
+
+	return retResult
+}
+
 func ApproveData(approveData []mpcprotocol.SendData) []error {
 	retResult := make([]error, len(approveData))
+	if operatorQuorum() != 0 {
+		for i := range retResult {
+			retResult[i] = mpcprotocol.ErrOperatorSigRequired
+		}
+		return retResult
+	}
+
 	for i := 0; i < len(approveData); i++ {
 		dataItem := approveData[i]
 		retResult[i] = approveOneData(dataItem)
@@ -247,34 +363,55 @@ func ApproveData(approveData []mpcprotocol.SendData) []error {
 	return retResult
 }
 
-func inByteArray(data *[]byte, collection *[][]byte) bool {
-	if data == nil || len(*collection) == 0 {
-		return false
-	}
+// keyWaiter is woken when one of the keys it waits for is written to the db.
+type keyWaiter struct {
+	keys [][]byte
+	ch   chan struct{}
+	once sync.Once
+}
+
+var (
+	waitersMu sync.Mutex
+	waiters   = make(map[string]map[*keyWaiter]bool)
+)
+
+func watchKeys(keys [][]byte) *keyWaiter {
+	w := &keyWaiter{keys: keys, ch: make(chan struct{})}
 
-	for _, value := range *collection {
-		if bytes.Compare(*data, value) == 0 {
-			return true
+	waitersMu.Lock()
+	defer waitersMu.Unlock()
+	for _, key := range keys {
+		if waiters[string(key)] == nil {
+			waiters[string(key)] = make(map[*keyWaiter]bool)
 		}
+		waiters[string(key)][w] = true
 	}
-	return false
+
+	return w
 }
 
-func deleteInByteArray(data *[]byte, collection *[][]byte) [][]byte {
-	if !inByteArray(data, collection) {
-		return *collection
+func (w *keyWaiter) stop() {
+	waitersMu.Lock()
+	defer waitersMu.Unlock()
+	for _, key := range w.keys {
+		delete(waiters[string(key)], w)
+		if len(waiters[string(key)]) == 0 {
+			delete(waiters, string(key))
+		}
 	}
+}
 
-	ret := make([][]byte, 0)
-	for _, value := range *collection {
-		if bytes.Compare(*data, value) != 0 {
-			ret = append(ret, value)
-		}
+// notifyKey wakes the waiters of the key.
+func notifyKey(key []byte) {
+	waitersMu.Lock()
+	defer waitersMu.Unlock()
+	for w := range waiters[string(key)] {
+		w.once.Do(func() { close(w.ch) })
 	}
-	return ret
 }
 
-func waitKeyFromDB(keys [][]byte) ([]byte, error) {
+// waitKeyFromDB waits until one of the keys is in the db, and returns it.
+func waitKeyFromDB(keys [][]byte, timeout time.Duration) ([]byte, error) {
 	log.SyslogInfo("waitKeyFromDB, begin")
 
 	for i, key := range keys {
@@ -287,29 +424,34 @@ func waitKeyFromDB(keys [][]byte) ([]byte, error) {
 		return nil, err
 	}
 
-	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
 	for {
+		// watch before checking the db, so a key written in between is not missed
+		w := watchKeys(keys)
 		for _, key := range keys {
 			isExist, err := db.Has(key)
 			if err != nil {
+				w.stop()
 				log.SyslogErr("================= waitKeyFromDB fail", "err", err.Error())
 				return nil, err
 			} else if isExist {
+				w.stop()
 				log.SyslogInfo("================= waitKeyFromDB, got it", "key", common.ToHex(key))
 				return key, nil
 			}
 
 		}
 
-		if time.Now().Sub(start) >= mpcprotocol.MPCTimeOut {
+		select {
+		case <-w.ch:
+			w.stop()
+		case <-timer.C:
+			w.stop()
 			log.SyslogInfo("waitKeyFromDB, time out")
 			return nil, errors.New("waitKeyFromDB, time out")
 		}
-
-		time.Sleep(200 * time.Microsecond)
 	}
-
-	return nil, errors.New("waitKeyFromDB, unknown error")
 }
 
 func addKeyValueToDB(key, value []byte) error {
@@ -325,6 +467,7 @@ func addKeyValueToDB(key, value []byte) error {
 		log.SyslogErr("addKeyValueToDB, getting storeman database fail", "err", err.Error())
 		return err
 	}
+	notifyKey(key)
 
 	log.SyslogInfo("addKeyValueToDB", "key", common.ToHex(key))
 	ret, err := sdb.Get(key)
@@ -359,6 +502,9 @@ func addApprovingData(dataItem *mpcprotocol.SendData) error {
 		return err
 	}
 
+	storeMu.Lock()
+	defer unlockStore()
+
 	// check in approved db
 	approvedKey := buildKeyFromData(dataItem, mpcprotocol.MpcApproved)
 	isExist, err := sdb.Has(approvedKey)
@@ -366,74 +512,50 @@ func addApprovingData(dataItem *mpcprotocol.SendData) error {
 		log.SyslogErr("addApprovingData", "sdb.Has err", err.Error())
 		return err
 	}
-	if isExist {
+	if isExist && !approvalExpired(sdb, approvedKey, time.Now()) {
 		log.SyslogInfo("addApprovingData", "isExist in approvedDB", "true")
 		return nil
 	}
-	// check in approving keys
-	approvingKey := buildKeyFromData(dataItem, mpcprotocol.MpcApproving)
-	ret, err := sdb.Get([]byte(mpcprotocol.MpcApprovingKeys))
-	if err != nil && err != lvdberror.ErrNotFound {
-		log.SyslogErr("addApprovingData,  sdb.Get fail", "err", err.Error())
+	// a rejected data stays rejected
+	isExist, err = sdb.Has(buildKeyFromData(dataItem, mpcprotocol.MpcRejected))
+	if err != nil {
+		log.SyslogErr("addApprovingData", "sdb.Has err", err.Error())
 		return err
 	}
-	var approvingKeys [][]byte
-	if len(ret) != 0 {
-		err = json.Unmarshal(ret, &approvingKeys)
-		if err != nil {
-			log.SyslogErr("addApprovingData,  Unmarshal fail", "err", err.Error())
-			return err
-		}
+	if isExist {
+		log.SyslogInfo("addApprovingData", "isExist in rejectedDB", "true")
+		return nil
 	}
-	// in approvingKey or not
-	inApprovingKeys := inByteArray(&approvingKey, &approvingKeys)
 	// check in approving db
-	existApprovingDb, err := sdb.Has(approvingKey)
+	approvingKey := buildKeyFromData(dataItem, mpcprotocol.MpcApproving)
+	isExist, err = sdb.Has(approvingKey)
 	if err != nil {
 		log.SyslogErr("addApprovingData, sdb.Has fail", "err", err.Error())
 		return err
 	}
-	log.SyslogErr("addApprovingData ", "existApprovingDb", existApprovingDb, "inApprovingKeys", inApprovingKeys)
-	if existApprovingDb && inApprovingKeys {
+	if isExist {
 		return nil
 	}
 
 	// put in approving db
-	if !existApprovingDb {
-		value, err := json.Marshal(&dataItem)
-		if err != nil {
-			log.SyslogErr("addApprovingData, json.Marshal fail", "err", err.Error())
-			return err
-		}
-		err = addKeyValueToDB(approvingKey, value)
-		log.SyslogInfo("=============== addApprovingData ", "approvingKey", hexutil.Encode(approvingKey), "value", value)
-		if err != nil {
-			log.SyslogErr("addApprovingData, addKeyValueToDB fail", "err", err.Error())
-			return err
-		}
+	value, err := json.Marshal(&dataItem)
+	if err != nil {
+		log.SyslogErr("addApprovingData, json.Marshal fail", "err", err.Error())
+		return err
 	}
 
-	// append key in keys
-	if !inApprovingKeys {
-		approvingKeys = append(approvingKeys, approvingKey)
-
-		approvingKeysBytes, err := json.Marshal(&approvingKeys)
-		if err != nil {
-			log.SyslogErr("addApprovingData, Marshal approvingKeys fail", "err", err.Error())
-			return err
-		}
-
-		log.SyslogInfo("=============== addApprovingData ",
-			"approvingKeys", hexutil.Encode([]byte(mpcprotocol.MpcApprovingKeys)),
-			"value", approvingKeysBytes)
+	b := sdb.NewBatch()
+	if _, err = putRecord(b, dataItem, mpcprotocol.MpcApproving, value, time.Now()); err != nil {
+		log.SyslogErr("addApprovingData, put approving record fail", "err", err.Error())
+		return err
+	}
 
-		err = addKeyValueToDB([]byte(mpcprotocol.MpcApprovingKeys), approvingKeysBytes)
-		if err != nil {
-			log.SyslogErr("addApprovingData,  MpcApprovingKeys addKeyValueToDB fail", "err", err.Error())
-			return err
-		}
+	log.SyslogInfo("=============== addApprovingData ", "approvingKey", hexutil.Encode(approvingKey), "value", value)
+	if err = writeBatch(b, approvingKey); err != nil {
+		return err
 	}
 
+	queueApprovalEvent(ApprovalPending, dataItem, "")
 	return nil
 }
 
@@ -441,8 +563,11 @@ func addApprovedData(data *mpcprotocol.SendData) error {
 	return approveOneData(*data)
 }
 
+// AddValidData approves the data directly, which is refused once operator signatures are required.
 func AddValidData(data *mpcprotocol.SendData) error {
+	if operatorQuorum() != 0 {
+		return mpcprotocol.ErrOperatorSigRequired
+	}
+
 	return addOneValidData(*data)
 }
-
-//TODO need delete the approved data when signature complete successfully.
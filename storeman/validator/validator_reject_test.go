@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	mpcprotocol "github.com/wanchain/schnorr-mpc/storeman/storemanmpc/protocol"
+)
+
+func newTestDB(t *testing.T) func() {
+	dir := tmpKeyStore(t)
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := dbInstance
+	dbInstance = &storemanDB{fn: dir, db: db}
+	return func() {
+		dbInstance.Close()
+		dbInstance = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRejectData(t *testing.T) {
+	defer newTestDB(t)()
+
+	data := mpcprotocol.SendData{PKBytes: []byte("gpk"), Data: []byte("wanchain"), Extern: "cross"}
+	if errs := RejectData([]mpcprotocol.SendData{data}, "unknown lock"); errs[0] != mpcprotocol.ErrNotApproving {
+		t.Fatalf("reject data not waiting for approval: %v", errs[0])
+	}
+
+	if err := AddApprovingData(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := RejectData([]mpcprotocol.SendData{data}, "unknown lock"); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+
+	pending, _ := GetDataForApprove()
+	if len(pending) != 0 {
+		t.Fatalf("rejected data is still pending: %v", pending)
+	}
+
+	start := time.Now()
+	ok, err := ValidateData(&data, time.Second)
+	if ok || err == nil || !strings.HasSuffix(err.Error(), "unknown lock") {
+		t.Fatalf("rejected data validated: %v %v", ok, err)
+	}
+	if time.Since(start) >= time.Second {
+		t.Fatal("rejected data waited for the timeout")
+	}
+
+	// the same data requested again stays rejected
+	if err := AddApprovingData(&data); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := GetDataForApprove(); len(pending) != 0 {
+		t.Fatalf("rejected data is pending again: %v", pending)
+	}
+}